@@ -29,7 +29,7 @@ func tc1(m *gogrinder.Meta, s gogrinder.Settings) {
 		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 		if err != nil {
 			m.Error += err.Error()
-			mm = &req.HttpMetric{*m, 0, 0, 400}
+			mm = &req.HttpMetric{Meta: *m, Code: 400}
 		}
 		_, _, mm = req.DoRaw(c, r, m)
 	}
@@ -40,7 +40,7 @@ func tc1(m *gogrinder.Meta, s gogrinder.Settings) {
 		r, err := http.NewRequest("GET", "http://localhost:3001/get_private", nil)
 		if err != nil {
 			m.Error += err.Error()
-			mm = &req.HttpMetric{*m, 0, 0, 400}
+			mm = &req.HttpMetric{Meta: *m, Code: 400}
 		}
 		_, _, mm = req.DoRaw(c, r, m)
 	}
@@ -57,7 +57,7 @@ func tc2(m *gogrinder.Meta, s gogrinder.Settings) {
 			util.NewRandReader(2000))
 		if err != nil {
 			m.Error += err.Error()
-			mm = &req.HttpMetric{*m, 0, 0, 400}
+			mm = &req.HttpMetric{Meta: *m, Code: 400}
 		}
 		_, _, mm = req.DoRaw(c, r, m)
 	}
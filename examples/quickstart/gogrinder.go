@@ -20,23 +20,23 @@ var gg = gogrinder.NewTest()
 // define testcases using teststeps
 func tc1(m *gogrinder.Meta, s gogrinder.Settings) {
 	var mm *req.HttpMetric
-	c := req.NewDefaultClient()
+	c := m.Session().Client // keeps cookies and connections between iterations
 
 	b := gg.NewBracket("01_01_teststep")
 	{
 		r, err := http.NewRequest("GET", "http://localhost:3001/get_stuff", nil)
 		if err != nil {
 			m.Error += err.Error()
-			mm = &req.HttpMetric{*m, 0, 0, 400}
+			mm = &req.HttpMetric{Meta: *m, Code: 400}
 		}
-		_, _, mm = req.DoRaw(c, r, m)
+		_, _, mm = req.DoRaw(c, r, m, req.StatusRange(200, 299))
 	}
 	b.End(mm)
 }
 
 func tc2(m *gogrinder.Meta, s gogrinder.Settings) {
 	var mm *req.HttpMetric
-	c := req.NewDefaultClient()
+	c := m.Session().Client // keeps cookies and connections between iterations
 
 	b := gg.NewBracket("02_01_teststep")
 	{
@@ -44,9 +44,9 @@ func tc2(m *gogrinder.Meta, s gogrinder.Settings) {
 			util.NewRandReader(2000))
 		if err != nil {
 			m.Error += err.Error()
-			mm = &req.HttpMetric{*m, 0, 0, 400}
+			mm = &req.HttpMetric{Meta: *m, Code: 400}
 		}
-		_, _, mm = req.DoRaw(c, r, m)
+		_, _, mm = req.DoRaw(c, r, m, req.StatusRange(200, 299))
 	}
 	b.End(mm)
 }
@@ -9,7 +9,6 @@ import (
 
 	"github.com/finklabs/GoGrinder/gogrinder"
 	"github.com/finklabs/GoGrinder/req"
-	"github.com/xuyu/goredis"
 )
 
 const (
@@ -19,6 +18,9 @@ const (
 // initialize the GoGrinder
 var gg = gogrinder.NewTest()
 
+// ids of the supercars created by the test
+var supercars = gg.NewPool("supercars", gogrinder.PoolOptions{Kind: gogrinder.PoolSet})
+
 // define testcases using teststeps
 func supercars_01_list(m *gogrinder.Meta, s gogrinder.Settings) {
 	var mm *req.HttpMetric
@@ -29,9 +31,9 @@ func supercars_01_list(m *gogrinder.Meta, s gogrinder.Settings) {
 	r, err := http.NewRequest("GET", base+"/rest/supercars/", nil)
 	if err != nil {
 		m.Error += err.Error()
-		mm = &req.HttpMetric{*m, 0, 0, 400}
+		mm = &req.HttpMetric{Meta: *m, Code: 400}
 	} else {
-		resp, _, mm = req.DoJson(c, r, m)
+		resp, _, mm = req.DoJson(c, r, m, req.StatusRange(200, 299))
 
 		// assert record count
 		count := len(resp["data"].([]interface{}))
@@ -52,13 +54,13 @@ func supercars_02_read(m *gogrinder.Meta, s gogrinder.Settings) {
 	r, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		m.Error += err.Error()
-		mm = &req.HttpMetric{*m, 0, 0, 400}
+		mm = &req.HttpMetric{Meta: *m, Code: 400}
 	} else {
-		resp, _, mm = req.DoJson(c, r, m)
+		resp, _, mm = req.DoJson(c, r, m, req.StatusRange(200, 299))
 		// assert record id
-		i, err := strconv.Atoi(resp["_id"].(string))
+		i, err := req.ExtractJsonInt(resp, "_id")
 		if err != nil || i != id {
-			m.Error += "Error: retrived wrong record!"
+			mm.Error += "Error: retrived wrong record!"
 		}
 	}
 	b.End(mm)
@@ -78,21 +80,14 @@ func supercars_03_create(m *gogrinder.Meta, s gogrinder.Settings) {
 	r, err := req.NewPostJsonRequest(base+"/rest/supercars/", newCar)
 	if err != nil {
 		m.Error += err.Error()
-		mm = &req.HttpMetric{*m, 0, 0, 400}
+		mm = &req.HttpMetric{Meta: *m, Code: 400}
 	} else {
-		resp, _, mm = req.DoJson(c, r, m)
-		id := resp["_id"].(string)
-		if i, err := strconv.Atoi(id); err != nil || i <= RECORDS {
-			m.Error += "Error: something went wrong during new record creation!"
+		resp, _, mm = req.DoJson(c, r, m, req.StatusRange(200, 299))
+		id, err := req.ExtractJsonString(resp, "_id")
+		if i, _ := strconv.Atoi(id); err != nil || i <= RECORDS {
+			mm.Error += "Error: something went wrong during new record creation!"
 		} else {
-			redis, err := goredis.Dial(
-				&goredis.DialConfig{Address: s["redis_srv"].(string)})
-			if err != nil {
-				// is the redis server running? correct address?
-				m.Error += err.Error()
-			} else {
-				redis.SAdd("supercars", id) // no way this can go wrong!
-			}
+			supercars.Put(id) // no way this can go wrong!
 		}
 	}
 	b.End(mm)
@@ -109,31 +104,22 @@ func supercars_04_update(m *gogrinder.Meta, s gogrinder.Settings) {
 			"is a 12 cylinder mid-engine berlinetta named after the company's " +
 			"founder, Enzo Ferrari.", "image": "050.png"}
 	b := gg.NewBracket("04_01_supercars_update")
-	redis, err := goredis.Dial(&goredis.DialConfig{Address: s["redis_srv"].(string)})
+	id, err := supercars.Take()
 	if err != nil {
-		// is the redis server running? correct address?
+		// probably run out of data - so it does not make sense to continue
 		m.Error += err.Error()
-		mm = &req.HttpMetric{*m, 0, 0, 400}
+		mm = &req.HttpMetric{Meta: *m, Code: 400}
 	} else {
-		id, err := redis.SPop("supercars")
+		r, err := req.NewPutJsonRequest(base+"/rest/supercars/"+id, change)
 		if err != nil {
-			// probably run out of data - so it does not make sense to continue
 			m.Error += err.Error()
-			mm = &req.HttpMetric{*m, 0, 0, 400}
+			mm = &req.HttpMetric{Meta: *m, Code: 400}
 		} else {
-			r, err := req.NewPutJsonRequest(base+"/rest/supercars/"+string(id),
-				change)
-			if err != nil {
-				m.Error += err.Error()
-				mm = &req.HttpMetric{*m, 0, 0, 400}
-			} else {
-				_, _, mm = req.DoJson(c, r, m)
-				//tsUpdate(m, c, base + "/rest/supercars/" + string(id), change)
-
-				// add the record back!
-				redis.SAdd("supercars", string(id)) // no way this can go wrong!
-			}
+			_, _, mm = req.DoJson(c, r, m, req.StatusRange(200, 299))
+			//tsUpdate(m, c, base + "/rest/supercars/" + id, change)
 		}
+		// add the record back!
+		supercars.Put(id) // no way this can go wrong!
 	}
 	b.End(mm)
 }
@@ -144,25 +130,18 @@ func supercars_05_delete(m *gogrinder.Meta, s gogrinder.Settings) {
 	base := s["supercars_url"].(string)
 
 	b := gg.NewBracket("05_01_supercars_delete")
-	redis, err := goredis.Dial(&goredis.DialConfig{Address: s["redis_srv"].(string)})
-	if err != nil {
-		// is the redis server running? correct address?
-		m.Error += err.Error()
-		return
-	}
-
-	id, err := redis.SPop("supercars")
+	id, err := supercars.Take()
 	if err != nil {
 		// probably run out of data - so it does not make sense to continue
 		m.Error += err.Error()
 		return
 	}
-	r, err := http.NewRequest("DELETE", base+"/rest/supercars/"+string(id), nil)
+	r, err := http.NewRequest("DELETE", base+"/rest/supercars/"+id, nil)
 	if err != nil {
 		m.Error += err.Error()
-		mm = &req.HttpMetric{*m, 0, 0, 400}
+		mm = &req.HttpMetric{Meta: *m, Code: 400}
 	} else {
-		_, _, mm = req.DoRaw(c, r, m)
+		_, _, mm = req.DoRaw(c, r, m, req.StatusRange(200, 299))
 	}
 	b.End(mm)
 }
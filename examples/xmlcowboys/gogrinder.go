@@ -34,7 +34,7 @@ func main() {
 				strings.NewReader(str))
 			if err != nil {
 				m.Error += err.Error()
-				mm = &req.HttpMetric{*m, 0, 0, 400}
+				mm = &req.HttpMetric{Meta: *m, Code: 400}
 			}
 			_, _, mm = req.DoRaw(c, r, m)
 		}
@@ -0,0 +1,203 @@
+package gogrinder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	time "github.com/finklabs/ttime"
+)
+
+// Interval in which the agent polls the controller for commands and sends
+// its measurements.
+var agentPollInterval = 500 * time.Millisecond
+
+// The controller does not know the agent (e.g. after a restart of the controller).
+var errNotFound = fmt.Errorf("not found by the controller")
+
+// Agent executes its share of the loadmodel on behalf of a controller
+// (distributed mode). The measurements are streamed back to the controller.
+type Agent struct {
+	test       Scenario
+	controller string // base url of the controller e.g. http://localhost:3030
+	client     *http.Client
+	id         string
+	lock       sync.Mutex
+	buffer     []json.RawMessage // measurements not yet sent to the controller
+	quit       chan bool
+}
+
+// Create an agent for the controller with the given base url.
+func NewAgent(test Scenario, controller string) *Agent {
+	a := &Agent{
+		test:       test,
+		controller: strings.TrimRight(controller, "/"),
+		client:     &http.Client{Timeout: 10 * time.Second},
+		quit:       make(chan bool),
+	}
+	test.AddReportPlugin(a)
+	return a
+}
+
+// Buffer the measurement until it is sent to the controller.
+func (a *Agent) Update(m Metric) {
+	buf, err := json.Marshal(m)
+	if err != nil {
+		log.Errorf("can not encode measurement: %v", err)
+		return
+	}
+	a.lock.Lock()
+	a.buffer = append(a.buffer, buf)
+	a.lock.Unlock()
+}
+
+// Helper to send a request to the controller.
+func (a *Agent) request(method string, path string, body interface{}, result interface{}) error {
+	var b bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, a.controller+path, &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("controller responded %s to %s %s", resp.Status, method, path)
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+// Send the buffered measurements to the controller.
+func (a *Agent) flush() error {
+	a.lock.Lock()
+	buffer := a.buffer
+	a.buffer = nil
+	a.lock.Unlock()
+	if len(buffer) == 0 {
+		return nil
+	}
+	return a.request("POST", "/agents/"+a.id+"/measurements", buffer, nil)
+}
+
+// Register with the controller. It retries until the controller is available.
+func (a *Agent) register() bool {
+	host, _ := os.Hostname()
+	for {
+		var res struct {
+			Id string `json:"id"`
+		}
+		err := a.request("POST", "/agents", map[string]string{"host": host}, &res)
+		if err == nil {
+			a.id = res.Id
+			log.Infof("registered as %s with controller %s", a.id, a.controller)
+			return true
+		}
+		log.Warnf("can not register with controller %s: %v", a.controller, err)
+		select {
+		case <-a.quit:
+			return false
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Execute the share of the loadmodel and report back to the controller.
+func (a *Agent) exec(loadmodel map[string]interface{}) {
+	buf, _ := json.Marshal(loadmodel)
+	if err := a.test.ReadConfigValidate(string(buf), LoadmodelSchema); err != nil {
+		log.Errorf("invalid loadmodel from controller: %v", err)
+	} else {
+		// use the data pools of the controller
+		if shared, _ := loadmodel["SharedPools"].(bool); shared {
+			a.test.SharePools(a.controller)
+		} else {
+			a.test.SharePools("")
+		}
+		// stream the measurements while the test is running
+		done := make(chan bool)
+		stopped := make(chan bool)
+		go func() {
+			defer close(stopped)
+			for {
+				select {
+				case <-done:
+					return
+				case <-time.After(agentPollInterval):
+					if err := a.flush(); err != nil {
+						log.Errorf("can not send measurements: %v", err)
+					}
+				}
+			}
+		}()
+		if err := a.test.Exec(); err != nil {
+			log.Warnf("test finished: %v", err)
+		}
+		close(done)
+		<-stopped
+		if err := a.flush(); err != nil {
+			log.Errorf("can not send measurements: %v", err)
+		}
+	}
+	if err := a.request("POST", "/agents/"+a.id+"/done", nil, nil); err != nil {
+		log.Errorf("can not report done to controller: %v", err)
+	}
+}
+
+// Run the agent: register with the controller and execute the commands.
+// Run blocks until the agent is stopped.
+func (a *Agent) Run() error {
+	if !a.register() {
+		return nil
+	}
+	for {
+		select {
+		case <-a.quit:
+			a.test.Stop()
+			return nil
+		case <-time.After(agentPollInterval):
+		}
+		var cmd AgentCommand
+		if err := a.request("GET", "/agents/"+a.id+"/command", nil, &cmd); err != nil {
+			log.Warnf("can not poll controller: %v", err)
+			if err == errNotFound {
+				// the controller was restarted and does not know us anymore
+				if !a.register() {
+					return nil
+				}
+			}
+			continue
+		}
+		switch cmd.Command {
+		case "start":
+			if a.test.Status() == Stopped {
+				go a.exec(cmd.Loadmodel)
+			}
+		case "stop":
+			a.test.Stop()
+		}
+	}
+}
+
+// Stop the agent.
+func (a *Agent) Stop() {
+	close(a.quit)
+}
@@ -0,0 +1,57 @@
+package gogrinder
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestAgentsWithController(t *testing.T) {
+	bak := agentPollInterval
+	agentPollInterval = 10 * time.Millisecond
+	defer func() { agentPollInterval = bak }()
+
+	controller := NewTest()
+	controller.EnableController()
+	controller.config["Scenario"] = "scenario1"
+	controller.config["Loadmodel"] = []interface{}{
+		map[string]interface{}{"Testcase": "01_tc", "Runfor": 0.05, "Users": 3.0, "Pacing": 0.01}}
+	srv := TestServer{test: controller}
+	server := httptest.NewServer(srv.Router())
+	defer server.Close()
+
+	// every agent is compiled from the same code as the controller
+	scenario := func(test *TestScenario) func() {
+		return func() {
+			test.Schedule("01_tc", func(meta *Meta, s Settings) {
+				b := test.NewBracket("01_01_sth")
+				b.End(meta)
+			})
+		}
+	}
+	controller.Testscenario("scenario1", scenario(controller))
+	for i := 0; i < 2; i++ {
+		test := NewTest()
+		test.Testscenario("scenario1", scenario(test))
+		agent := NewAgent(test, server.URL)
+		go agent.Run()
+		defer agent.Stop()
+	}
+	for len(controller.Agents()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := controller.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	results := controller.Results("")
+	if len(results) != 1 || results[0].Teststep != "01_01_sth" || results[0].Count < 3 {
+		t.Errorf("Results of the cluster not as expected: %v", results)
+	}
+	for _, a := range controller.Agents() {
+		if a.Running {
+			t.Errorf("Agent %s is expected to be done!", a.Id)
+		}
+	}
+}
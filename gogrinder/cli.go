@@ -4,6 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	time "github.com/finklabs/ttime"
 )
 
 // simple helper contains
@@ -16,9 +21,29 @@ func contains(s []string, e string) bool {
 	return false
 }
 
+// Options of the GoGrinder command line.
+type CLIOptions struct {
+	Filename     string            // loadmodel filename (default "loadmodel.json")
+	NoExec       bool              // supress auto execution of the test scenario
+	NoReport     bool              // supress the console report
+	NoFrontend   bool              // do not start the web frontend
+	NoPrometheus bool              // do not start the prometheus reporter
+	Prometheus   PrometheusOptions // endpoint and Pushgateway of the prometheus reporter
+	Jtl          bool              // use jtl format for event reporting
+	JtlHeader    bool              // write the csv header line to the jtl file
+	JtlXml       bool              // use the xml variant of the jtl format
+	JUnit        string            // junit xml results file ("" = none)
+	HtmlReport   string            // directory of the static html report ("" = none)
+	ReadEventLog string            // create the reports from this event-log instead of executing the test
+	Controller   string            // url of the controller (agent mode)
+	Port         int               // port of the web frontend
+	Agents       int               // number of agents to wait for (controller mode)
+	LogLevel     string            // panic, fatal, error, warn, info, debug
+}
+
 // Simple command line interface for GoGrinder.
 //  * (default is to start/stop test via UI, event-log and prometheus reporter)
-func GetCLI() (string, bool, bool, bool, bool, bool, int, string, error) {
+func GetCLI() (CLIOptions, error) {
 	// for now try to work with the std. Golang flag package
 
 	// In my research I found this tutorial useful:
@@ -26,14 +51,7 @@ func GetCLI() (string, bool, bool, bool, bool, bool, int, string, error) {
 	// probably a suitable flag alternative:
 	// https://github.com/voxelbrain/goptions
 
-	var filename string = "loadmodel.json"
-	var noExec bool
-	var noReport bool
-	var noFrontend bool
-	var noPrometheus bool
-	var jtl bool
-	var port int
-	var logLevel string
+	opts := CLIOptions{Filename: "loadmodel.json"}
 	var err error = nil
 
 	// no ExitOnError - we maintain control of the program flow
@@ -41,17 +59,30 @@ func GetCLI() (string, bool, bool, bool, bool, bool, int, string, error) {
 
 	cli.SetOutput(stdout)
 
-	cli.BoolVar(&noExec, "no-exec", false, "supress auto execution of the test scenario.")
-	cli.BoolVar(&noReport, "no-report", false, "supress the console report.")
-	cli.BoolVar(&noFrontend, "no-frontend", false, "do not start the web frontend.")
-	cli.BoolVar(&noPrometheus, "no-prometheus", false, "do not start the prometheus reporter.")
-	cli.BoolVar(&jtl, "jtl", false, "use jtl format for event reporting.")
-	cli.StringVar(&logLevel, "log-level", "warn", "panic, fatal, error, warn, info, debug")
-	cli.IntVar(&port, "port", 3030, "specify the port for the web frontend.")
+	cli.BoolVar(&opts.NoExec, "no-exec", false, "supress auto execution of the test scenario.")
+	cli.BoolVar(&opts.NoReport, "no-report", false, "supress the console report.")
+	cli.BoolVar(&opts.NoFrontend, "no-frontend", false, "do not start the web frontend.")
+	cli.BoolVar(&opts.NoPrometheus, "no-prometheus", false, "do not start the prometheus reporter.")
+	cli.StringVar(&opts.Prometheus.Addr, "prometheus-addr", ":9110", "listen address of the prometheus /metrics endpoint.")
+	cli.StringVar(&opts.Prometheus.PushURL, "prometheus-push", "", "push the metrics to the given Pushgateway (e.g. http://localhost:9091).")
+	cli.DurationVar(&opts.Prometheus.PushInterval, "prometheus-push-interval", 10*time.Second, "interval of the pushes to the Pushgateway.")
+	cli.StringVar(&opts.Prometheus.RunID, "run-id", "", "add the label run_id to the prometheus metrics.")
+	cli.DurationVar(&opts.Prometheus.ScrapeWait, "prometheus-wait", 11*time.Second, "wait at most this long for the final scrape of the /metrics endpoint (0 = no wait).")
+	cli.BoolVar(&opts.Jtl, "jtl", false, "use jtl format for event reporting.")
+	cli.BoolVar(&opts.JtlHeader, "jtl-header", false, "write the csv header line to the jtl file.")
+	cli.BoolVar(&opts.JtlXml, "jtl-xml", false, "use the xml variant of the jtl format.")
+	cli.StringVar(&opts.JUnit, "junit", "", "write the results to the given file in junit xml format.")
+	cli.StringVar(&opts.HtmlReport, "html-report", "", "write a static html report to the given directory.")
+	cli.StringVar(&opts.ReadEventLog, "read-event-log", "", "create the reports from an existing event-log instead of executing the test.")
+	cli.StringVar(&opts.Controller, "controller", "", "run as agent of the controller with the given url (e.g. http://localhost:3030).")
+	cli.IntVar(&opts.Agents, "agents", 0, "run as controller and wait for the given number of agents.")
+	cli.StringVar(&opts.LogLevel, "log-level", "warn", "panic, fatal, error, warn, info, debug")
+	cli.IntVar(&opts.Port, "port", 3030, "specify the port for the web frontend.")
 
 	cli.Usage = func() {
 		fmt.Fprintf(stdout, "Usage of %s:\n", os.Args[0])
 		fmt.Fprintf(stdout, "  %s base_loadmodel.json -no-frontend\n", os.Args[0])
+		fmt.Fprintf(stdout, "  %s analyze [options] event-log.txt [loadmodel.json]\n", os.Args[0])
 		fmt.Fprintf(stdout, "\n")
 		fmt.Fprintf(stdout, "  arg-1  loadmodel filename.  (defaults 'loadmodel.json')\n")
 		cli.PrintDefaults()
@@ -65,24 +96,125 @@ func GetCLI() (string, bool, bool, bool, bool, bool, int, string, error) {
 	}
 
 	if cli.NArg() == 1 {
-		filename = cli.Arg(0)
+		opts.Filename = cli.Arg(0)
 	}
 
-	if !contains([]string{"panic", "fatal", "error", "warn", "info", "debug"}, logLevel) {
+	if !contains([]string{"panic", "fatal", "error", "warn", "info", "debug"}, opts.LogLevel) {
 		cli.Usage()
 	}
 
-	if err == nil {
-		// check file exists
-		if _, ferr := os.Stat(filename); ferr != nil {
-			err = fmt.Errorf("File %s does not exist.", filename)
+	if err == nil && opts.Controller == "" {
+		// check file exists (agents receive the loadmodel from the controller)
+		if _, ferr := os.Stat(opts.Filename); ferr != nil {
+			err = fmt.Errorf("File %s does not exist.", opts.Filename)
 		}
 	}
 
 	// check for invalid combination of options
-	if noExec && noFrontend {
+	if opts.NoExec && opts.NoFrontend {
 		err = fmt.Errorf("Invalid combination of -no-exec and -no-frontend.")
 	}
+	if opts.Controller != "" && opts.Agents > 0 {
+		err = fmt.Errorf("Invalid combination of -controller and -agents.")
+	}
+
+	return opts, err
+}
+
+// Check if the analyze command is given on the command line.
+func IsAnalyze() bool {
+	return len(os.Args) > 1 && os.Args[1] == "analyze"
+}
+
+// Options of the analyze command.
+type AnalyzeOptions struct {
+	EventLog    string         // event-log to analyze
+	Filename    string         // loadmodel filename (default "loadmodel.json")
+	Filter      EventLogFilter // time window, testcases and teststeps to include
+	Percentiles []float64      // percentiles to report (nil = default)
+	Csv         string         // csv results file ("" = none)
+	HtmlReport  string         // directory of the static html report ("" = none)
+	JUnit       string         // junit xml results file ("" = none)
+	NoReport    bool           // supress the console report
+}
+
+// Command line interface of the analyze command. The analyze command rebuilds
+// the statistics from an existing event-log:
+//  gogrinder analyze [options] event-log.txt [loadmodel.json]
+func GetAnalyzeCLI() (AnalyzeOptions, error) {
+	opts := AnalyzeOptions{Filename: "loadmodel.json"}
+	var testcase string
+	var teststep string
+	var ps string
+	var err error = nil
+
+	// no ExitOnError - we maintain control of the program flow
+	cli := flag.NewFlagSet("gogrinder analyze", flag.ContinueOnError)
+
+	cli.SetOutput(stdout)
+
+	cli.DurationVar(&opts.Filter.From, "from", 0, "skip the measurements before this offset to the start (e.g. 30s warm-up).")
+	cli.DurationVar(&opts.Filter.To, "to", 0, "skip the measurements after this offset to the start (e.g. 5m).")
+	cli.StringVar(&testcase, "testcase", "", "regular expression of the testcases to include.")
+	cli.StringVar(&teststep, "teststep", "", "regular expression of the teststeps to include.")
+	cli.StringVar(&ps, "percentiles", "", "comma separated percentiles to report (e.g. 90,95,99).")
+	cli.StringVar(&opts.Csv, "csv", "", "write the results to the given file in csv format.")
+	cli.StringVar(&opts.HtmlReport, "html-report", "", "write a static html report to the given directory.")
+	cli.StringVar(&opts.JUnit, "junit", "", "write the results to the given file in junit xml format.")
+	cli.BoolVar(&opts.NoReport, "no-report", false, "supress the console report.")
+
+	cli.Usage = func() {
+		fmt.Fprintf(stdout, "Usage of %s analyze:\n", os.Args[0])
+		fmt.Fprintf(stdout, "  %s analyze -from 30s -teststep '^01_' event-log.txt loadmodel.json\n", os.Args[0])
+		fmt.Fprintf(stdout, "\n")
+		fmt.Fprintf(stdout, "  arg-1  event-log filename.\n")
+		fmt.Fprintf(stdout, "  arg-2  loadmodel filename.  (defaults 'loadmodel.json')\n")
+		cli.PrintDefaults()
+		err = fmt.Errorf("Command line usage problem.")
+	}
+
+	cli.Parse(os.Args[2:]) // exclude the program and the command
+
+	if cli.NArg() < 1 || cli.NArg() > 2 {
+		cli.Usage()
+	}
+	if cli.NArg() >= 1 {
+		opts.EventLog = cli.Arg(0)
+	}
+	if cli.NArg() == 2 {
+		opts.Filename = cli.Arg(1)
+	}
+
+	if testcase != "" {
+		if opts.Filter.Testcase, err = regexp.Compile(testcase); err != nil {
+			cli.Usage()
+		}
+	}
+	if teststep != "" {
+		if opts.Filter.Teststep, err = regexp.Compile(teststep); err != nil {
+			cli.Usage()
+		}
+	}
+	if ps != "" {
+		for _, p := range strings.Split(ps, ",") {
+			f, perr := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if perr != nil || f < 0 || f > 100 {
+				cli.Usage()
+				break
+			}
+			opts.Percentiles = append(opts.Percentiles, f)
+		}
+	}
+
+	if err == nil {
+		// check files exist
+		for _, f := range []string{opts.EventLog, opts.Filename} {
+			if _, ferr := os.Stat(f); ferr != nil {
+				err = fmt.Errorf("File %s does not exist.", f)
+				break
+			}
+		}
+	}
 
-	return filename, noExec, noReport, noFrontend, noPrometheus, jtl, port, logLevel, err
+	return opts, err
 }
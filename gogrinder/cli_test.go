@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"testing"
+
+	time "github.com/finklabs/ttime"
 )
 
 func TestDefaults(t *testing.T) {
@@ -20,36 +23,87 @@ func TestDefaults(t *testing.T) {
 	f.Close()
 	defer os.Remove("./loadmodel.json")
 
-	filename, noExec, noReport, noFrontend, noPrometheus, jtl, port, logLevel, err := GetCLI()
-	if filename != "loadmodel.json" {
-		t.Errorf("Default filename was expected 'loadmodel.json' but was: %s", filename)
+	opts, err := GetCLI()
+	if opts.Filename != "loadmodel.json" {
+		t.Errorf("Default filename was expected 'loadmodel.json' but was: %s", opts.Filename)
+	}
+	if opts.NoExec != false {
+		t.Errorf("Default -no-exec was expected false but was: %t", opts.NoExec)
+	}
+	if opts.NoReport != false {
+		t.Errorf("Default -no-report was expected false but was: %t", opts.NoReport)
+	}
+	if opts.NoFrontend != false {
+		t.Errorf("Default -no-frontend was expected false but was: %t", opts.NoFrontend)
+	}
+	if opts.NoPrometheus != false {
+		t.Errorf("Default -no-prometheus was expected false but was: %t", opts.NoPrometheus)
+	}
+	if opts.Prometheus != (PrometheusOptions{Addr: ":9110", PushInterval: 10 * time.Second,
+		ScrapeWait: 11 * time.Second}) {
+		t.Errorf("Default prometheus options not as expected: %v", opts.Prometheus)
+	}
+	if opts.Jtl != false {
+		t.Errorf("Default -jtl was expected false but was: %t", opts.Jtl)
 	}
-	if noExec != false {
-		t.Errorf("Default -no-exec was expected false but was: %t", noExec)
+	if opts.JtlHeader != false {
+		t.Errorf("Default -jtl-header was expected false but was: %t", opts.JtlHeader)
 	}
-	if noReport != false {
-		t.Errorf("Default -no-report was expected false but was: %t", noReport)
+	if opts.JtlXml != false {
+		t.Errorf("Default -jtl-xml was expected false but was: %t", opts.JtlXml)
 	}
-	if noFrontend != false {
-		t.Errorf("Default -no-frontend was expected false but was: %t", noFrontend)
+	if opts.JUnit != "" {
+		t.Errorf("Default -junit was expected '' but was: %s", opts.JUnit)
 	}
-	if noPrometheus != false {
-		t.Errorf("Default -no-prometheus was expected false but was: %t", noPrometheus)
+	if opts.HtmlReport != "" {
+		t.Errorf("Default -html-report was expected '' but was: %s", opts.HtmlReport)
 	}
-	if jtl != false {
-		t.Errorf("Default -jtl was expected false but was: %t", jtl)
+	if opts.ReadEventLog != "" {
+		t.Errorf("Default -read-event-log was expected '' but was: %s", opts.ReadEventLog)
 	}
-	if port != 3030 {
-		t.Errorf("Default port was expected 3030 but was: %d", port)
+	if opts.Controller != "" {
+		t.Errorf("Default -controller was expected '' but was: %s", opts.Controller)
 	}
-	if logLevel != "warn" {
-		t.Errorf("Default logLevel was expected 'warn' but was: %d", logLevel)
+	if opts.Agents != 0 {
+		t.Errorf("Default -agents was expected 0 but was: %d", opts.Agents)
+	}
+	if opts.Port != 3030 {
+		t.Errorf("Default port was expected 3030 but was: %d", opts.Port)
+	}
+	if opts.LogLevel != "warn" {
+		t.Errorf("Default logLevel was expected 'warn' but was: %s", opts.LogLevel)
 	}
 	if err != nil {
 		t.Errorf("Default err was expected nil but was: %s", err)
 	}
 }
 
+func TestPrometheusOptions(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "-prometheus-addr", ":9999", "-prometheus-push",
+		"http://localhost:9091", "-prometheus-push-interval", "1s", "-run-id", "42",
+		"-prometheus-wait", "5s"}
+
+	// prepare the default loadmodel.json file
+	f, ferr := os.Create("./loadmodel.json")
+	if ferr != nil {
+		t.Errorf("problem during default file creation: %s", ferr)
+	}
+	f.Close()
+	defer os.Remove("./loadmodel.json")
+
+	opts, err := GetCLI()
+	exp := PrometheusOptions{Addr: ":9999", PushURL: "http://localhost:9091",
+		PushInterval: time.Second, RunID: "42", ScrapeWait: 5 * time.Second}
+	if opts.Prometheus != exp {
+		t.Errorf("Prometheus options expected %v but was: %v", exp, opts.Prometheus)
+	}
+	if err != nil {
+		t.Errorf("err was expected nil but was: %s", err)
+	}
+}
+
 func TestNoExec(t *testing.T) {
 	oldArgs := os.Args
 	defer func() { os.Args = oldArgs }()
@@ -63,9 +117,9 @@ func TestNoExec(t *testing.T) {
 	f.Close()
 	defer os.Remove("./loadmodel.json")
 
-	_, noExec, _, _, _, _, _, _, err := GetCLI()
-	if noExec != true {
-		t.Errorf("-no-exec was expected true but was: %t", noExec)
+	opts, err := GetCLI()
+	if opts.NoExec != true {
+		t.Errorf("-no-exec was expected true but was: %t", opts.NoExec)
 	}
 	if err != nil {
 		t.Errorf("err was expected nil but was: %s", err)
@@ -85,9 +139,9 @@ func TestNoFrontend(t *testing.T) {
 	f.Close()
 	defer os.Remove("./loadmodel.json")
 
-	_, _, _, noFrontend, _, _, _, _, err := GetCLI()
-	if noFrontend != true {
-		t.Errorf("-no-frontend was expected true but was: %t", noFrontend)
+	opts, err := GetCLI()
+	if opts.NoFrontend != true {
+		t.Errorf("-no-frontend was expected true but was: %t", opts.NoFrontend)
 	}
 	if err != nil {
 		t.Errorf("err was expected nil but was: %s", err)
@@ -107,9 +161,9 @@ func TestNoReport(t *testing.T) {
 	f.Close()
 	defer os.Remove("./loadmodel.json")
 
-	_, _, noReport, _, _, _, _, _, err := GetCLI()
-	if noReport != true {
-		t.Errorf("-no-report was expected true but was: %t", noReport)
+	opts, err := GetCLI()
+	if opts.NoReport != true {
+		t.Errorf("-no-report was expected true but was: %t", opts.NoReport)
 	}
 	if err != nil {
 		t.Errorf("err was expected nil but was: %s", err)
@@ -129,9 +183,9 @@ func TestNoPrometheus(t *testing.T) {
 	f.Close()
 	defer os.Remove("./loadmodel.json")
 
-	_, _, _, _, noPrometheus, _, _, _, err := GetCLI()
-	if noPrometheus != true {
-		t.Errorf("-no-prometheus was expected true but was: %t", noPrometheus)
+	opts, err := GetCLI()
+	if opts.NoPrometheus != true {
+		t.Errorf("-no-prometheus was expected true but was: %t", opts.NoPrometheus)
 	}
 	if err != nil {
 		t.Errorf("err was expected nil but was: %s", err)
@@ -151,15 +205,173 @@ func TestJtl(t *testing.T) {
 	f.Close()
 	defer os.Remove("./loadmodel.json")
 
-	_, _, _, _, _, jtl, _, _, err := GetCLI()
-	if jtl != true {
-		t.Errorf("-jtl was expected true but was: %t", jtl)
+	opts, err := GetCLI()
+	if opts.Jtl != true {
+		t.Errorf("-jtl was expected true but was: %t", opts.Jtl)
+	}
+	if err != nil {
+		t.Errorf("err was expected nil but was: %s", err)
+	}
+}
+
+func TestJtlHeader(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "-jtl", "-jtl-header"}
+
+	// prepare the default loadmodel.json file
+	f, ferr := os.Create("./loadmodel.json")
+	if ferr != nil {
+		t.Errorf("problem during default file creation: %s", ferr)
+	}
+	f.Close()
+	defer os.Remove("./loadmodel.json")
+
+	opts, err := GetCLI()
+	if opts.JtlHeader != true {
+		t.Errorf("-jtl-header was expected true but was: %t", opts.JtlHeader)
+	}
+	if err != nil {
+		t.Errorf("err was expected nil but was: %s", err)
+	}
+}
+
+func TestJtlXml(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "-jtl", "-jtl-xml"}
+
+	// prepare the default loadmodel.json file
+	f, ferr := os.Create("./loadmodel.json")
+	if ferr != nil {
+		t.Errorf("problem during default file creation: %s", ferr)
+	}
+	f.Close()
+	defer os.Remove("./loadmodel.json")
+
+	opts, err := GetCLI()
+	if opts.JtlXml != true {
+		t.Errorf("-jtl-xml was expected true but was: %t", opts.JtlXml)
+	}
+	if err != nil {
+		t.Errorf("err was expected nil but was: %s", err)
+	}
+}
+
+func TestJunit(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "-junit", "results.xml"}
+
+	// prepare the default loadmodel.json file
+	f, ferr := os.Create("./loadmodel.json")
+	if ferr != nil {
+		t.Errorf("problem during default file creation: %s", ferr)
+	}
+	f.Close()
+	defer os.Remove("./loadmodel.json")
+
+	opts, err := GetCLI()
+	if opts.JUnit != "results.xml" {
+		t.Errorf("-junit was expected results.xml but was: %s", opts.JUnit)
+	}
+	if err != nil {
+		t.Errorf("err was expected nil but was: %s", err)
+	}
+}
+
+func TestHtmlReport(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "-html-report", "report"}
+
+	// prepare the default loadmodel.json file
+	f, ferr := os.Create("./loadmodel.json")
+	if ferr != nil {
+		t.Errorf("problem during default file creation: %s", ferr)
+	}
+	f.Close()
+	defer os.Remove("./loadmodel.json")
+
+	opts, err := GetCLI()
+	if opts.HtmlReport != "report" {
+		t.Errorf("-html-report was expected report but was: %s", opts.HtmlReport)
+	}
+	if err != nil {
+		t.Errorf("err was expected nil but was: %s", err)
+	}
+}
+
+func TestReadEventLog(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "-read-event-log", "event-log.txt"}
+
+	// prepare the default loadmodel.json file
+	f, ferr := os.Create("./loadmodel.json")
+	if ferr != nil {
+		t.Errorf("problem during default file creation: %s", ferr)
+	}
+	f.Close()
+	defer os.Remove("./loadmodel.json")
+
+	opts, err := GetCLI()
+	if opts.ReadEventLog != "event-log.txt" {
+		t.Errorf("-read-event-log was expected event-log.txt but was: %s", opts.ReadEventLog)
 	}
 	if err != nil {
 		t.Errorf("err was expected nil but was: %s", err)
 	}
 }
 
+func TestController(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	// agents do not need a loadmodel file
+	os.Args = []string{"gogrinder", "-controller", "http://localhost:3030", "-no-frontend"}
+
+	opts, err := GetCLI()
+	if opts.Controller != "http://localhost:3030" {
+		t.Errorf("-controller was expected http://localhost:3030 but was: %s", opts.Controller)
+	}
+	if err != nil {
+		t.Errorf("err was expected nil but was: %s", err)
+	}
+}
+
+func TestAgents(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "-agents", "3"}
+
+	// prepare the default loadmodel.json file
+	f, ferr := os.Create("./loadmodel.json")
+	if ferr != nil {
+		t.Errorf("problem during default file creation: %s", ferr)
+	}
+	f.Close()
+	defer os.Remove("./loadmodel.json")
+
+	opts, err := GetCLI()
+	if opts.Agents != 3 {
+		t.Errorf("-agents was expected 3 but was: %d", opts.Agents)
+	}
+	if err != nil {
+		t.Errorf("err was expected nil but was: %s", err)
+	}
+}
+
+func TestControllerAndAgents(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "-controller", "http://localhost:3030", "-agents", "3"}
+
+	_, err := GetCLI()
+	if err.Error() != "Invalid combination of -controller and -agents." {
+		t.Errorf("err was expected %s but was: %s", "Invalid combination of -controller and -agents.", err.Error())
+	}
+}
+
 func TestPort(t *testing.T) {
 	oldArgs := os.Args
 	defer func() { os.Args = oldArgs }()
@@ -173,9 +385,9 @@ func TestPort(t *testing.T) {
 	f.Close()
 	defer os.Remove("./loadmodel.json")
 
-	_, _, _, _, _, _, port, _, err := GetCLI()
-	if port != 8888 {
-		t.Errorf("Port was expected 8888 but was: %d", port)
+	opts, err := GetCLI()
+	if opts.Port != 8888 {
+		t.Errorf("Port was expected 8888 but was: %d", opts.Port)
 	}
 	if err != nil {
 		t.Errorf("err was expected nil but was: %s", err)
@@ -195,9 +407,9 @@ func TestLogLevel(t *testing.T) {
 	f.Close()
 	defer os.Remove("./loadmodel.json")
 
-	_, _, _, _, _, _, _, logLevel, err := GetCLI()
-	if logLevel != "debug" {
-		t.Errorf("LogLevel was expected 'debug' but was: %d", logLevel)
+	opts, err := GetCLI()
+	if opts.LogLevel != "debug" {
+		t.Errorf("LogLevel was expected 'debug' but was: %s", opts.LogLevel)
 	}
 	if err != nil {
 		t.Errorf("err was expected nil but was: %s", err)
@@ -217,7 +429,7 @@ func TestLogLevelUnknown(t *testing.T) {
 	f.Close()
 	defer os.Remove("./loadmodel.json")
 
-	_, _, _, _, _, _, _, _, err := GetCLI()
+	_, err := GetCLI()
 	if err.Error() != "Command line usage problem." {
 		t.Errorf("err was expected %s but was: %s", "Command line usage problem.", err.Error())
 	}
@@ -231,9 +443,9 @@ func TestFilename(t *testing.T) {
 	defer func() { os.Args = oldArgs }()
 	os.Args = []string{"gogrinder", file.Name()}
 
-	filename, _, _, _, _, _, _, _, err := GetCLI()
-	if filename != file.Name() {
-		t.Errorf("Filename was expected %s but was: %s", file.Name(), filename)
+	opts, err := GetCLI()
+	if opts.Filename != file.Name() {
+		t.Errorf("Filename was expected %s but was: %s", file.Name(), opts.Filename)
 	}
 	if err != nil {
 		t.Errorf("err was expected nil but was: %s", err)
@@ -257,7 +469,7 @@ func TestUnknownFlag(t *testing.T) {
 	f.Close()
 	defer os.Remove("./loadmodel.json")
 
-	_, _, _, _, _, _, _, _, err := GetCLI()
+	_, err := GetCLI()
 	if err.Error() != "Command line usage problem." {
 		t.Errorf("err was expected %s but was: %s", "Command line usage problem.", err.Error())
 	}
@@ -275,7 +487,7 @@ func TestAdditionalArgument(t *testing.T) {
 	stdout = new(bytes.Buffer)
 	defer func() { stdout = bak }()
 
-	_, _, _, _, _, _, _, _, err := GetCLI()
+	_, err := GetCLI()
 	if err.Error() != "Command line usage problem." {
 		t.Errorf("err was expected %s but was: %s", "Command line usage problem.", err.Error())
 	}
@@ -290,7 +502,7 @@ func TestFileNotFound(t *testing.T) {
 	stdout = new(bytes.Buffer)
 	defer func() { stdout = bak }()
 
-	_, _, _, _, _, _, _, _, err := GetCLI()
+	_, err := GetCLI()
 	if err.Error() != "File unknown_file.json does not exist." {
 		t.Errorf("err was expected %s but was: %s", "File unknown_file.json does not exist.", err.Error())
 	}
@@ -313,8 +525,124 @@ func TestInvalidCombinationOfOptions(t *testing.T) {
 	f.Close()
 	defer os.Remove("./loadmodel.json")
 
-	_, _, _, _, _, _, _, _, err := GetCLI()
+	_, err := GetCLI()
 	if err.Error() != "Invalid combination of -no-exec and -no-frontend." {
 		t.Errorf("err was expected %s but was: %s", "Invalid combination of -no-exec and -no-frontend.", err.Error())
 	}
 }
+
+func TestIsAnalyze(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	os.Args = []string{"gogrinder", "analyze", "event-log.txt"}
+	if !IsAnalyze() {
+		t.Errorf("IsAnalyze was expected true!")
+	}
+	os.Args = []string{"gogrinder", "loadmodel.json"}
+	if IsAnalyze() {
+		t.Errorf("IsAnalyze was expected false!")
+	}
+}
+
+func TestAnalyzeCLI(t *testing.T) {
+	eventLog, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
+	defer os.Remove(eventLog.Name())
+	loadmodel, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
+	defer os.Remove(loadmodel.Name())
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "analyze", "-from", "30s", "-to", "5m", "-testcase", "^01_",
+		"-teststep", "step$", "-percentiles", "90, 99.9", "-csv", "results.csv",
+		"-html-report", "report", "-junit", "results.xml", "-no-report",
+		eventLog.Name(), loadmodel.Name()}
+
+	opts, err := GetAnalyzeCLI()
+	if opts.EventLog != eventLog.Name() {
+		t.Errorf("event-log was expected %s but was: %s", eventLog.Name(), opts.EventLog)
+	}
+	if opts.Filename != loadmodel.Name() {
+		t.Errorf("loadmodel was expected %s but was: %s", loadmodel.Name(), opts.Filename)
+	}
+	if opts.Filter.From != 30*time.Second || opts.Filter.To != 5*time.Minute {
+		t.Errorf("-from, -to were expected 30s, 5m but were: %s, %s", opts.Filter.From, opts.Filter.To)
+	}
+	if opts.Filter.Testcase.String() != "^01_" || opts.Filter.Teststep.String() != "step$" {
+		t.Errorf("-testcase, -teststep not as expected: %s, %s", opts.Filter.Testcase, opts.Filter.Teststep)
+	}
+	if !reflect.DeepEqual(opts.Percentiles, []float64{90, 99.9}) {
+		t.Errorf("-percentiles not as expected: %v", opts.Percentiles)
+	}
+	if opts.Csv != "results.csv" || opts.HtmlReport != "report" || opts.JUnit != "results.xml" {
+		t.Errorf("-csv, -html-report, -junit not as expected: %s, %s, %s", opts.Csv, opts.HtmlReport, opts.JUnit)
+	}
+	if opts.NoReport != true {
+		t.Errorf("-no-report was expected true but was: %t", opts.NoReport)
+	}
+	if err != nil {
+		t.Errorf("err was expected nil but was: %s", err)
+	}
+}
+
+func TestAnalyzeCLIDefaults(t *testing.T) {
+	eventLog, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
+	defer os.Remove(eventLog.Name())
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "analyze", eventLog.Name()}
+
+	// prepare the default loadmodel.json file
+	f, ferr := os.Create("./loadmodel.json")
+	if ferr != nil {
+		t.Errorf("problem during default file creation: %s", ferr)
+	}
+	f.Close()
+	defer os.Remove("./loadmodel.json")
+
+	opts, err := GetAnalyzeCLI()
+	if opts.Filename != "loadmodel.json" {
+		t.Errorf("Default loadmodel was expected loadmodel.json but was: %s", opts.Filename)
+	}
+	if opts.Filter.From != 0 || opts.Filter.To != 0 || opts.Filter.Testcase != nil || opts.Filter.Teststep != nil {
+		t.Errorf("Default filter not as expected: %v", opts.Filter)
+	}
+	if opts.Percentiles != nil || opts.Csv != "" || opts.HtmlReport != "" || opts.JUnit != "" || opts.NoReport != false {
+		t.Errorf("Defaults not as expected: %v, %s, %s, %s, %t", opts.Percentiles, opts.Csv,
+			opts.HtmlReport, opts.JUnit, opts.NoReport)
+	}
+	if err != nil {
+		t.Errorf("err was expected nil but was: %s", err)
+	}
+}
+
+func TestAnalyzeCLIMissingEventLog(t *testing.T) {
+	bak := stdout
+	stdout = new(bytes.Buffer)
+	defer func() { stdout = bak }()
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "analyze"}
+
+	_, err := GetAnalyzeCLI()
+	if err.Error() != "Command line usage problem." {
+		t.Errorf("err was expected %s but was: %s", "Command line usage problem.", err.Error())
+	}
+}
+
+func TestAnalyzeCLIInvalidPercentiles(t *testing.T) {
+	bak := stdout
+	stdout = new(bytes.Buffer)
+	defer func() { stdout = bak }()
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "analyze", "-percentiles", "90,x", "event-log.txt"}
+
+	_, err := GetAnalyzeCLI()
+	if err.Error() != "Command line usage problem." {
+		t.Errorf("err was expected %s but was: %s", "Command line usage problem.", err.Error())
+	}
+}
@@ -0,0 +1,287 @@
+package gogrinder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	time "github.com/finklabs/ttime"
+)
+
+// In the distributed mode a controller distributes the loadmodel among the
+// registered agents. The agents execute the scenario and stream their
+// measurements back to the controller. The statistics, report and csv of
+// the controller reflect the whole cluster.
+
+// Controller is implemented by the TestScenario. The TestServer uses it to
+// communicate with the agents.
+type Controller interface {
+	EnableController()
+	RegisterAgent(host string) (string, error)
+	Agents() []AgentInfo
+	AgentCommand(id string) (AgentCommand, error)
+	AgentMeasurements(id string, measurements []Meta) error
+	AgentDone(id string) error
+}
+
+// AgentInfo is what you get from test.Agents().
+type AgentInfo struct {
+	Id       string `json:"id"`
+	Host     string `json:"host"`
+	LastSeen string `json:"last_seen"`
+	Running  bool   `json:"running"`
+}
+
+// AgentCommand is the response of the controller to the poll of an agent.
+// Command is one of "wait", "start", "stop".
+type AgentCommand struct {
+	Command   string                 `json:"command"`
+	Loadmodel map[string]interface{} `json:"loadmodel,omitempty"`
+}
+
+// Agents that do not poll the controller within this timeout are considered lost.
+var agentTimeout = 10 * time.Second
+
+// Internal datastructure of the controller to keep track of an agent.
+type agentState struct {
+	host      string
+	lastSeen  time.Time
+	run       int                    // run the agent takes part in (0 = none)
+	loadmodel map[string]interface{} // share of the loadmodel for the run
+	started   bool
+	done      bool
+}
+
+// Internal datastructure of the controller.
+type cluster struct {
+	lock   sync.Mutex
+	agents map[string]*agentState
+	ids    []string // ids of the agents in the order they registered
+	run    int      // current run
+}
+
+// Enable the distributed mode. Exec distributes the loadmodel among the
+// registered agents instead of executing the scenario.
+func (test *TestScenario) EnableController() {
+	test.cluster = &cluster{agents: make(map[string]*agentState)}
+}
+
+// Error for agent requests if the controller mode is not enabled.
+var errNoController = fmt.Errorf("the controller mode is not enabled")
+
+// Helper to find the agent.
+// Careful: the caller needs to hold the cluster lock!
+func (c *cluster) agent(id string) (*agentState, error) {
+	a, ok := c.agents[id]
+	if !ok {
+		return nil, fmt.Errorf("agent %s is not registered", id)
+	}
+	return a, nil
+}
+
+// Register a new agent. It returns the id of the agent.
+func (test *TestScenario) RegisterAgent(host string) (string, error) {
+	if test.cluster == nil {
+		return "", errNoController
+	}
+	c := test.cluster
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	id := "agent-" + strconv.Itoa(len(c.ids)+1)
+	c.agents[id] = &agentState{host: host, lastSeen: time.Now()}
+	c.ids = append(c.ids, id)
+	log.Infof("agent %s registered from %s", id, host)
+	return id, nil
+}
+
+// Give me the registered agents in the order they registered.
+func (test *TestScenario) Agents() []AgentInfo {
+	agents := []AgentInfo{}
+	if test.cluster == nil {
+		return agents
+	}
+	c := test.cluster
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, id := range c.ids {
+		a := c.agents[id]
+		agents = append(agents, AgentInfo{id, a.host, a.lastSeen.UTC().Format(ISO8601),
+			a.run == c.run && a.run > 0 && !a.done})
+	}
+	return agents
+}
+
+// The agent polls for the next command.
+func (test *TestScenario) AgentCommand(id string) (AgentCommand, error) {
+	wait := AgentCommand{Command: "wait"}
+	if test.cluster == nil {
+		return wait, errNoController
+	}
+	c := test.cluster
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	a, err := c.agent(id)
+	if err != nil {
+		return wait, err
+	}
+	a.lastSeen = time.Now()
+	if a.run != c.run || a.run == 0 || a.done {
+		return wait, nil
+	}
+	if test.Status() == Stopping {
+		return AgentCommand{Command: "stop"}, nil
+	}
+	if !a.started {
+		a.started = true
+		return AgentCommand{"start", a.loadmodel}, nil
+	}
+	return wait, nil
+}
+
+// The agent sends its measurements.
+func (test *TestScenario) AgentMeasurements(id string, measurements []Meta) error {
+	if test.cluster == nil {
+		return errNoController
+	}
+	c := test.cluster
+	c.lock.Lock()
+	a, err := c.agent(id)
+	if err == nil && (a.run != c.run || a.run == 0 || a.done) {
+		err = fmt.Errorf("agent %s is not running", id)
+	}
+	c.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	for i := range measurements {
+		test.Update(&measurements[i])
+	}
+	return nil
+}
+
+// The agent finished its share of the run.
+func (test *TestScenario) AgentDone(id string) error {
+	if test.cluster == nil {
+		return errNoController
+	}
+	c := test.cluster
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	a, err := c.agent(id)
+	if err != nil {
+		return err
+	}
+	if a.run == c.run && !a.done {
+		a.done = true
+		test.wg.Done()
+	}
+	return nil
+}
+
+// Distribute the loadmodel among the registered agents and start a new run.
+// The waitgroup of the test is released once all agents are done.
+func (test *TestScenario) startAgents() error {
+	c := test.cluster
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	// agents that stopped polling do not take part in the run
+	active := []string{}
+	for _, id := range c.ids {
+		if time.Now().Sub(c.agents[id].lastSeen) <= agentTimeout {
+			active = append(active, id)
+		}
+	}
+	if len(active) == 0 {
+		return fmt.Errorf("no agents registered")
+	}
+	c.run++
+	for i, id := range active {
+		loadmodel, err := splitLoadmodel(test.GetConfigMap(), i, len(active))
+		if err != nil {
+			return err
+		}
+		a := c.agents[id]
+		a.run, a.loadmodel, a.started, a.done = c.run, loadmodel, false, false
+	}
+	test.wg.Add(len(active))
+	go test.watchAgents(c.run)
+	return nil
+}
+
+// Agents that stop polling are considered done so the run can finish.
+func (test *TestScenario) watchAgents(run int) {
+	c := test.cluster
+	for {
+		time.Sleep(time.Second)
+		c.lock.Lock()
+		running := 0
+		for id, a := range c.agents {
+			if a.run != run || a.done {
+				continue
+			}
+			if time.Now().Sub(a.lastSeen) > agentTimeout {
+				log.Errorf("agent %s is lost", id)
+				a.done = true
+				test.wg.Done()
+				continue
+			}
+			running++
+		}
+		c.lock.Unlock()
+		if running == 0 {
+			return
+		}
+	}
+}
+
+// Share of the loadmodel for agent <nbr> of <count> agents. The users of every
+// testcase are split among the agents, the arrival rate in proportion to them.
+func splitLoadmodel(config map[string]interface{}, nbr int, count int) (map[string]interface{}, error) {
+	// deep copy of the loadmodel
+	buf, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	loadmodel := make(map[string]interface{})
+	if err = json.Unmarshal(buf, &loadmodel); err != nil {
+		return nil, err
+	}
+
+	// the controller checks the thresholds for the whole cluster
+	delete(loadmodel, "Thresholds")
+
+	share := func(users float64) float64 {
+		s := float64(int(users) / count)
+		if nbr < int(users)%count {
+			s++
+		}
+		return s
+	}
+	if entries, ok := loadmodel["Loadmodel"].([]interface{}); ok {
+		for _, e := range entries {
+			entry := e.(map[string]interface{})
+			if r, ok := entry["Rate"].(float64); ok {
+				// the rate follows the share of the worker pool (users); every
+				// agent needs at least one worker for its arrivals
+				u, _ := entry["Users"].(float64)
+				if int(u) < count {
+					return nil, fmt.Errorf("testcase %v needs at least one user per agent "+
+						"to split its rate among %d agents", entry["Testcase"], count)
+				}
+				entry["Rate"] = r * share(u) / u
+			}
+			if u, ok := entry["Users"].(float64); ok {
+				entry["Users"] = share(u)
+			}
+			if stages, ok := entry["Stages"].([]interface{}); ok {
+				for _, s := range stages {
+					stage := s.(map[string]interface{})
+					stage["Users"] = share(stage["Users"].(float64))
+				}
+			}
+		}
+	}
+	return loadmodel, nil
+}
@@ -0,0 +1,188 @@
+package gogrinder
+
+import (
+	"reflect"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestSplitLoadmodel(t *testing.T) {
+	config := map[string]interface{}{
+		"Scenario": "scenario1",
+		"Loadmodel": []interface{}{
+			map[string]interface{}{"Testcase": "01_tc", "Runfor": 10.0, "Users": 5.0, "Pacing": 1.0},
+			map[string]interface{}{"Testcase": "02_tc", "Runfor": 10.0, "Users": 4.0, "Rate": 9.0},
+			map[string]interface{}{"Testcase": "03_tc", "Runfor": 10.0, "Users": 3.0, "Pacing": 1.0,
+				"Stages": []interface{}{map[string]interface{}{"Duration": 5.0, "Users": 3.0}}},
+		},
+		"Thresholds": []interface{}{map[string]interface{}{"MaxAvg": 5.0}},
+	}
+
+	for nbr, exp := range [][]float64{{3, 2, 3, 2, 2}, {2, 2, 3, 1, 1}} {
+		loadmodel, err := splitLoadmodel(config, nbr, 2)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+		entries := loadmodel["Loadmodel"].([]interface{})
+		tc1 := entries[0].(map[string]interface{})
+		tc2 := entries[1].(map[string]interface{})
+		tc3 := entries[2].(map[string]interface{})
+		stage := tc3["Stages"].([]interface{})[0].(map[string]interface{})
+		users := []float64{tc1["Users"].(float64), tc2["Users"].(float64),
+			tc2["Rate"].(float64) * 2 / 3, tc3["Users"].(float64), stage["Users"].(float64)}
+		if !reflect.DeepEqual(users, exp) {
+			t.Errorf("Share of agent %d not as expected: %v", nbr, users)
+		}
+		if _, exists := loadmodel["Thresholds"]; exists {
+			t.Errorf("Thresholds are not expected in the share of the agents!")
+		}
+	}
+	// the original loadmodel is not modified
+	if config["Loadmodel"].([]interface{})[0].(map[string]interface{})["Users"] != 5.0 {
+		t.Errorf("Loadmodel of the controller was modified!")
+	}
+}
+
+func TestSplitLoadmodelRateUneven(t *testing.T) {
+	config := map[string]interface{}{
+		"Loadmodel": []interface{}{
+			map[string]interface{}{"Testcase": "01_tc", "Runfor": 10.0, "Users": 5.0, "Rate": 10.0},
+		},
+	}
+
+	for nbr, exp := range [][]float64{{2, 4}, {2, 4}, {1, 2}} {
+		loadmodel, err := splitLoadmodel(config, nbr, 3)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+		tc1 := loadmodel["Loadmodel"].([]interface{})[0].(map[string]interface{})
+		share := []float64{tc1["Users"].(float64), tc1["Rate"].(float64)}
+		if !reflect.DeepEqual(share, exp) {
+			t.Errorf("Share of agent %d not as expected: %v", nbr, share)
+		}
+	}
+}
+
+func TestSplitLoadmodelRateWithLessUsersThanAgents(t *testing.T) {
+	config := map[string]interface{}{
+		"Loadmodel": []interface{}{
+			map[string]interface{}{"Testcase": "01_tc", "Runfor": 10.0, "Users": 2.0, "Rate": 6.0},
+		},
+	}
+
+	_, err := splitLoadmodel(config, 0, 3)
+	if err == nil || err.Error() != "testcase 01_tc needs at least one user per agent "+
+		"to split its rate among 3 agents" {
+		t.Errorf("Error msg not as expected: %v", err)
+	}
+}
+
+func TestAgentsWithoutController(t *testing.T) {
+	fake := NewTest()
+	if _, err := fake.RegisterAgent("localhost"); err != errNoController {
+		t.Errorf("Error not as expected: %v", err)
+	}
+	if _, err := fake.AgentCommand("agent-1"); err != errNoController {
+		t.Errorf("Error not as expected: %v", err)
+	}
+	if agents := fake.Agents(); len(agents) != 0 {
+		t.Errorf("Agents not as expected: %v", agents)
+	}
+}
+
+// Wait until the test is running. Do not freeze the time while waiting, the
+// controller would consider the agents lost.
+func waitForRunning(t *testing.T, test *TestScenario) {
+	for i := 0; test.Status() != Running; i++ {
+		if i > 5000 {
+			t.Fatalf("Test is expected to be running but is: %v", test.Status())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestControllerRun(t *testing.T) {
+	fake := NewTest()
+	fake.EnableController()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{
+		map[string]interface{}{"Testcase": "01_tc", "Runfor": 10.0, "Users": 3.0, "Pacing": 1.0}}
+	fake.Testscenario("scenario1", func() {})
+
+	id1, _ := fake.RegisterAgent("host1")
+	id2, _ := fake.RegisterAgent("host2")
+	if id1 != "agent-1" || id2 != "agent-2" {
+		t.Fatalf("Agent ids not as expected: %s, %s", id1, id2)
+	}
+	if cmd, _ := fake.AgentCommand(id1); cmd.Command != "wait" {
+		t.Errorf("Command before the run not as expected: %v", cmd)
+	}
+
+	done := make(chan error)
+	go func() { done <- fake.Exec() }()
+	waitForRunning(t, fake)
+
+	cmd, _ := fake.AgentCommand(id1)
+	if cmd.Command != "start" || cmd.Loadmodel["Loadmodel"].([]interface{})[0].(map[string]interface{})["Users"] != 2.0 {
+		t.Errorf("Command for agent-1 not as expected: %v", cmd)
+	}
+	if cmd, _ := fake.AgentCommand(id1); cmd.Command != "wait" {
+		t.Errorf("Agents are expected to be started only once: %v", cmd)
+	}
+	agents := fake.Agents()
+	if len(agents) != 2 || agents[0].Host != "host1" || !agents[0].Running {
+		t.Errorf("Agents not as expected: %v", agents)
+	}
+
+	now := Timestamp(time.Now())
+	fake.AgentMeasurements(id1, []Meta{{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond), Timestamp: now}})
+	fake.AgentMeasurements(id2, []Meta{{Teststep: "sth", Elapsed: Elapsed(2 * time.Millisecond), Timestamp: now},
+		{Teststep: "sth", Elapsed: Elapsed(5 * time.Millisecond), Timestamp: now}})
+	fake.AgentDone(id1)
+	fake.AgentDone(id1) // done only counts once
+	if err := fake.AgentMeasurements(id1, []Meta{{Teststep: "sth"}}); err == nil {
+		t.Errorf("Measurements of an agent that is done are expected to be rejected!")
+	}
+	fake.AgentDone(id2)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	results := fake.Results("")
+	if len(results) != 1 || results[0].Count != 3 || results[0].Avg != 5.0 {
+		t.Errorf("Results not as expected: %v", results)
+	}
+}
+
+func TestControllerStop(t *testing.T) {
+	fake := NewTest()
+	fake.EnableController()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{}
+	fake.Testscenario("scenario1", func() {})
+	id, _ := fake.RegisterAgent("host1")
+
+	done := make(chan error)
+	go func() { done <- fake.Exec() }()
+	waitForRunning(t, fake)
+	fake.AgentCommand(id) // start
+	fake.Stop()
+	if cmd, _ := fake.AgentCommand(id); cmd.Command != "stop" {
+		t.Errorf("Command not as expected: %v", cmd)
+	}
+	fake.AgentDone(id)
+	<-done
+}
+
+func TestControllerWithoutAgents(t *testing.T) {
+	fake := NewTest()
+	fake.EnableController()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{}
+	fake.Testscenario("scenario1", func() {})
+
+	if err := fake.Exec(); err == nil || err.Error() != "no agents registered" {
+		t.Errorf("Error not as expected: %v", err)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"sort"
+	"strconv"
+	"sync"
 
 	"github.com/xeipuuv/gojsonschema"
 	"os"
@@ -17,16 +20,32 @@ type Config interface {
 	GetSettings() Settings
 	GetScenarioConfig() (string, float64, float64, float64)
 	GetTestcaseConfig(testcase string) (float64, float64, float64, int, float64, error)
+	GetTestcaseRate(testcase string) (float64, error)
+	GetTestcaseStages(testcase string) ([]Stage, error)
+	GetTestcaseMaxFailures(testcase string) int
+	GetPercentiles() []float64
+	GetTimeseriesInterval() float64
+	GetThresholds() ([]Threshold, error)
 	GetConfigMap() map[string]interface{}
 	GetConfigMTime() time.Time
 }
 
 type Settings map[string]interface{}
 
+// Stage of a load profile. The target number of users changes linearly from the
+// target of the previous stage to Users within Duration (in Seconds).
+// A Duration of 0 results in a sudden change (spike).
+type Stage struct {
+	Duration float64
+	Users    int
+}
+
 type TestConfig struct {
-	config   map[string]interface{} // datastructure to hold the json config loaded from file
-	filename string
-	mtime    time.Time
+	configLock sync.RWMutex           // lock that is used on config (it can change during the test)
+	config     map[string]interface{} // datastructure to hold the json config loaded from file
+	filename   string
+	mtime      time.Time
+	changed    chan bool // closed when the config changes (see configChanged)
 }
 
 // Default schema to validate loadmodel.json files.
@@ -39,6 +58,33 @@ var LoadmodelSchema string = `{
         "ThinkTimeFactor":   { "type": "number" },
         "ThinkTimeVariance": { "type": "number" },
         "PacingVariance":    { "type": "number" },
+        "SharedPools":       { "type": "boolean" },
+        "Thresholds": {
+            "type": "array",
+            "items": {
+                "type": "object",
+                "properties": {
+                    "Teststep":       { "type": "string" },
+                    "MaxAvg":         { "type": "number" },
+                    "MaxPercentiles": {
+                        "type": "object",
+                        "patternProperties": {
+                            "^[0-9]+(\\.[0-9]+)?$": { "type": "number" }
+                        },
+                        "additionalProperties": false
+                    },
+                    "MaxErrorRate":   { "type": "number" },
+                    "MinThroughput":  { "type": "number" },
+                    "Abort":          { "type": "boolean" }
+                },
+                "additionalProperties": false
+            }
+        },
+        "TimeseriesInterval": { "type": "number", "minimum": 0, "exclusiveMinimum": true },
+        "Percentiles": {
+            "type": "array",
+            "items": { "type": "number", "minimum": 0, "maximum": 100 }
+        },
         "Loadmodel": {
             "type":"array",
             "items": {
@@ -49,9 +95,27 @@ var LoadmodelSchema string = `{
                     "Runfor":     { "type": "number" },
                     "Rampup":     { "type": "number" },
                     "Users":      { "type": "integer" },
-                    "Pacing":     { "type": "number" }
+                    "Pacing":     { "type": "number" },
+                    "Rate":       { "type": "number", "minimum": 0, "exclusiveMinimum": true },
+                    "MaxFailures": { "type": "integer", "minimum": 0 },
+                    "Stages": {
+                        "type": "array",
+                        "items": {
+                            "type": "object",
+                            "properties": {
+                                "Duration": { "type": "number", "minimum": 0 },
+                                "Users":    { "type": "integer", "minimum": 0 }
+                            },
+                            "required": ["Duration", "Users"],
+                            "additionalProperties": false
+                        }
+                    }
                 },
-                "required": ["Testcase", "Runfor", "Users", "Pacing"],
+                "required": ["Testcase", "Runfor", "Users"],
+                "anyOf": [
+                    { "required": ["Pacing"] },
+                    { "required": ["Rate"] }
+                ],
                 "additionalProperties": false
             }
         }
@@ -95,12 +159,28 @@ func (test *TestConfig) ReadConfigValidate(document string, schema string) error
 		return fmt.Errorf(msg)
 	}
 
-	return json.Unmarshal([]byte(document), &test.config)
+	// the new document is merged into the existing config
+	config := make(map[string]interface{})
+	if err = json.Unmarshal([]byte(document), &config); err != nil {
+		return err
+	}
+	test.configLock.Lock()
+	defer test.configLock.Unlock()
+	if test.config == nil {
+		test.config = make(map[string]interface{})
+	}
+	for k, v := range config {
+		test.config[k] = v
+	}
+	test.notifyChanged()
+	return nil
 }
 
 // Write the loadmodel to file with the given filename.
 func (test *TestConfig) WriteConfig() error {
+	test.configLock.RLock()
 	out, err := json.Marshal(test.config)
+	test.configLock.RUnlock()
 	if err != nil {
 		return err
 	}
@@ -126,6 +206,8 @@ func (test *TestConfig) WriteConfig() error {
 
 // Return ThinkTimeFactor, ThinkTimeVariance from the loadmodel configuration.
 func (test *TestConfig) GetScenarioConfig() (string, float64, float64, float64) {
+	test.configLock.RLock()
+	defer test.configLock.RUnlock()
 	// defaults for optional properties
 	ttf := 1.0
 	ttv := 0.0
@@ -144,36 +226,171 @@ func (test *TestConfig) GetScenarioConfig() (string, float64, float64, float64)
 	return scenario, ttf, ttv, pv
 }
 
+// Find the loadmodel entry for the given testcase.
+// The entries are replaced as a whole on config changes so the caller can
+// read the entry without holding the lock.
+func (test *TestConfig) getTestcaseEntry(testcase string) (map[string]interface{}, bool) {
+	test.configLock.RLock()
+	defer test.configLock.RUnlock()
+	if conf, ok := test.config["Loadmodel"].([]interface{}); ok {
+		for _, tc := range conf {
+			entry := tc.(map[string]interface{})
+			if entry["Testcase"] == testcase {
+				return entry, true
+			}
+		}
+	}
+	return nil, false
+}
+
 // Return delay, runfor, rampup, users, pacing from the loadmodel configuration.
 func (test *TestConfig) GetTestcaseConfig(testcase string) (float64, float64, float64, int, float64, error) {
-	if conf, ok := test.config["Loadmodel"]; ok {
-		if len(conf.([]interface{})) > 0 {
-			for _, tc := range conf.([]interface{}) {
-				entry := tc.(map[string]interface{})
-				if entry["Testcase"] == testcase {
-					// defaults for optional properties
-					delay := 0.0
-					rampup := 0.0
-					if d, ok := entry["Delay"].(float64); ok {
-						delay = d
-					}
-					if r, ok := entry["Rampup"].(float64); ok {
-						rampup = r
-					}
-					// required properties
-					runfor := entry["Runfor"].(float64)
-					// Note: the JSON format itself has no integers (unlike JSON Schema). In JSON all values are float64.
-					users := int(entry["Users"].(float64))
-					pacing := entry["Pacing"].(float64)
-					return delay, runfor, rampup, users, pacing, nil
-				}
-			}
+	if entry, ok := test.getTestcaseEntry(testcase); ok {
+		// defaults for optional properties
+		delay := 0.0
+		rampup := 0.0
+		pacing := 0.0 // testcases using an arrival rate have no pacing
+		if d, ok := entry["Delay"].(float64); ok {
+			delay = d
+		}
+		if r, ok := entry["Rampup"].(float64); ok {
+			rampup = r
 		}
+		if p, ok := entry["Pacing"].(float64); ok {
+			pacing = p
+		}
+		// required properties
+		runfor := entry["Runfor"].(float64)
+		// Note: the JSON format itself has no integers (unlike JSON Schema). In JSON all values are float64.
+		users := int(entry["Users"].(float64))
+		return delay, runfor, rampup, users, pacing, nil
 	}
 	// configuration not found!
 	return 0.0, 0.0, 0.0, 0, 0.0, fmt.Errorf("config for testcase %s not found", testcase)
 }
 
+// Return the arrival rate (iterations per second) from the loadmodel configuration.
+// A rate of 0.0 means the testcase is not scheduled by rate but by users and pacing.
+func (test *TestConfig) GetTestcaseRate(testcase string) (float64, error) {
+	if entry, ok := test.getTestcaseEntry(testcase); ok {
+		if r, ok := entry["Rate"].(float64); ok {
+			return r, nil
+		}
+		return 0.0, nil
+	}
+	// configuration not found!
+	return 0.0, fmt.Errorf("config for testcase %s not found", testcase)
+}
+
+// Return the number of consecutive failed iterations after which a user of the
+// testcase stops. 0 means the users never stop because of failures.
+func (test *TestConfig) GetTestcaseMaxFailures(testcase string) int {
+	if entry, ok := test.getTestcaseEntry(testcase); ok {
+		if m, ok := entry["MaxFailures"].(float64); ok {
+			return int(m)
+		}
+	}
+	return 0
+}
+
+// Return the percentiles (e.g. 90, 95, 99) to report from the loadmodel configuration.
+func (test *TestConfig) GetPercentiles() []float64 {
+	test.configLock.RLock()
+	defer test.configLock.RUnlock()
+	percentiles := []float64{}
+	if conf, ok := test.config["Percentiles"].([]interface{}); ok {
+		for _, p := range conf {
+			percentiles = append(percentiles, p.(float64))
+		}
+	}
+	return percentiles
+}
+
+// Return the interval of the timeseries buckets in Seconds from the loadmodel configuration.
+func (test *TestConfig) GetTimeseriesInterval() float64 {
+	test.configLock.RLock()
+	defer test.configLock.RUnlock()
+	// default for optional property
+	interval := 5.0
+	if i, ok := test.config["TimeseriesInterval"].(float64); ok {
+		interval = i
+	}
+	return interval
+}
+
+// Return the thresholds from the loadmodel configuration. Every limit of a
+// Thresholds entry results in a separate Threshold.
+func (test *TestConfig) GetThresholds() ([]Threshold, error) {
+	test.configLock.RLock()
+	defer test.configLock.RUnlock()
+	thresholds := []Threshold{}
+	conf, ok := test.config["Thresholds"].([]interface{})
+	if !ok {
+		return thresholds, nil
+	}
+	for _, th := range conf {
+		entry := th.(map[string]interface{})
+		// defaults for optional properties
+		teststep := ""
+		abort := false
+		if t, ok := entry["Teststep"].(string); ok {
+			teststep = t
+		}
+		if a, ok := entry["Abort"].(bool); ok {
+			abort = a
+		}
+		if l, ok := entry["MaxAvg"].(float64); ok {
+			thresholds = append(thresholds, Threshold{teststep, "avg_ms", 0, l, abort})
+		}
+		if ps, ok := entry["MaxPercentiles"].(map[string]interface{}); ok {
+			keys := []string{}
+			for k := range ps {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				p, err := strconv.ParseFloat(k, 64)
+				if err != nil {
+					return nil, fmt.Errorf("threshold percentile %s is not a number", k)
+				}
+				thresholds = append(thresholds, Threshold{teststep, pname(p) + "_ms", p,
+					ps[k].(float64), abort})
+			}
+		}
+		if l, ok := entry["MaxErrorRate"].(float64); ok {
+			thresholds = append(thresholds, Threshold{teststep, "error_rate", 0, l, abort})
+		}
+		if l, ok := entry["MinThroughput"].(float64); ok {
+			thresholds = append(thresholds, Threshold{teststep, "throughput", 0, l, abort})
+		}
+	}
+	return thresholds, nil
+}
+
+// Return the stages of the load profile from the loadmodel configuration.
+// Stages are optional so a testcase without stages returns an empty profile.
+func (test *TestConfig) GetTestcaseStages(testcase string) ([]Stage, error) {
+	entry, ok := test.getTestcaseEntry(testcase)
+	if !ok {
+		// configuration not found!
+		return nil, fmt.Errorf("config for testcase %s not found", testcase)
+	}
+	stages := []Stage{}
+	if conf, ok := entry["Stages"].([]interface{}); ok {
+		users := int(entry["Users"].(float64))
+		for _, st := range conf {
+			s := st.(map[string]interface{})
+			stage := Stage{s["Duration"].(float64), int(s["Users"].(float64))}
+			if stage.Users > users {
+				return nil, fmt.Errorf("stage of testcase %s exceeds the maximum of %d users",
+					testcase, users)
+			}
+			stages = append(stages, stage)
+		}
+	}
+	return stages, nil
+}
+
 // Return map containing additional properties from the json configuration file.
 func (test *TestConfig) GetSettings() Settings {
 	// defaults for optional properties
@@ -192,12 +409,26 @@ func (test *TestConfig) GetSettings() Settings {
 		if key == "PacingVariance" {
 			return true
 		}
+		if key == "Percentiles" {
+			return true
+		}
+		if key == "TimeseriesInterval" {
+			return true
+		}
+		if key == "Thresholds" {
+			return true
+		}
+		if key == "SharedPools" {
+			return true
+		}
 		if key == "Loadmodel" {
 			return true
 		}
 		return false
 	}
 
+	test.configLock.RLock()
+	defer test.configLock.RUnlock()
 	for k, v := range test.config {
 		if !stdProperty(k) {
 			opts[k] = v
@@ -206,9 +437,15 @@ func (test *TestConfig) GetSettings() Settings {
 	return opts
 }
 
-// Get the Json config data.
+// Get the Json config data (a copy since the config can change during the test).
 func (test *TestConfig) GetConfigMap() map[string]interface{} {
-	return test.config
+	test.configLock.RLock()
+	defer test.configLock.RUnlock()
+	config := make(map[string]interface{}, len(test.config))
+	for k, v := range test.config {
+		config[k] = v
+	}
+	return config
 }
 
 // Query the timestamp (mtime) of the config file.
@@ -3,6 +3,7 @@ package gogrinder
 import (
 	"io/ioutil"
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -84,6 +85,47 @@ func TestGetScenarioConfigUsingDefaults(t *testing.T) {
 	}
 }
 
+func TestGetPercentiles(t *testing.T) {
+	fake := NewTest()
+	loadmodel := `{
+	  "Scenario": "baseline",
+	  "Percentiles": [90, 95, 99.9]
+	}`
+	err := fake.ReadConfigValidate(loadmodel, LoadmodelSchema)
+	if err != nil {
+		t.Fatalf("Error while reading loadmodel config: %s!", err.Error())
+	}
+
+	percentiles := fake.GetPercentiles()
+	if !reflect.DeepEqual(percentiles, []float64{90, 95, 99.9}) {
+		t.Errorf("Percentiles %v not as expected!", percentiles)
+	}
+	if _, ok := fake.GetSettings()["Percentiles"]; ok {
+		t.Errorf("Error: additional properties must not contain 'Percentiles'!")
+	}
+
+	// percentiles are optional
+	fake = NewTest()
+	if percentiles := fake.GetPercentiles(); len(percentiles) != 0 {
+		t.Errorf("Default Percentiles %v not as expected!", percentiles)
+	}
+}
+
+func TestGetTimeseriesInterval(t *testing.T) {
+	fake := NewTest()
+	if interval := fake.GetTimeseriesInterval(); interval != 5.0 {
+		t.Errorf("Default TimeseriesInterval %f not as expected!", interval)
+	}
+
+	fake.config["TimeseriesInterval"] = 10.0
+	if interval := fake.GetTimeseriesInterval(); interval != 10.0 {
+		t.Errorf("TimeseriesInterval %f not as expected!", interval)
+	}
+	if _, ok := fake.GetSettings()["TimeseriesInterval"]; ok {
+		t.Errorf("Error: additional properties must not contain 'TimeseriesInterval'!")
+	}
+}
+
 func TestGetTestcaseConfig(t *testing.T) {
 	expDelay, expRampup, expUsers, expRunfor, expPacing := 10.0, 20.0, 30.0, 40.0, 50.0
 
@@ -184,6 +226,169 @@ func TestGetTestcaseConfigMissingTestcase(t *testing.T) {
 	}
 }
 
+func TestGetTestcaseRate(t *testing.T) {
+	tc1 := make(map[string]interface{})
+	tc1["Testcase"] = "testcase1"
+	tc1["Users"] = 10.0
+	tc1["Runfor"] = 20.0
+	tc1["Rate"] = 50.0
+
+	fake := NewTest()
+	l := make([]interface{}, 1)
+	l[0] = tc1
+	fake.config["Loadmodel"] = l
+
+	rate, err := fake.GetTestcaseRate("testcase1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s!", err.Error())
+	}
+	if rate != 50.0 {
+		t.Errorf("Rate %f not as expected!", rate)
+	}
+
+	// a rate based testcase has no pacing
+	_, _, _, users, pacing, _ := fake.GetTestcaseConfig("testcase1")
+	if users != 10 {
+		t.Errorf("Users %d not as expected!", users)
+	}
+	if pacing != 0.0 {
+		t.Errorf("Default Pacing %f not as expected!", pacing)
+	}
+}
+
+func TestGetTestcaseMaxFailures(t *testing.T) {
+	tc1 := make(map[string]interface{})
+	tc1["Testcase"] = "testcase1"
+	tc1["Users"] = 1.0
+	tc1["Runfor"] = 20.0
+	tc1["Pacing"] = 30.0
+	tc1["MaxFailures"] = 5.0
+
+	fake := NewTest()
+	l := make([]interface{}, 1)
+	l[0] = tc1
+	fake.config["Loadmodel"] = l
+
+	if max := fake.GetTestcaseMaxFailures("testcase1"); max != 5 {
+		t.Errorf("MaxFailures %d not as expected!", max)
+	}
+	// default: users never stop because of failures
+	if max := fake.GetTestcaseMaxFailures("testcase2"); max != 0 {
+		t.Errorf("MaxFailures %d not as expected!", max)
+	}
+}
+
+func TestGetTestcaseRateUsingDefaults(t *testing.T) {
+	tc1 := make(map[string]interface{})
+	tc1["Testcase"] = "testcase1"
+	tc1["Users"] = 1.0
+	tc1["Runfor"] = 20.0
+	tc1["Pacing"] = 30.0
+
+	fake := NewTest()
+	l := make([]interface{}, 1)
+	l[0] = tc1
+	fake.config["Loadmodel"] = l
+
+	rate, _ := fake.GetTestcaseRate("testcase1")
+	if rate != 0.0 {
+		t.Errorf("Default Rate %f not as expected!", rate)
+	}
+
+	_, err := fake.GetTestcaseRate("testcase2")
+	e := err.Error()
+	if e != "config for testcase testcase2 not found" {
+		t.Errorf("Error handling for missing testcase configuration not as expected: %s!", e)
+	}
+}
+
+func TestReadLoadmodelSchemaWithRate(t *testing.T) {
+	fake := NewTest()
+	rateLoadmodel := `{
+	  "Scenario": "scenario1",
+	  "Loadmodel": [
+		{"Rate":50,"Runfor":0.95,"Testcase":"01_testcase","Users":10},
+		{"Pacing":0,"Runfor":0.95,"Testcase":"02_testcase","Users":10}
+	  ]
+	}`
+
+	err := fake.ReadConfigValidate(rateLoadmodel, LoadmodelSchema)
+	if err != nil {
+		t.Fatalf("Error while reading loadmodel config: %s!", err.Error())
+	}
+}
+
+func TestReadLoadmodelSchemaWithoutPacingOrRate(t *testing.T) {
+	fake := NewTest()
+	invalid := `{
+	  "Scenario": "scenario1",
+	  "Loadmodel": [
+		{"Runfor":0.95,"Testcase":"01_testcase","Users":10}
+	  ]
+	}`
+
+	err := fake.ReadConfigValidate(invalid, LoadmodelSchema)
+	if err == nil {
+		t.Fatalf("Expected an error for a testcase without Pacing or Rate!")
+	}
+}
+
+func TestGetTestcaseStages(t *testing.T) {
+	fake := NewTest()
+	stagesLoadmodel := `{
+	  "Scenario": "scenario1",
+	  "Loadmodel": [
+		{"Pacing":0,"Runfor":60,"Testcase":"01_testcase","Users":20,"Stages":[
+		  {"Duration":10,"Users":10},
+		  {"Duration":0,"Users":20},
+		  {"Duration":20,"Users":0}
+		]},
+		{"Pacing":0,"Runfor":60,"Testcase":"02_testcase","Users":10}
+	  ]
+	}`
+	err := fake.ReadConfigValidate(stagesLoadmodel, LoadmodelSchema)
+	if err != nil {
+		t.Fatalf("Error while reading loadmodel config: %s!", err.Error())
+	}
+
+	stages, err := fake.GetTestcaseStages("01_testcase")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s!", err.Error())
+	}
+	exp := []Stage{{10, 10}, {0, 20}, {20, 0}}
+	if !reflect.DeepEqual(stages, exp) {
+		t.Errorf("Stages %v not as expected!", stages)
+	}
+
+	// stages are optional
+	stages, _ = fake.GetTestcaseStages("02_testcase")
+	if len(stages) != 0 {
+		t.Errorf("Default Stages %v not as expected!", stages)
+	}
+}
+
+func TestGetTestcaseStagesExceedUsers(t *testing.T) {
+	fake := NewTest()
+	stagesLoadmodel := `{
+	  "Scenario": "scenario1",
+	  "Loadmodel": [
+		{"Pacing":0,"Runfor":60,"Testcase":"01_testcase","Users":5,"Stages":[
+		  {"Duration":10,"Users":10}
+		]}
+	  ]
+	}`
+	err := fake.ReadConfigValidate(stagesLoadmodel, LoadmodelSchema)
+	if err != nil {
+		t.Fatalf("Error while reading loadmodel config: %s!", err.Error())
+	}
+
+	_, err = fake.GetTestcaseStages("01_testcase")
+	e := err.Error()
+	if e != "stage of testcase 01_testcase exceeds the maximum of 5 users" {
+		t.Errorf("Error msg for stage exceeding users not as expected: %s", e)
+	}
+}
+
 func TestReadLoadmodelSchema(t *testing.T) {
 	fake := NewTest()
 
@@ -286,6 +491,7 @@ func TestGetSettings(t *testing.T) {
 	  "ThinkTimeFactor": 2.0,
 	  "ThinkTimeVariance": 0.0,
 	  "PacingVariance": 0.0,
+	  "SharedPools": true,
 	  "AdditionalProperty": 123
 	}`
 	err := fake.ReadConfigValidate(loadmodel, LoadmodelSchema)
@@ -308,6 +514,9 @@ func TestGetSettings(t *testing.T) {
 	if _, ok := opts["ThinkTimeVariance"]; ok {
 		t.Errorf("Error: additional properties must not contain 'ThinkTimeVariance'!")
 	}
+	if _, ok := opts["SharedPools"]; ok {
+		t.Errorf("Error: additional properties must not contain 'SharedPools'!")
+	}
 	if _, ok := opts["PacingVariance"]; ok {
 		t.Errorf("Error: additional properties must not contain 'PacingVariance'!")
 	}
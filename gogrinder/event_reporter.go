@@ -1,9 +1,13 @@
 package gogrinder
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"regexp"
+
+	time "github.com/finklabs/ttime"
 )
 
 type Reporter interface {
@@ -12,7 +16,13 @@ type Reporter interface {
 
 // EventReporter
 type EventReporter struct {
-	logfile *os.File
+	logfile *bufio.Writer
+}
+
+// Create an EventReporter that writes the event-log to the given file. The
+// entries are buffered until the collector flushes the reporters.
+func NewEventReporter(logfile io.Writer) *EventReporter {
+	return &EventReporter{bufio.NewWriter(logfile)}
 }
 
 // Log metrics to the event-log.
@@ -22,3 +32,120 @@ func (r *EventReporter) Update(m Metric) {
 	r.logfile.Write(s)
 	fmt.Fprintln(r.logfile)
 }
+
+// Write the buffered entries to the event-log.
+func (r *EventReporter) Flush() error {
+	return r.logfile.Flush()
+}
+
+// Filter for the measurements that are replayed from an event-log.
+type EventLogFilter struct {
+	From     time.Duration  // start of the time window (offset to the earliest measurement)
+	To       time.Duration  // end of the time window (offset to the earliest measurement; 0 = open)
+	Testcase *regexp.Regexp // testcases to include (nil = all)
+	Teststep *regexp.Regexp // teststeps to include (nil = all)
+}
+
+// Check if the measurement passes the filter. <first> is the earliest
+// timestamp of the event-log.
+func (f EventLogFilter) match(m *Meta, first time.Time) bool {
+	if f.From != 0 || f.To != 0 {
+		offset := time.Time(m.Timestamp).Sub(first)
+		if offset < f.From || (f.To > 0 && offset >= f.To) {
+			return false
+		}
+	}
+	if f.Testcase != nil && !f.Testcase.MatchString(m.Testcase) {
+		return false
+	}
+	if f.Teststep != nil && !f.Teststep.MatchString(m.Teststep) {
+		return false
+	}
+	return true
+}
+
+// Decoders of the event-log entries of metrics other than Meta.
+var eventLogDecoders []func(entry []byte) (Metric, bool)
+
+// Register a decoder for the event-log entries of a metric type (e.g. the req
+// package registers req.HttpMetric) so the replay keeps all fields of the
+// measurements. The decoder returns false if the entry is not of its type.
+// Entries no decoder recognises are replayed as Meta.
+func RegisterEventLogDecoder(decode func(entry []byte) (Metric, bool)) {
+	eventLogDecoders = append(eventLogDecoders, decode)
+}
+
+// Measurement of the event-log in the shape of its metric type.
+func decodeEventLogEntry(entry []byte, meta *Meta) Metric {
+	for _, decode := range eventLogDecoders {
+		if m, ok := decode(entry); ok {
+			return m
+		}
+	}
+	return meta
+}
+
+// Replay the measurements of an existing event-log into the statistics (and the
+// plugged in reporters) so the reports can be created after the test. Only
+// measurements that pass the filter are replayed. The reporters are started and
+// ended like in a test run and the thresholds are checked, too.
+func (test *TestScenario) ReadEventLog(r io.Reader, filter EventLogFilter) error {
+	thresholds, err := test.GetThresholds()
+	if err != nil {
+		return err
+	}
+
+	// the entries are written when the teststeps end so the log is not ordered
+	// by timestamp. The time window starts at the earliest measurement.
+	type entry struct {
+		meta   *Meta  // for the filter
+		metric Metric // full shape of the measurement (e.g. req.HttpMetric)
+	}
+	var measurements []entry
+	var first time.Time
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // long error messages
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		m := &Meta{}
+		if err = json.Unmarshal(scanner.Bytes(), m); err != nil {
+			return fmt.Errorf("can not read line %d of the event-log: %s", line, err.Error())
+		}
+		if ts := time.Time(m.Timestamp); first.IsZero() || ts.Before(first) {
+			first = ts
+		}
+		measurements = append(measurements, entry{m, decodeEventLogEntry(scanner.Bytes(), m)})
+	}
+	if err = scanner.Err(); err != nil {
+		return err
+	}
+
+	test.Reset() // clear stats from previous run
+	test.SetPercentiles(test.GetPercentiles()...)
+	test.SetTimeseriesInterval(time.Duration(test.GetTimeseriesInterval() * float64(time.Second)))
+	replay := []Metric{}
+	var start time.Time // earliest of the replayed measurements
+	for _, e := range measurements {
+		if filter.match(e.meta, first) {
+			replay = append(replay, e.metric)
+			if ts := time.Time(e.meta.Timestamp); start.IsZero() || ts.Before(start) {
+				start = ts
+			}
+		}
+	}
+	sel, _, _, _ := test.GetScenarioConfig()
+	test.startReporters(RunInfo{Scenario: sel, Start: start, Loadmodel: test.GetConfigMap()})
+	done := test.Collect() // start the collector
+	for _, m := range replay {
+		test.Update(m)
+	}
+	close(test.measurements)
+	<-done // wait for collector to finish
+
+	start, end := test.Runtime()
+	test.setThresholdResults(test.checkThresholds(thresholds, end.Sub(start), 0))
+	test.endReporters()
+	return nil
+}
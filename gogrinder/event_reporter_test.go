@@ -1,9 +1,12 @@
 package gogrinder
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"regexp"
 	"strings"
 	"testing"
 
@@ -21,7 +24,7 @@ func TestEventReporterUpdateWithSomeMetric(t *testing.T) {
 	fake := NewTest()
 	tmp, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
 	defer os.Remove(tmp.Name())
-	fake.AddReportPlugin(&EventReporter{tmp})
+	fake.AddReportPlugin(NewEventReporter(tmp))
 
 	done := fake.Collect() // this needs a collector to unblock update
 
@@ -31,21 +34,21 @@ func TestEventReporterUpdateWithSomeMetric(t *testing.T) {
 	exp := fmt.Sprintf(`{"testcase":"","teststep":"sth","user":0,"iteration"`+
 		`:0,"ts":"%s","elapsed":8.000000,"status":100}`, now.Format(time.RFC3339Nano))
 
+	close(fake.measurements)
+	<-done // the collector flushes the reporter
+
 	buf, _ := ioutil.ReadFile(tmp.Name())
 	last := strings.TrimSpace(string(buf))
 	if last != exp {
 		t.Errorf("Entry for SomeMetric expected: %s, but got: %s", exp, last)
 	}
-
-	close(fake.measurements)
-	<-done
 }
 
 func TestEventReporterUpdateWithSomeMetricError(t *testing.T) {
 	fake := NewTest()
 	tmp, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
 	defer os.Remove(tmp.Name())
-	fake.AddReportPlugin(&EventReporter{tmp})
+	fake.AddReportPlugin(NewEventReporter(tmp))
 
 	done := fake.Collect() // this needs a collector to unblock update
 
@@ -56,12 +59,172 @@ func TestEventReporterUpdateWithSomeMetricError(t *testing.T) {
 		`:0,"ts":"%s","elapsed":8.000000,"error":"something went wrong!",`+
 		`"status":100}`, now.Format(time.RFC3339Nano))
 
+	close(fake.measurements)
+	<-done // the collector flushes the reporter
+
 	buf, _ := ioutil.ReadFile(tmp.Name())
 	last := strings.TrimSpace(string(buf))
 	if last != exp {
 		t.Errorf("Entry for SomeMetric expected: %s, but got: %s", exp, last)
 	}
+}
 
-	close(fake.measurements)
-	<-done
+func TestReadEventLogReplaysMeasurements(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Thresholds"] = []interface{}{
+		map[string]interface{}{"Teststep": "sth", "MaxAvg": 5.0}}
+
+	log := `{"testcase":"01_tc","teststep":"sth","user":0,"iteration":0,"ts":"2016-01-02T15:04:05Z","elapsed":8.000000,"status":200}
+{"testcase":"01_tc","teststep":"sth","user":1,"iteration":0,"ts":"2016-01-02T15:04:06Z","elapsed":4.000000,"error":"something went wrong!"}
+
+{"testcase":"01_tc","teststep":"else","user":0,"iteration":0,"ts":"2016-01-02T15:04:07.5Z","elapsed":500.000000}
+`
+	err := fake.ReadEventLog(strings.NewReader(log), EventLogFilter{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	results := fake.Results("")
+	if len(results) != 2 {
+		t.Fatalf("Results %v not as expected!", results)
+	}
+	if results[1].Teststep != "sth" || results[1].Avg != 6.0 || results[1].Count != 2 ||
+		results[1].Error != 1 {
+		t.Errorf("Result %v not as expected!", results[1])
+	}
+	first, end := fake.Runtime()
+	if first.UTC().Format(ISO8601) != "2016-01-02T15:04:05Z" ||
+		end.UTC().Format(ISO8601) != "2016-01-02T15:04:08Z" {
+		t.Errorf("Runtime %s - %s not as expected!", first, end)
+	}
+	th := fake.Thresholds()
+	if len(th) != 1 || th[0].Passed || th[0].Value != 6.0 {
+		t.Errorf("Thresholds %v not as expected!", th)
+	}
+}
+
+func TestReadEventLogReporterLifecycle(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	r := &lifecycleReporter{}
+	fake.AddReportPlugin(r)
+
+	log := `{"testcase":"01_tc","teststep":"sth","user":0,"iteration":0,"ts":"2016-01-02T15:04:05Z","elapsed":8.000000}
+`
+	if err := fake.ReadEventLog(strings.NewReader(log), EventLogFilter{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	exp := []string{"start scenario1 scenario1", "update sth", "end sth 1"}
+	if !reflect.DeepEqual(r.calls, exp) {
+		t.Errorf("Lifecycle calls %v not as expected!", r.calls)
+	}
+}
+
+// Metric with an additional field to check the decoders of the event-log.
+type someCodeMetric struct {
+	Meta
+	Code int `json:"code"`
+}
+
+// Reporter that keeps the measurements.
+type recordingReporter struct {
+	metrics []Metric
+}
+
+func (r *recordingReporter) Update(m Metric) {
+	r.metrics = append(r.metrics, m)
+}
+
+func TestReadEventLogDecoder(t *testing.T) {
+	bak := eventLogDecoders
+	defer func() { eventLogDecoders = bak }()
+	RegisterEventLogDecoder(func(entry []byte) (Metric, bool) {
+		m := &someCodeMetric{}
+		if err := json.Unmarshal(entry, m); err != nil || m.Code == 0 {
+			return nil, false
+		}
+		return m, true
+	})
+
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	r := &recordingReporter{}
+	fake.AddReportPlugin(r)
+	log := `{"testcase":"01_tc","teststep":"sth","user":0,"iteration":0,"ts":"2016-01-02T15:04:05Z","elapsed":8.000000,"code":200}
+{"testcase":"01_tc","teststep":"else","user":0,"iteration":0,"ts":"2016-01-02T15:04:06Z","elapsed":8.000000}
+`
+	if err := fake.ReadEventLog(strings.NewReader(log), EventLogFilter{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	replayed := r.metrics
+	if len(replayed) != 2 {
+		t.Fatalf("Replayed measurements %v not as expected!", replayed)
+	}
+	if m, ok := replayed[0].(*someCodeMetric); !ok || m.Code != 200 || m.Teststep != "sth" {
+		t.Errorf("Measurement %v expected in the shape of its metric!", replayed[0])
+	}
+	if _, ok := replayed[1].(*Meta); !ok {
+		t.Errorf("Measurement %v expected as Meta!", replayed[1])
+	}
+}
+
+func TestReadEventLogInvalidLine(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+
+	err := fake.ReadEventLog(strings.NewReader("{\"teststep\":\"sth\"}\nnot json\n"),
+		EventLogFilter{})
+	if err == nil || !strings.HasPrefix(err.Error(), "can not read line 2 of the event-log:") {
+		t.Errorf("Error not as expected: %v", err)
+	}
+}
+
+func TestReadEventLogUnordered(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+
+	// the longer teststep started earlier but ended later
+	log := `{"testcase":"01_tc","teststep":"short","ts":"2016-01-02T15:04:06Z","elapsed":100.000000}
+{"testcase":"01_tc","teststep":"long","ts":"2016-01-02T15:04:05Z","elapsed":2000.000000}
+`
+	if err := fake.ReadEventLog(strings.NewReader(log), EventLogFilter{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if results := fake.Results(""); len(results) != 2 {
+		t.Errorf("Results %v not as expected!", results)
+	}
+
+	// the time window starts at the earliest measurement
+	err := fake.ReadEventLog(strings.NewReader(log), EventLogFilter{From: time.Second})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if results := fake.Results(""); len(results) != 1 || results[0].Teststep != "short" {
+		t.Errorf("Results %v not as expected!", results)
+	}
+}
+
+func TestReadEventLogWithFilter(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+
+	log := `{"testcase":"01_tc","teststep":"01_01_warmup","ts":"2016-01-02T15:04:05Z","elapsed":100.000000}
+{"testcase":"01_tc","teststep":"01_02_sth","ts":"2016-01-02T15:04:35Z","elapsed":8.000000}
+{"testcase":"02_tc","teststep":"02_01_sth","ts":"2016-01-02T15:04:36Z","elapsed":4.000000}
+{"testcase":"01_tc","teststep":"01_02_sth","ts":"2016-01-02T15:04:45Z","elapsed":6.000000}
+{"testcase":"01_tc","teststep":"01_02_sth","ts":"2016-01-02T15:05:05Z","elapsed":2.000000}
+`
+	filter := EventLogFilter{From: 30 * time.Second, To: time.Minute,
+		Testcase: regexp.MustCompile("^01_"), Teststep: regexp.MustCompile("sth$")}
+	err := fake.ReadEventLog(strings.NewReader(log), filter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	results := fake.Results("")
+	if len(results) != 1 || results[0].Teststep != "01_02_sth" || results[0].Count != 2 ||
+		results[0].Avg != 7.0 {
+		t.Errorf("Results %v not as expected!", results)
+	}
 }
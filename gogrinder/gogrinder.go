@@ -6,11 +6,12 @@ package gogrinder
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 
 	log "github.com/Sirupsen/logrus"
-	"github.com/finklabs/graceful"
 	time "github.com/finklabs/ttime"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Modify stdout during testing.
@@ -22,80 +23,212 @@ var ISO8601 = "2006-01-02T15:04:05.999Z"
 // This is the "standard" gogrinder behaviour. If you need a special configuration
 // or setup then maybe you should start with this code.
 func GoGrinder(test Scenario) error {
+	if IsAnalyze() {
+		return Analyze(test)
+	}
 	var err error
-	filename, noExec, noReport, noFrontend, noPrometheus, jtl, port, logLevel, err := GetCLI()
+	opts, err := GetCLI()
 	if err != nil {
 		return err
 	}
-	ll, _ := log.ParseLevel(logLevel)
+	ll, _ := log.ParseLevel(opts.LogLevel)
 	log.SetLevel(ll)
-	err = test.ReadConfig(filename)
+	if opts.Controller != "" {
+		// agent mode: the loadmodel is provided by the controller
+		return NewAgent(test, opts.Controller).Run()
+	}
+	err = test.ReadConfig(opts.Filename)
 	if err != nil {
 		return err
 	}
 
+	// result reporter
+	report := func() {
+		if !opts.NoReport {
+			test.Report(stdout)
+		}
+		writeReports(test, opts.JUnit, opts.HtmlReport)
+	}
+
+	if opts.Agents > 0 {
+		// controller mode: the registered agents execute the scenario
+		test.EnableController()
+	}
+
+	// create the reports from an existing event-log (analyze without filter)
+	if opts.ReadEventLog != "" {
+		return analyze(test, AnalyzeOptions{EventLog: opts.ReadEventLog, Filename: opts.Filename,
+			HtmlReport: opts.HtmlReport, JUnit: opts.JUnit, NoReport: opts.NoReport})
+	}
+
 	// prepare reporter plugins
-	if jtl {
+	if opts.Jtl {
 		// initialize the jtl reporter
 		fj, err := os.OpenFile("results.jtl", os.O_CREATE | os.O_TRUNC | os.O_WRONLY, 0666)
 		if err != nil {
-			log.Error("can not open jtl file: %v", err)
+			log.Errorf("can not open jtl file: %v", err)
 			// we do not need to stop in this case...
+		} else {
+			defer fj.Close()
+			test.AddReportPlugin(NewJtlReporter(fj, test, opts.JtlHeader, opts.JtlXml))
 		}
-		defer fj.Close()
-		test.AddReportPlugin(&JtlReporter{fj})
 	} else {
 		// initialize the event reporter
 		fe, err := os.OpenFile("event-log.txt", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
 		if err != nil {
-			log.Error("can not open event log file: %v", err)
+			log.Errorf("can not open event log file: %v", err)
+		} else {
+			defer fe.Close()
+			test.AddReportPlugin(NewEventReporter(fe))
 		}
-		defer fe.Close()
-		test.AddReportPlugin(&EventReporter{fe})
 	}
+	// close the reporter plugins (e.g. footer of the jtl file) before the files
+	defer func() {
+		if cerr := test.CloseReportPlugins(); cerr != nil {
+			log.Errorf("can not close reporter: %v", cerr)
+		}
+	}()
 
     // result reporter
 	exec := func() {
-		err = test.Exec()
-		if !noReport {
-			test.Report(stdout)
+		if opts.Agents > 0 {
+			// controller mode: wait for the agents to register
+			for len(test.Agents()) < opts.Agents {
+				log.Infof("waiting for %d agents", opts.Agents-len(test.Agents()))
+				time.Sleep(time.Second)
+			}
 		}
+		err = test.Exec()
+		report()
 	}
 
 	frontend := func() {
 		srv := NewTestServer(test)
-		srv.Addr = fmt.Sprintf(":%d", port)
+		srv.Addr = fmt.Sprintf(":%d", opts.Port)
 		err = srv.ListenAndServe()
 	}
 
 	// prometheus reporter needs to "wrap" all test executions
-	var srv *graceful.Server
-	if !noPrometheus {
-		srv = NewPrometheusReporterServer()
-		srv.Addr = fmt.Sprintf(":%d", 9110)
-		go srv.ListenAndServe()
-		// if for example the port is in use we continue...
+	var exporter *PrometheusExporter
+	if !opts.NoPrometheus {
+		// expose the metrics of the prometheus reporters (e.g. req.HttpMetricReporter)
+		collectors := []prometheus.Collector{}
+		for _, r := range test.ReportPlugins() {
+			if c, ok := r.(prometheus.Collector); ok {
+				collectors = append(collectors, c)
+			}
+		}
+		exporter, err = NewPrometheusExporter(opts.Prometheus, collectors...)
+		if err != nil {
+			return err
+		}
+		exporter.Start()
 	}
 
 	// handle the different run modes
 	// invalid mode of noExec && noFrontend is handled in cli.go
-	if noExec {
+	if opts.NoExec {
 		frontend()
 	}
-	if noFrontend {
+	if opts.NoFrontend {
+		if opts.Agents > 0 {
+			// the agents communicate with the controller via the web server
+			srv := NewTestServer(test)
+			srv.Addr = fmt.Sprintf(":%d", opts.Port)
+			go srv.ListenAndServe()
+			defer srv.Stop(time.Second)
+		}
 		exec()
 	}
-	if !noExec && !noFrontend {
+	if !opts.NoExec && !opts.NoFrontend {
 		// this is the "normal" case - webserver is blocking
 		go exec()
 		frontend()
 	}
 
-	// run for another +2 * scrape_interval so we read all metrics in
-	if !noPrometheus {
-		time.Sleep(11 * time.Second)
-		srv.Stop(1 * time.Second)
+	if !opts.NoPrometheus {
+		if opts.Prometheus.PushURL == "" && opts.Prometheus.Addr != "" && opts.Prometheus.ScrapeWait > 0 {
+			// wait for the next scrape so Prometheus reads in the final metrics
+			if !exporter.WaitScrape(opts.Prometheus.ScrapeWait) {
+				log.Warnf("the final metrics were not scraped within %s", opts.Prometheus.ScrapeWait)
+			}
+		}
+		// the final metrics are pushed at the end of the test
+		if perr := exporter.Close(); perr != nil {
+			log.Errorf("can not push metrics: %v", perr)
+		}
 	}
 
 	return err
 }
+
+// Analyze rebuilds the statistics from an existing event-log and creates the
+// reports (e.g. to re-analyse a test excluding the warm-up phase).
+func Analyze(test Scenario) error {
+	opts, err := GetAnalyzeCLI()
+	if err != nil {
+		return err
+	}
+	err = test.ReadConfig(opts.Filename)
+	if err != nil {
+		return err
+	}
+	return analyze(test, opts)
+}
+
+// Replay the event-log of the analyze options and create the reports. The
+// loadmodel must already be read.
+func analyze(test Scenario, opts AnalyzeOptions) error {
+	fr, err := os.Open(opts.EventLog)
+	if err != nil {
+		return err
+	}
+	defer fr.Close()
+	// close the reporter plugins (e.g. footer of the jtl file)
+	defer func() {
+		if cerr := test.CloseReportPlugins(); cerr != nil {
+			log.Errorf("can not close reporter: %v", cerr)
+		}
+	}()
+	if err = test.ReadEventLog(fr, opts.Filter); err != nil {
+		return err
+	}
+	if len(opts.Percentiles) > 0 {
+		test.SetPercentiles(opts.Percentiles...)
+	}
+
+	if !opts.NoReport {
+		test.Report(stdout)
+	}
+	if opts.Csv != "" {
+		c, cerr := test.Csv()
+		if cerr == nil {
+			cerr = ioutil.WriteFile(opts.Csv, []byte(c), 0666)
+		}
+		if cerr != nil {
+			log.Errorf("can not write csv file: %v", cerr)
+		}
+	}
+	writeReports(test, opts.JUnit, opts.HtmlReport)
+	return thresholdError(test.Thresholds())
+}
+
+// Write the junit and html reports (if a filename is given).
+func writeReports(test Scenario, junit string, htmlReport string) {
+	if junit != "" {
+		// junit results for the CI system
+		scenario, _, _, _ := test.GetScenarioConfig()
+		xml, err := test.JUnit(scenario)
+		if err == nil {
+			err = ioutil.WriteFile(junit, []byte(xml), 0666)
+		}
+		if err != nil {
+			log.Errorf("can not write junit file: %v", err)
+		}
+	}
+	if htmlReport != "" {
+		if err := test.HtmlReport(htmlReport); err != nil {
+			log.Errorf("can not write html report: %v", err)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package gogrinder
 
-import ()
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
 
 // Common test utils
 
@@ -28,3 +33,68 @@ func (m someMetric) MarshalJSON() ([]byte, error) {
 */
 
 // GoGrinder tests
+
+func TestAnalyze(t *testing.T) {
+	eventLog, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
+	defer os.Remove(eventLog.Name())
+	eventLog.WriteString(`{"testcase":"01_tc","teststep":"01_01_warmup","ts":"2016-01-02T15:04:05Z","elapsed":100.000000}
+{"testcase":"01_tc","teststep":"01_02_sth","ts":"2016-01-02T15:04:35Z","elapsed":8.000000}
+{"testcase":"01_tc","teststep":"01_02_sth","ts":"2016-01-02T15:04:45Z","elapsed":6.000000,"error":"something went wrong!"}
+`)
+	eventLog.Close()
+	loadmodel, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
+	defer os.Remove(loadmodel.Name())
+	loadmodel.WriteString(`{"Loadmodel": [], "Scenario": "scenario1"}`)
+	loadmodel.Close()
+
+	bak := stdout
+	buf := new(bytes.Buffer)
+	stdout = buf
+	defer func() { stdout = bak }()
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "analyze", "-from", "10s", "-percentiles", "50",
+		eventLog.Name(), loadmodel.Name()}
+
+	err := GoGrinder(NewTest())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if buf.String() != "01_02_sth, 7.000000, 6.000000, 8.000000, 2, 1, 6.029311\n" {
+		t.Errorf("Report output not as expected: %s", buf.String())
+	}
+}
+
+func TestGoGrinderReadEventLog(t *testing.T) {
+	eventLog, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
+	defer os.Remove(eventLog.Name())
+	eventLog.WriteString(`{"testcase":"01_tc","teststep":"01_01_warmup","ts":"2016-01-02T15:04:05Z","elapsed":100.000000}
+{"testcase":"01_tc","teststep":"01_02_sth","ts":"2016-01-02T15:04:35Z","elapsed":8.000000}
+`)
+	eventLog.Close()
+	loadmodel, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
+	defer os.Remove(loadmodel.Name())
+	loadmodel.WriteString(`{"Loadmodel": [], "Scenario": "scenario1"}`)
+	loadmodel.Close()
+
+	bak := stdout
+	buf := new(bytes.Buffer)
+	stdout = buf
+	defer func() { stdout = bak }()
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"gogrinder", "-read-event-log", eventLog.Name(), loadmodel.Name()}
+
+	// -read-event-log replays the event-log like analyze without filter
+	err := GoGrinder(NewTest())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	exp := "01_01_warmup, 100.000000, 100.000000, 100.000000, 1, 0\n" +
+		"01_02_sth, 8.000000, 8.000000, 8.000000, 1, 0\n"
+	if buf.String() != exp {
+		t.Errorf("Report output not as expected: %s", buf.String())
+	}
+}
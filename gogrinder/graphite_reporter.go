@@ -0,0 +1,76 @@
+package gogrinder
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	log "github.com/Sirupsen/logrus"
+	time "github.com/finklabs/ttime"
+)
+
+// GraphiteReporter sends the metrics in the Graphite plaintext protocol over
+// TCP. The metrics are aggregated per teststep and sent every flush interval:
+//
+//	<prefix>.<teststep>.count <count> <timestamp>
+//	<prefix>.<teststep>.errors <errors> <timestamp>
+//	<prefix>.<teststep>.elapsed.mean <mean> <timestamp>
+//	<prefix>.<teststep>.elapsed.min <min> <timestamp>
+//	<prefix>.<teststep>.elapsed.max <max> <timestamp>
+type GraphiteReporter struct {
+	aggregator
+	addr   string
+	prefix string
+	conn   net.Conn // connection to carbon (reconnected after errors)
+}
+
+// Create a GraphiteReporter that sends to the given carbon address (e.g.
+// localhost:2003). The prefix is prepended to the metric names (e.g.
+// "gogrinder"). Use Close to send the last aggregates.
+func NewGraphiteReporter(addr string, prefix string, interval time.Duration) (*GraphiteReporter, error) {
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	r := &GraphiteReporter{addr: addr, prefix: prefix, conn: conn}
+	r.start(interval, r.send)
+	return r, nil
+}
+
+// Send the aggregates. If carbon is not available the aggregates are discarded.
+func (r *GraphiteReporter) send(steps map[string]*aggregate) {
+	var b bytes.Buffer
+	ts := time.Now().Unix()
+	for _, name := range sortedSteps(steps) {
+		s := steps[name]
+		metric := metricName(r.prefix, name)
+		fmt.Fprintf(&b, "%s.count %d %d\n", metric, s.count, ts)
+		fmt.Fprintf(&b, "%s.errors %d %d\n", metric, s.errors, ts)
+		fmt.Fprintf(&b, "%s.elapsed.mean %s %d\n", metric, formatValue(s.sum/float64(s.count)), ts)
+		fmt.Fprintf(&b, "%s.elapsed.min %s %d\n", metric, formatValue(s.min), ts)
+		fmt.Fprintf(&b, "%s.elapsed.max %s %d\n", metric, formatValue(s.max), ts)
+	}
+	if r.conn == nil {
+		conn, err := net.DialTimeout("tcp", r.addr, 10*time.Second)
+		if err != nil {
+			log.Errorf("can not connect to graphite: %v", err)
+			return
+		}
+		r.conn = conn
+	}
+	r.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := r.conn.Write(b.Bytes()); err != nil {
+		log.Errorf("can not send metrics to graphite: %v", err)
+		r.conn.Close()
+		r.conn = nil // reconnect with the next interval
+	}
+}
+
+// Send the last aggregates and close the connection.
+func (r *GraphiteReporter) Close() error {
+	r.aggregator.Close()
+	if r.conn != nil {
+		return r.conn.Close()
+	}
+	return nil
+}
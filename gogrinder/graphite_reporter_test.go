@@ -0,0 +1,70 @@
+package gogrinder
+
+import (
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestCheckGraphiteReporterImplementsReporterInterface(t *testing.T) {
+	s := &GraphiteReporter{}
+	if _, ok := interface{}(s).(Reporter); !ok {
+		t.Errorf("GraphiteReporter does not implement the Reporter interface!")
+	}
+}
+
+func TestGraphiteReporterSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	received := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		defer conn.Close()
+		b, _ := ioutil.ReadAll(conn)
+		received <- string(b)
+	}()
+
+	r, err := NewGraphiteReporter(ln.Addr().String(), "gogrinder", time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r.Update(&Meta{Teststep: "01_home.page", Elapsed: Elapsed(8 * time.Millisecond)})
+	r.Update(&Meta{Teststep: "01_home.page", Elapsed: Elapsed(2 * time.Millisecond), Error: "failed"})
+	ts := time.Now().Unix()
+	r.Close()
+
+	lines := strings.Split(strings.TrimSpace(<-received), "\n")
+	exp := []string{"gogrinder.01_home_page.count 2", "gogrinder.01_home_page.errors 1",
+		"gogrinder.01_home_page.elapsed.mean 5", "gogrinder.01_home_page.elapsed.min 2",
+		"gogrinder.01_home_page.elapsed.max 8"}
+	if len(lines) != len(exp) {
+		t.Fatalf("Lines not as expected: %v", lines)
+	}
+	for i, l := range lines {
+		fields := strings.Fields(l)
+		if len(fields) != 3 || fields[0]+" "+fields[1] != exp[i] ||
+			(fields[2] != strconv.FormatInt(ts, 10) && fields[2] != strconv.FormatInt(ts+1, 10)) {
+			t.Errorf("Line expected: %s %d, but got: %s", exp[i], ts, l)
+		}
+	}
+}
+
+func TestGraphiteReporterNotAvailable(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+	if _, err := NewGraphiteReporter(addr, "gogrinder", time.Hour); err == nil {
+		t.Errorf("Expected an error if carbon is not available!")
+	}
+}
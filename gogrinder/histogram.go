@@ -0,0 +1,100 @@
+package gogrinder
+
+import (
+	"math"
+	"math/bits"
+	"strconv"
+
+	time "github.com/finklabs/ttime"
+)
+
+// The histogram follows the idea of the HdrHistogram (http://hdrhistogram.org/).
+// Values are recorded into log-linear buckets. Every power of two range is
+// split into subBucketHalf sub-buckets so the relative error is below 1/64.
+// Memory is bounded since the number of buckets grows only logarithmically
+// with the largest recorded value. Histograms can be merged.
+const (
+	subBucketBits = 7
+	subBucketHalf = 1 << (subBucketBits - 1)
+)
+
+// Internal datastructure to record a distribution of durations.
+type histogram struct {
+	counts []int64
+	total  int64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]int64, 2*subBucketHalf)}
+}
+
+// Index of the bucket the value is recorded in.
+func bucketIndex(v int64) int {
+	if v < 2*subBucketHalf {
+		return int(v)
+	}
+	exp := bits.Len64(uint64(v)) - subBucketBits
+	return exp*subBucketHalf + int(v>>uint(exp))
+}
+
+// Highest value that is recorded into the bucket with the given index.
+func bucketValue(idx int) int64 {
+	if idx < 2*subBucketHalf {
+		return int64(idx)
+	}
+	exp := idx/subBucketHalf - 1
+	sub := int64(idx - exp*subBucketHalf)
+	return (sub+1)<<uint(exp) - 1
+}
+
+// Record a single duration.
+func (h *histogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	idx := bucketIndex(int64(d))
+	if idx >= len(h.counts) {
+		grow := make([]int64, idx+1)
+		copy(grow, h.counts)
+		h.counts = grow
+	}
+	h.counts[idx]++
+	h.total++
+}
+
+// Merge the recorded values of another histogram into this one.
+func (h *histogram) merge(o *histogram) {
+	if len(o.counts) > len(h.counts) {
+		grow := make([]int64, len(o.counts))
+		copy(grow, h.counts)
+		h.counts = grow
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.total += o.total
+}
+
+// Duration below or equal to which <p> percent of the recorded values fall.
+func (h *histogram) percentile(p float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	target := int64(math.Ceil(p / 100.0 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+	var count int64
+	for idx, c := range h.counts {
+		count += c
+		if count >= target {
+			return time.Duration(bucketValue(idx))
+		}
+	}
+	return time.Duration(bucketValue(len(h.counts) - 1))
+}
+
+// Helper to name a percentile e.g. p90, p99.9.
+func pname(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
@@ -0,0 +1,78 @@
+package gogrinder
+
+import (
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestBucketIndexAndValue(t *testing.T) {
+	// small values are recorded exactly
+	for v := int64(0); v < 2*subBucketHalf; v++ {
+		if got := bucketValue(bucketIndex(v)); got != v {
+			t.Errorf("Value %d recorded as %d!", v, got)
+		}
+	}
+	// larger values are recorded with a relative error below 1/64
+	for _, v := range []int64{128, 129, 255, 256, 1000, 123456789, 3600000000000} {
+		got := bucketValue(bucketIndex(v))
+		if got < v || float64(got-v)/float64(v) > 1.0/64 {
+			t.Errorf("Value %d recorded as %d!", v, got)
+		}
+	}
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	h := newHistogram()
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	for _, c := range []struct {
+		p   float64
+		exp time.Duration
+	}{{50, 500 * time.Millisecond}, {90, 900 * time.Millisecond},
+		{99, 990 * time.Millisecond}, {100, 1000 * time.Millisecond}} {
+		got := h.percentile(c.p)
+		if got < c.exp || float64(got-c.exp)/float64(c.exp) > 1.0/64 {
+			t.Errorf("Percentile %s expected %v but was %v!", pname(c.p), c.exp, got)
+		}
+	}
+}
+
+func TestHistogramPercentileEmpty(t *testing.T) {
+	h := newHistogram()
+	if got := h.percentile(90); got != 0 {
+		t.Errorf("Percentile of empty histogram expected 0 but was %v!", got)
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	h1, h2 := newHistogram(), newHistogram()
+	for i := 0; i < 90; i++ {
+		h1.record(10 * time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		h2.record(10 * time.Second)
+	}
+	h1.merge(h2)
+
+	if h1.total != 100 {
+		t.Errorf("Merged histogram count %d not as expected!", h1.total)
+	}
+	if got := h1.percentile(90); got > 11*time.Millisecond {
+		t.Errorf("Percentile p90 of merged histogram not as expected: %v!", got)
+	}
+	if got := h1.percentile(95); got < 10*time.Second {
+		t.Errorf("Percentile p95 of merged histogram not as expected: %v!", got)
+	}
+}
+
+func TestPercentileName(t *testing.T) {
+	if n := pname(90); n != "p90" {
+		t.Errorf("Percentile name expected p90 but was %s!", n)
+	}
+	if n := pname(99.9); n != "p99.9" {
+		t.Errorf("Percentile name expected p99.9 but was %s!", n)
+	}
+}
@@ -0,0 +1,190 @@
+package gogrinder
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	log "github.com/Sirupsen/logrus"
+	time "github.com/finklabs/ttime"
+)
+
+// Hooks of a testcase. The hooks are executed by every virtual user of the
+// testcase. Their timings are reported as teststeps "<testcase>_<hook>"
+// (e.g. "01_testcase_before_user") so they are not mixed up with the measured
+// teststeps; thresholds without teststep do not apply to them. A panic of a
+// hook is recovered and fails the hook.
+type Hooks struct {
+	BeforeUser      func(*Meta, Settings) error // before the first iteration of the user (e.g. login)
+	AfterUser       func(*Meta, Settings) error // after the last iteration of the user (e.g. logout)
+	BeforeIteration func(*Meta, Settings) error // before every iteration
+	AfterIteration  func(*Meta, Settings) error // after every iteration
+	Abort           bool                        // a failing hook stops the test
+}
+
+// HookError is returned by Exec if a hook failed and aborted the test.
+type HookError struct {
+	Hook string // e.g. before_user
+	Name string // testcase or scenario
+	User int
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	if e.Hook == "before_scenario" || e.Hook == "after_scenario" {
+		return fmt.Sprintf("hook %s of scenario %s failed: %v", e.Hook, e.Name, e.Err)
+	}
+	return fmt.Sprintf("hook %s of testcase %s (user %d) failed: %v", e.Hook, e.Name, e.User, e.Err)
+}
+
+// Register a hook that is executed before the scenario starts (e.g. to seed
+// test data). If the hook fails the scenario is not executed.
+func (test *TestScenario) BeforeScenario(hook func() error) {
+	test.hooksLock.Lock()
+	defer test.hooksLock.Unlock()
+	test.beforeScenario = append(test.beforeScenario, hook)
+}
+
+// Register a hook that is executed after all testcases of the scenario
+// finished (e.g. to clean up test data).
+func (test *TestScenario) AfterScenario(hook func() error) {
+	test.hooksLock.Lock()
+	defer test.hooksLock.Unlock()
+	test.afterScenario = append(test.afterScenario, hook)
+}
+
+// Register the hooks of a testcase. Use "" for the testcase of DoIterations.
+func (test *TestScenario) TestcaseHooks(testcase string, hooks Hooks) {
+	test.hooksLock.Lock()
+	defer test.hooksLock.Unlock()
+	if test.hooks == nil {
+		test.hooks = make(map[string]Hooks)
+	}
+	test.hooks[testcase] = hooks
+}
+
+// Give me the hooks of the testcase.
+func (test *TestScenario) testcaseHooks(testcase string) Hooks {
+	test.hooksLock.Lock()
+	defer test.hooksLock.Unlock()
+	return test.hooks[testcase]
+}
+
+// Remember the first hook error that aborted the test (nil resets it).
+func (test *TestScenario) setHookError(err error) {
+	test.hooksLock.Lock()
+	defer test.hooksLock.Unlock()
+	if err == nil || test.hookErr == nil {
+		test.hookErr = err
+	}
+}
+
+// Give me the hook error that aborted the test.
+func (test *TestScenario) hookError() error {
+	test.hooksLock.Lock()
+	defer test.hooksLock.Unlock()
+	return test.hookErr
+}
+
+// Execute the hooks of the scenario. It stops at the first failing hook.
+func (test *TestScenario) runScenarioHooks(scenario string, name string) error {
+	test.hooksLock.Lock()
+	hooks := test.beforeScenario
+	if name == "after_scenario" {
+		hooks = test.afterScenario
+	}
+	test.hooksLock.Unlock()
+	for _, hook := range hooks {
+		b := test.NewBracket(scenario + "_" + name)
+		err := callHook(name, func() error { return hook() })
+		m := &Meta{Testcase: scenario}
+		if err != nil {
+			m.Error = err.Error()
+		}
+		b.End(m)
+		if err != nil {
+			herr := &HookError{Hook: name, Name: scenario, Err: err}
+			log.Error(herr.Error())
+			return herr
+		}
+	}
+	return nil
+}
+
+// Call the hook and turn a panic into an error so the other users and the
+// results are not affected.
+func callHook(name string, hook func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("panic in hook %s: %v\n%s", name, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return hook()
+}
+
+// Execute a hook of a testcase. It returns false if the hook failed.
+func (test *TestScenario) runHook(name string, hook func(*Meta, Settings) error,
+	abort bool, meta *Meta, settings Settings) bool {
+	if hook == nil {
+		return true
+	}
+	b := test.NewBracket(meta.Testcase + "_" + name)
+	err := callHook(name, func() error { return hook(meta, settings) })
+	m := &Meta{Testcase: meta.Testcase, User: meta.User, Iteration: meta.Iteration}
+	if err != nil {
+		m.Error = err.Error()
+	}
+	b.End(m)
+	if err != nil {
+		herr := &HookError{Hook: name, Name: meta.Testcase, User: meta.User, Err: err}
+		log.Error(herr.Error())
+		if abort {
+			test.setHookError(herr)
+			test.Stop()
+		}
+		return false
+	}
+	return true
+}
+
+// Execute a single iteration of the testcase including the iteration hooks.
+// A panic of the iteration is recovered so the other users are not affected.
+// It is reported as failed teststep "<testcase>_panic". The iteration returns
+// false if it panicked or the before_iteration hook failed (both count towards
+// MaxFailures).
+func (test *TestScenario) iteration(testcase func(*Meta, Settings), hooks Hooks,
+	meta *Meta, settings Settings) (ok bool) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("panic in testcase %s (user %d, iteration %d): %v\n%s",
+				meta.Testcase, meta.User, meta.Iteration, r, debug.Stack())
+			test.Update(&Meta{Testcase: meta.Testcase, Teststep: meta.Testcase + "_panic",
+				User: meta.User, Iteration: meta.Iteration, Timestamp: Timestamp(start),
+				Elapsed: Elapsed(time.Now().Sub(start)), Error: fmt.Sprintf("panic: %v", r)})
+			ok = false
+		}
+	}()
+	if !test.runHook("before_iteration", hooks.BeforeIteration, hooks.Abort, meta, settings) {
+		return false // skip the iteration
+	}
+	testcase(meta, settings)
+	test.runHook("after_iteration", hooks.AfterIteration, hooks.Abort, meta, settings)
+	return true
+}
+
+// Keep track of the consecutive failed iterations of a user. It returns false
+// once the user reached the MaxFailures of the testcase and needs to stop.
+func (test *TestScenario) failures(name string, nbr int, ok bool, count *int) bool {
+	if ok {
+		*count = 0
+		return true
+	}
+	*count++
+	if max := test.GetTestcaseMaxFailures(name); max > 0 && *count >= max {
+		log.Errorf("user %d of testcase %s stopped after %d consecutive failed iterations",
+			nbr, name, *count)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,290 @@
+package gogrinder
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestHookErrorMessage(t *testing.T) {
+	err := &HookError{"before_user", "01_tc", 3, fmt.Errorf("login failed")}
+	if exp := "hook before_user of testcase 01_tc (user 3) failed: login failed"; err.Error() != exp {
+		t.Errorf("Error message not as expected: %s", err.Error())
+	}
+	err = &HookError{Hook: "before_scenario", Name: "scenario1", Err: fmt.Errorf("no data")}
+	if exp := "hook before_scenario of scenario scenario1 failed: no data"; err.Error() != exp {
+		t.Errorf("Error message not as expected: %s", err.Error())
+	}
+}
+
+func TestHooksOrder(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{
+		map[string]interface{}{"Testcase": "01_tc", "Runfor": 0.2, "Users": 2.0, "Pacing": 0.0}}
+
+	var lock sync.Mutex
+	calls := []string{}
+	users := map[int][]string{}
+	record := func(call string) func(*Meta, Settings) error {
+		return func(meta *Meta, s Settings) error {
+			lock.Lock()
+			defer lock.Unlock()
+			if len(users[meta.User]) == 0 || users[meta.User][len(users[meta.User])-1] != call {
+				users[meta.User] = append(users[meta.User], call)
+			}
+			if meta.Session() == nil {
+				t.Errorf("Hook %s is expected to have a session!", call)
+			}
+			return nil
+		}
+	}
+	fake.BeforeScenario(func() error { calls = append(calls, "before_scenario"); return nil })
+	fake.AfterScenario(func() error { calls = append(calls, "after_scenario"); return nil })
+	fake.TestcaseHooks("01_tc", Hooks{
+		BeforeUser:      record("before_user"),
+		AfterUser:       record("after_user"),
+		BeforeIteration: record("before_iteration"),
+		AfterIteration:  record("after_iteration"),
+	})
+	fake.Testscenario("scenario1", func() {
+		calls = append(calls, "scenario")
+		fake.Schedule("01_tc", func(meta *Meta, s Settings) {
+			record("iteration")(meta, s)
+			time.Sleep(10 * time.Millisecond)
+		})
+	})
+
+	if err := fake.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if fmt.Sprint(calls) != "[before_scenario scenario after_scenario]" {
+		t.Errorf("Scenario hooks not as expected: %v", calls)
+	}
+	if len(users) != 2 {
+		t.Fatalf("Expected hooks of 2 users: %v", users)
+	}
+	for user, u := range users {
+		if len(u) < 5 || u[0] != "before_user" || u[1] != "before_iteration" || u[2] != "iteration" ||
+			u[3] != "after_iteration" || u[len(u)-1] != "after_user" {
+			t.Errorf("Hooks of user %d not as expected: %v", user, u)
+		}
+	}
+
+	// hook timings are reported as separate teststeps
+	steps := map[string]bool{}
+	for _, r := range fake.Results("") {
+		steps[r.Teststep] = true
+	}
+	for _, s := range []string{"scenario1_before_scenario", "scenario1_after_scenario",
+		"01_tc_before_user", "01_tc_after_user", "01_tc_before_iteration", "01_tc_after_iteration"} {
+		if !steps[s] {
+			t.Errorf("Expected hook timings for %s: %v", s, steps)
+		}
+	}
+}
+
+func TestHookFailureSkipsUser(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{
+		map[string]interface{}{"Testcase": "01_tc", "Runfor": 0.02, "Users": 1.0, "Pacing": 0.0}}
+
+	iterations := 0
+	afterUser := false
+	fake.TestcaseHooks("01_tc", Hooks{
+		BeforeUser: func(*Meta, Settings) error { return fmt.Errorf("login failed") },
+		AfterUser:  func(*Meta, Settings) error { afterUser = true; return nil },
+	})
+	fake.Testscenario("scenario1", func() {
+		fake.Schedule("01_tc", func(meta *Meta, s Settings) { iterations++ })
+	})
+
+	// without Abort the test continues
+	if err := fake.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	if iterations != 0 || afterUser {
+		t.Errorf("User is expected to be skipped: %d iterations, after user %v", iterations, afterUser)
+	}
+	results := fake.Results("")
+	if len(results) != 1 || results[0].Teststep != "01_tc_before_user" || results[0].Error != 1 {
+		t.Errorf("Results not as expected: %v", results)
+	}
+}
+
+func TestHookFailureAbortsTest(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{
+		map[string]interface{}{"Testcase": "01_tc", "Runfor": 10.0, "Users": 1.0, "Pacing": 0.0}}
+
+	var lock sync.Mutex
+	iterations := 0
+	fake.TestcaseHooks("01_tc", Hooks{
+		BeforeIteration: func(meta *Meta, s Settings) error {
+			if meta.Iteration == 2 {
+				return fmt.Errorf("no more data")
+			}
+			return nil
+		},
+		Abort: true,
+	})
+	fake.Testscenario("scenario1", func() {
+		fake.Schedule("01_tc", func(meta *Meta, s Settings) {
+			lock.Lock()
+			iterations++
+			lock.Unlock()
+		})
+	})
+
+	err := fake.Exec()
+	if err == nil || err.Error() != "hook before_iteration of testcase 01_tc (user 0) failed: no more data" {
+		t.Fatalf("Error not as expected: %v", err)
+	}
+	if _, ok := err.(*HookError); !ok {
+		t.Errorf("Expected a HookError!")
+	}
+	if iterations != 2 {
+		t.Errorf("Expected 2 iterations but got %d!", iterations)
+	}
+}
+
+func TestBeforeScenarioFailure(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	executed := false
+	fake.BeforeScenario(func() error { return fmt.Errorf("can not seed data") })
+	fake.Testscenario("scenario1", func() { executed = true })
+
+	err := fake.Exec()
+	if err == nil || err.Error() != "hook before_scenario of scenario scenario1 failed: can not seed data" {
+		t.Errorf("Error not as expected: %v", err)
+	}
+	if executed {
+		t.Errorf("Scenario is not expected to be executed!")
+	}
+	if fake.Status() != Stopped {
+		t.Errorf("Test is expected to be stopped!")
+	}
+}
+
+func TestHookRecoversPanic(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{
+		map[string]interface{}{"Testcase": "01_tc", "Runfor": 0.02, "Users": 1.0, "Pacing": 0.0}}
+
+	fake.TestcaseHooks("01_tc", Hooks{
+		BeforeUser: func(*Meta, Settings) error { panic("login broken") },
+		Abort:      true,
+	})
+	fake.Testscenario("scenario1", func() {
+		fake.Schedule("01_tc", func(meta *Meta, s Settings) {})
+	})
+
+	err := fake.Exec()
+	if err == nil || err.Error() != "hook before_user of testcase 01_tc (user 0) failed: panic: login broken" {
+		t.Fatalf("Error not as expected: %v", err)
+	}
+	results := fake.Results("")
+	if len(results) != 1 || results[0].Teststep != "01_tc_before_user" || results[0].Error != 1 {
+		t.Errorf("Results not as expected: %v", results)
+	}
+}
+
+func TestAfterScenarioRecoversPanic(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.AfterScenario(func() error { panic("cleanup broken") })
+	fake.Testscenario("scenario1", func() {})
+
+	err := fake.Exec()
+	if err == nil || err.Error() != "hook after_scenario of scenario scenario1 failed: panic: cleanup broken" {
+		t.Errorf("Error not as expected: %v", err)
+	}
+}
+
+func TestIterationRecoversPanic(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{
+		map[string]interface{}{"Testcase": "01_tc", "Runfor": 0.2, "Users": 2.0, "Pacing": 0.0}}
+
+	var iterations int32
+	fake.Testscenario("scenario1", func() {
+		fake.Schedule("01_tc", func(meta *Meta, s Settings) {
+			atomic.AddInt32(&iterations, 1)
+			time.Sleep(10 * time.Millisecond)
+			var resp map[string]interface{}
+			_ = resp["data"].([]interface{}) // panics
+		})
+	})
+
+	if err := fake.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// the users continue after the panic
+	if n := atomic.LoadInt32(&iterations); n < 4 {
+		t.Errorf("Users expected to continue after a panic, but only %d iterations", n)
+	}
+	if v, ok := fake.stats["01_tc_panic"]; ok {
+		if v.count != int64(iterations) || v.count != v.error {
+			t.Errorf("Panics %d (errors %d) not as expected!", v.count, v.error)
+		}
+	} else {
+		t.Errorf("Expected panics to be reported as 01_tc_panic!")
+	}
+}
+
+func TestMaxFailuresStopsUser(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{
+		map[string]interface{}{"Testcase": "01_tc", "Runfor": 10.0, "Users": 2.0, "Pacing": 0.0,
+			"MaxFailures": 3.0}}
+
+	var iterations int32
+	fake.Testscenario("scenario1", func() {
+		fake.Schedule("01_tc", func(meta *Meta, s Settings) {
+			atomic.AddInt32(&iterations, 1)
+			panic("backend down")
+		})
+	})
+
+	if err := fake.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&iterations); n != 6 {
+		t.Errorf("Users expected to stop after 3 consecutive failures, but %d iterations", n)
+	}
+}
+
+func TestMaxFailuresCountsHookFailures(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{
+		map[string]interface{}{"Testcase": "01_tc", "Runfor": 10.0, "Users": 1.0, "Pacing": 0.0,
+			"MaxFailures": 3.0}}
+
+	var hooks int32
+	fake.TestcaseHooks("01_tc", Hooks{
+		BeforeIteration: func(*Meta, Settings) error {
+			atomic.AddInt32(&hooks, 1)
+			return fmt.Errorf("no more data")
+		},
+	})
+	fake.Testscenario("scenario1", func() {
+		fake.Schedule("01_tc", func(meta *Meta, s Settings) {})
+	})
+
+	if err := fake.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&hooks); n != 3 {
+		t.Errorf("User expected to stop after 3 failed hooks, but %d hooks", n)
+	}
+}
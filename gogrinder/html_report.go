@@ -0,0 +1,275 @@
+package gogrinder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+
+	time "github.com/finklabs/ttime"
+)
+
+// Percentiles contained in the html report if the loadmodel does not configure any.
+var htmlReportPercentiles = []float64{50, 90, 95, 99}
+
+// Datastructure that is rendered by the html report template.
+type htmlReport struct {
+	Scenario        string
+	Host            string
+	Start           string
+	End             string
+	Duration        string
+	Generated       string
+	Count           int64
+	Error           int64
+	Percentiles     []string
+	Steps           []htmlReportStep
+	Thresholds      []ThresholdResult
+	Errors          []ErrorResult
+	ElapsedChart    template.HTML
+	ThroughputChart template.HTML
+	Loadmodel       string
+}
+
+// Summary line of a teststep in the html report.
+type htmlReportStep struct {
+	Result
+	Percentiles []float64 // in the order of htmlReport.Percentiles
+	ErrorRate   float64   // errors per teststep [%]
+	Throughput  float64   // teststeps per second
+}
+
+// Single line of a chart.
+type chartSeries struct {
+	Name   string
+	Points []chartPoint
+}
+
+type chartPoint struct {
+	X time.Time
+	Y float64
+}
+
+// Render a static html report of the results into <dir>/index.html. The report
+// is self-contained (no scripts, no external resources) so it can be handed out
+// as is.
+func (test *TestScenario) HtmlReport(dir string) error {
+	data := htmlReport{Generated: time.Now().UTC().Format(ISO8601)}
+	data.Scenario, _, _, _ = test.GetScenarioConfig()
+	data.Host, _ = os.Hostname()
+	first, end := test.Runtime()
+	runtime := end.Sub(first)
+	if !first.IsZero() {
+		data.Start = first.UTC().Format(ISO8601)
+		data.End = end.UTC().Format(ISO8601)
+		data.Duration = runtime.String()
+	}
+
+	// summary table
+	test.lock.RLock()
+	ps := test.percentiles
+	if len(ps) == 0 {
+		ps = htmlReportPercentiles
+	}
+	results := test.results("", ps)
+	test.lock.RUnlock()
+	for _, p := range ps {
+		data.Percentiles = append(data.Percentiles, pname(p))
+	}
+	for _, r := range results {
+		step := htmlReportStep{Result: r}
+		for _, p := range data.Percentiles {
+			step.Percentiles = append(step.Percentiles, r.Percentiles[p])
+		}
+		step.ErrorRate = 100.0 * float64(r.Error) / float64(r.Count)
+		if runtime > 0 {
+			step.Throughput = float64(r.Count) / runtime.Seconds()
+		}
+		data.Steps = append(data.Steps, step)
+		data.Count += r.Count
+		data.Error += r.Error
+	}
+	data.Thresholds = test.Thresholds()
+	data.Errors = test.Errors()
+
+	// charts over time
+	elapsed := map[string]*chartSeries{}
+	throughput := map[string]*chartSeries{}
+	names := []string{}
+	for _, ts := range test.Timeseries("") {
+		x, err := time.Parse(ISO8601, ts.Timestamp)
+		if err != nil || ts.Count == 0 {
+			continue // no measurements (e.g. config change)
+		}
+		if _, exists := elapsed[ts.Teststep]; !exists {
+			elapsed[ts.Teststep] = &chartSeries{Name: ts.Teststep}
+			throughput[ts.Teststep] = &chartSeries{Name: ts.Teststep}
+			names = append(names, ts.Teststep)
+		}
+		elapsed[ts.Teststep].Points = append(elapsed[ts.Teststep].Points, chartPoint{x, ts.Avg})
+		throughput[ts.Teststep].Points = append(throughput[ts.Teststep].Points,
+			chartPoint{x, ts.Throughput})
+	}
+	sort.Strings(names)
+	es, ts := []chartSeries{}, []chartSeries{}
+	for _, n := range names {
+		es = append(es, *elapsed[n])
+		ts = append(ts, *throughput[n])
+	}
+	data.ElapsedChart = svgChart(es, "ms")
+	data.ThroughputChart = svgChart(ts, "1/s")
+
+	// loadmodel that was used
+	loadmodel, err := json.MarshalIndent(test.GetConfigMap(), "", "  ")
+	if err != nil {
+		return err
+	}
+	data.Loadmodel = string(loadmodel)
+
+	// write the report
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, "index.html"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return htmlReportTemplate.Execute(f, data)
+}
+
+// Colors of the chart lines.
+var chartColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+// Render a line chart as inline svg.
+func svgChart(series []chartSeries, unit string) template.HTML {
+	const width, height = 900, 260
+	const left, right, top, bottom = 70, 200, 10, 30
+	var minX, maxX time.Time
+	maxY := 0.0
+	for _, s := range series {
+		for _, p := range s.Points {
+			if minX.IsZero() || p.X.Before(minX) {
+				minX = p.X
+			}
+			if p.X.After(maxX) {
+				maxX = p.X
+			}
+			if p.Y > maxY {
+				maxY = p.Y
+			}
+		}
+	}
+	if minX.IsZero() {
+		return template.HTML("<p>no data</p>")
+	}
+	if maxY == 0 {
+		maxY = 1
+	}
+	span := maxX.Sub(minX).Seconds()
+	if span == 0 {
+		span = 1
+	}
+	px := func(x time.Time) float64 {
+		return left + x.Sub(minX).Seconds()/span*(width-left-right)
+	}
+	py := func(y float64) float64 {
+		return top + (1-y/maxY)*(height-top-bottom)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d">`, width, height)
+	// axes and grid
+	for i := 0; i <= 4; i++ {
+		y := maxY * float64(i) / 4
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`,
+			left, py(y), width-right, py(y))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" font-size="11">%.2f %s</text>`,
+			left-5, py(y)+4, y, template.HTMLEscapeString(unit))
+	}
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11">%s</text>`, left, height-10,
+		minX.UTC().Format("15:04:05"))
+	fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end" font-size="11">%s</text>`,
+		width-right, height-10, maxX.UTC().Format("15:04:05"))
+	// lines and legend
+	for i, s := range series {
+		color := chartColors[i%len(chartColors)]
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="`, color)
+		for _, p := range s.Points {
+			fmt.Fprintf(&b, "%.1f,%.1f ", px(p.X), py(p.Y))
+		}
+		fmt.Fprint(&b, `"/>`)
+		for _, p := range s.Points {
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="2" fill="%s"/>`, px(p.X), py(p.Y), color)
+		}
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`,
+			width-right+10, top+i*16, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11">%s</text>`,
+			width-right+25, top+i*16+9, template.HTMLEscapeString(s.Name))
+	}
+	fmt.Fprint(&b, `</svg>`)
+	return template.HTML(b.String())
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GoGrinder report {{.Scenario}}</title>
+<style>
+body { font-family: sans-serif; font-size: 14px; margin: 2em; color: #333; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+td.num { text-align: right; }
+tr.failed td { background: #f8d7da; }
+pre { background: #f5f5f5; padding: 1em; }
+</style>
+</head>
+<body>
+<h1>GoGrinder report {{.Scenario}}</h1>
+
+<h2>Run</h2>
+<table>
+<tr><th>Scenario</th><td>{{.Scenario}}</td></tr>
+<tr><th>Start</th><td>{{.Start}}</td></tr>
+<tr><th>End</th><td>{{.End}}</td></tr>
+<tr><th>Duration</th><td>{{.Duration}}</td></tr>
+<tr><th>Teststeps</th><td>{{.Count}}</td></tr>
+<tr><th>Errors</th><td>{{.Error}}</td></tr>
+<tr><th>Host</th><td>{{.Host}}</td></tr>
+<tr><th>Generated</th><td>{{.Generated}}</td></tr>
+</table>
+
+<h2>Summary</h2>
+<table>
+<tr><th>teststep</th><th>avg_ms</th><th>min_ms</th><th>max_ms</th>{{range .Percentiles}}<th>{{.}}_ms</th>{{end}}<th>count</th><th>error</th><th>error %</th><th>throughput [1/s]</th></tr>
+{{range .Steps}}<tr{{if .Error}} class="failed"{{end}}><td>{{.Teststep}}</td><td class="num">{{printf "%.3f" .Avg}}</td><td class="num">{{printf "%.3f" .Min}}</td><td class="num">{{printf "%.3f" .Max}}</td>{{range .Percentiles}}<td class="num">{{printf "%.3f" .}}</td>{{end}}<td class="num">{{.Count}}</td><td class="num">{{.Error}}</td><td class="num">{{printf "%.2f" .ErrorRate}}</td><td class="num">{{printf "%.3f" .Throughput}}</td></tr>
+{{end}}</table>
+{{if .Thresholds}}
+<h2>Thresholds</h2>
+<table>
+<tr><th>teststep</th><th>metric</th><th>value</th><th>limit</th><th>result</th></tr>
+{{range .Thresholds}}<tr{{if not .Passed}} class="failed"{{end}}><td>{{.Teststep}}</td><td>{{.Metric}}</td><td class="num">{{printf "%.3f" .Value}}</td><td class="num">{{printf "%.3f" .Limit}}</td><td>{{if .Passed}}passed{{else if .Missing}}MISSING{{else}}BREACHED{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Response time over time (avg)</h2>
+{{.ElapsedChart}}
+
+<h2>Throughput over time</h2>
+{{.ThroughputChart}}
+
+<h2>Errors</h2>
+{{if .Errors}}<table>
+<tr><th>teststep</th><th>error</th><th>count</th></tr>
+{{range .Errors}}<tr><td>{{.Teststep}}</td><td>{{.Error}}</td><td class="num">{{.Count}}</td></tr>
+{{end}}</table>{{else}}<p>no errors</p>{{end}}
+
+<h2>Loadmodel</h2>
+<pre>{{.Loadmodel}}</pre>
+</body>
+</html>
+`))
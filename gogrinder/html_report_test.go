@@ -0,0 +1,70 @@
+package gogrinder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestHtmlReportWritesIndex(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{}
+	done := fake.Collect() // this needs a collector to unblock update
+	now := time.Now()
+	for i := 1; i <= 10; i++ {
+		fake.Update(&Meta{Teststep: "01_<step>", Elapsed: Elapsed(time.Duration(i) * time.Millisecond),
+			Timestamp: Timestamp(now.Add(time.Duration(i) * time.Second))})
+	}
+	fake.Update(&Meta{Teststep: "02_step", Elapsed: Elapsed(20 * time.Millisecond),
+		Timestamp: Timestamp(now), Error: "something went wrong!"})
+	close(fake.measurements)
+	<-done
+
+	dir, _ := ioutil.TempDir(os.TempDir(), "gogrinder_test")
+	defer os.RemoveAll(dir)
+	if err := fake.HtmlReport(filepath.Join(dir, "report")); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "report", "index.html"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	html := string(buf)
+
+	for _, exp := range []string{
+		"<title>GoGrinder report scenario1</title>",
+		"<th>p50_ms</th><th>p90_ms</th><th>p95_ms</th><th>p99_ms</th>",
+		"<td>01_&lt;step&gt;</td><td class=\"num\">5.500</td><td class=\"num\">1.000</td>" +
+			"<td class=\"num\">10.000</td><td class=\"num\">5.046</td>",
+		"<tr class=\"failed\"><td>02_step</td>",
+		"<td>02_step</td><td>something went wrong!</td><td class=\"num\">1</td>",
+		"<svg xmlns=\"http://www.w3.org/2000/svg\"",
+		"&#34;Scenario&#34;: &#34;scenario1&#34;",
+		"<tr><th>Duration</th><td>10.01s</td></tr>",
+	} {
+		if !strings.Contains(html, exp) {
+			t.Errorf("Html report does not contain: %s", exp)
+		}
+	}
+}
+
+func TestSvgChartWithoutData(t *testing.T) {
+	if chart := svgChart([]chartSeries{}, "ms"); chart != "<p>no data</p>" {
+		t.Errorf("Chart not as expected: %s", chart)
+	}
+}
+
+func TestSvgChart(t *testing.T) {
+	now := time.Now()
+	chart := string(svgChart([]chartSeries{{"sth", []chartPoint{{now, 0.0},
+		{now.Add(time.Second), 2.0}}}}, "ms"))
+	if !strings.Contains(chart, `<polyline fill="none" stroke="#1f77b4" stroke-width="1.5" `+
+		`points="70.0,230.0 700.0,10.0 "/>`) {
+		t.Errorf("Chart not as expected: %s", chart)
+	}
+}
@@ -0,0 +1,258 @@
+package gogrinder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	time "github.com/finklabs/ttime"
+)
+
+// InfluxReporter writes the metrics in InfluxDB line protocol to the http write
+// endpoint of an InfluxDB (e.g. http://localhost:8086/write?db=loadtest) or to
+// a local file. The metrics are written in batches:
+//
+//	ir, err := gogrinder.NewInfluxReporter("http://localhost:8086/write?db=loadtest",
+//	    gogrinder.InfluxOptions{Tags: map[string]string{"run_id": "42"}})
+//	...
+//	defer ir.Close()
+//	gg.AddReportPlugin(ir)
+type InfluxReporter struct {
+	opts    InfluxOptions
+	tags    string // escaped tags of the options (",k1=v1,k2=v2")
+	lock    sync.Mutex
+	buf     bytes.Buffer // lines of the current batch
+	lines   int          // number of lines in buf
+	batches chan []byte  // batches for the writer
+	closed  bool         // further metrics are discarded
+	quit    chan bool    // stop the flush ticker
+	done    chan bool    // closed when the writer finished
+	write   func([]byte) error
+	closer  io.Closer
+	err     error // last write error (access only by the writer before done)
+}
+
+// InfluxOptions configure the InfluxReporter.
+type InfluxOptions struct {
+	Measurement   string            // name of the measurement (default "gogrinder")
+	Tags          map[string]string // tags added to every point (e.g. run_id)
+	BatchSize     int               // points per write (default 1000)
+	FlushInterval time.Duration     // write incomplete batches after (default 1s)
+	Timeout       time.Duration     // timeout of the http writes (default 10s)
+}
+
+// Create an InfluxReporter. If the target starts with http:// or https:// the
+// metrics are posted to this url otherwise they are written to the file.
+// Use Close to write the last batch.
+func NewInfluxReporter(target string, opts InfluxOptions) (*InfluxReporter, error) {
+	if opts.Measurement == "" {
+		opts.Measurement = "gogrinder"
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	r := &InfluxReporter{opts: opts, tags: influxTags(opts.Tags),
+		batches: make(chan []byte, 16), quit: make(chan bool), done: make(chan bool)}
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		client := &http.Client{Timeout: opts.Timeout}
+		r.write = func(batch []byte) error {
+			return influxPost(client, target, batch)
+		}
+	} else {
+		fo, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+		if err != nil {
+			return nil, err
+		}
+		r.closer = fo
+		r.write = func(batch []byte) error {
+			_, err := fo.Write(batch)
+			return err
+		}
+	}
+	go r.writer(r.batches)
+	go r.ticker()
+	return r, nil
+}
+
+// Post a batch to the InfluxDB write endpoint.
+func influxPost(client *http.Client, url string, batch []byte) error {
+	resp, err := client.Post(url, "text/plain; charset=utf-8", bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influxdb responded %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Write the batches. Write errors are logged and the batch is discarded.
+func (r *InfluxReporter) writer(batches <-chan []byte) {
+	defer close(r.done)
+	for batch := range batches {
+		if err := r.write(batch); err != nil {
+			log.Errorf("can not write metrics to influxdb: %v", err)
+			r.err = err
+		}
+	}
+}
+
+// Write incomplete batches every FlushInterval.
+func (r *InfluxReporter) ticker() {
+	t := time.NewTicker(r.opts.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			r.Flush()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// Hand the current batch to the writer.
+func (r *InfluxReporter) Flush() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.flush()
+}
+
+// Careful: the caller needs to hold the lock!
+func (r *InfluxReporter) flush() {
+	if r.lines == 0 || r.closed {
+		return
+	}
+	batch := make([]byte, r.buf.Len())
+	copy(batch, r.buf.Bytes())
+	r.buf.Reset()
+	r.lines = 0
+	r.batches <- batch
+}
+
+// Write the last batch and wait for the writer to finish. Close returns the
+// last write error.
+func (r *InfluxReporter) Close() error {
+	r.lock.Lock()
+	if r.closed {
+		r.lock.Unlock()
+		<-r.done
+		return r.err
+	}
+	close(r.quit)
+	r.flush()
+	r.closed = true
+	close(r.batches)
+	r.lock.Unlock()
+	<-r.done
+	if r.closer != nil {
+		if err := r.closer.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+	}
+	return r.err
+}
+
+// Add the metric to the current batch.
+func (r *InfluxReporter) Update(m Metric) {
+	line := r.line(m)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.closed {
+		return
+	}
+	r.buf.WriteString(line)
+	r.lines++
+	if r.lines >= r.opts.BatchSize {
+		r.flush()
+	}
+}
+
+// Format the metric as point in line protocol:
+// gogrinder,teststep=01_01_home,testcase=01_tc,run_id=42 elapsed=8,user=1i,error=false,code=200i 1465313371000000000
+func (r *InfluxReporter) line(m Metric) string {
+	var b bytes.Buffer
+	b.WriteString(influxEscape(r.opts.Measurement, ", "))
+	b.WriteString(",teststep=")
+	b.WriteString(influxEscape(influxTagValue(m.GetTeststep()), ", ="))
+	if u, ok := m.(userMetric); ok && u.GetTestcase() != "" {
+		b.WriteString(",testcase=")
+		b.WriteString(influxEscape(u.GetTestcase(), ", ="))
+	}
+	b.WriteString(r.tags)
+
+	b.WriteString(" elapsed=")
+	b.WriteString(strconv.FormatFloat(float64(m.GetElapsed())/float64(time.Millisecond), 'f', -1, 64))
+	if u, ok := m.(userMetric); ok {
+		fmt.Fprintf(&b, ",user=%di", u.GetUser())
+	}
+	fmt.Fprintf(&b, ",error=%t", m.GetError() != "")
+	if m.GetError() != "" {
+		b.WriteString(",message=\"")
+		b.WriteString(influxEscape(m.GetError(), "\"\\"))
+		b.WriteString("\"")
+	}
+	if h, ok := m.(httpMetric); ok {
+		fmt.Fprintf(&b, ",code=%di,bytes=%di", h.GetCode(), h.GetBytes())
+		b.WriteString(",first_byte=")
+		b.WriteString(strconv.FormatFloat(float64(h.GetFirstByte())/float64(time.Millisecond), 'f', -1, 64))
+	}
+	fmt.Fprintf(&b, " %d\n", m.GetTimestamp().UnixNano())
+	return b.String()
+}
+
+// Escape the given characters with a backslash.
+func influxEscape(s string, chars string) string {
+	if !strings.ContainsAny(s, chars) {
+		return s
+	}
+	var b bytes.Buffer
+	for _, c := range s {
+		if strings.ContainsRune(chars, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Tag values can not be empty.
+func influxTagValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Format the tags sorted by key (recommended by InfluxDB).
+func influxTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		b.WriteString(",")
+		b.WriteString(influxEscape(k, ", ="))
+		b.WriteString("=")
+		b.WriteString(influxEscape(influxTagValue(tags[k]), ", ="))
+	}
+	return b.String()
+}
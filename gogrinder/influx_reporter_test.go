@@ -0,0 +1,140 @@
+package gogrinder
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestCheckInfluxReporterImplementsReporterInterface(t *testing.T) {
+	s := &InfluxReporter{}
+	if _, ok := interface{}(s).(Reporter); !ok {
+		t.Errorf("InfluxReporter does not implement the Reporter interface!")
+	}
+}
+
+func TestInfluxReporterLine(t *testing.T) {
+	r := &InfluxReporter{opts: InfluxOptions{Measurement: "gogrinder"},
+		tags: influxTags(map[string]string{"run_id": "42", "env": "perf test"})}
+	ts := time.Unix(1465313371, 0)
+
+	line := r.line(&someHttpMetric{Meta{Testcase: "01_tc", Teststep: "Home, page", User: 2,
+		Elapsed: Elapsed(599500 * time.Microsecond), Timestamp: Timestamp(ts)},
+		Elapsed(258 * time.Millisecond), 18193, 200})
+	exp := `gogrinder,teststep=Home\,\ page,testcase=01_tc,env=perf\ test,run_id=42 ` +
+		`elapsed=599.5,user=2i,error=false,code=200i,bytes=18193i,first_byte=258 1465313371000000000` + "\n"
+	if line != exp {
+		t.Errorf("Line expected: %s, but got: %s", exp, line)
+	}
+
+	line = r.line(&someMetric{Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond),
+		Timestamp: Timestamp(ts), Error: `"quoted" error`}, 100})
+	exp = `gogrinder,teststep=sth,env=perf\ test,run_id=42 ` +
+		`elapsed=8,user=0i,error=true,message="\"quoted\" error" 1465313371000000000` + "\n"
+	if line != exp {
+		t.Errorf("Line expected: %s, but got: %s", exp, line)
+	}
+}
+
+// Local stand-in for the InfluxDB write endpoint.
+type influxStandIn struct {
+	lock    sync.Mutex
+	batches []string
+}
+
+func (s *influxStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.lock.Lock()
+	s.batches = append(s.batches, string(body))
+	s.lock.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestInfluxReporterHttpBatches(t *testing.T) {
+	standIn := &influxStandIn{}
+	ts := httptest.NewServer(standIn)
+	defer ts.Close()
+
+	r, err := NewInfluxReporter(ts.URL+"/write?db=loadtest",
+		InfluxOptions{BatchSize: 2, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, step := range []string{"01", "02", "03"} {
+		r.Update(&Meta{Testcase: "01_tc", Teststep: step, Timestamp: Timestamp(time.Now())})
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(standIn.batches) != 2 {
+		t.Fatalf("Expected 2 batches but got: %v", standIn.batches)
+	}
+	if strings.Count(standIn.batches[0], "\n") != 2 || strings.Count(standIn.batches[1], "\n") != 1 ||
+		!strings.HasPrefix(standIn.batches[1], "gogrinder,teststep=03,testcase=01_tc ") {
+		t.Errorf("Batches not as expected: %v", standIn.batches)
+	}
+}
+
+func TestInfluxReporterFlushInterval(t *testing.T) {
+	standIn := &influxStandIn{}
+	ts := httptest.NewServer(standIn)
+	defer ts.Close()
+
+	r, _ := NewInfluxReporter(ts.URL, InfluxOptions{FlushInterval: 10 * time.Millisecond})
+	defer r.Close()
+	r.Update(&Meta{Teststep: "sth", Timestamp: Timestamp(time.Now())})
+	for i := 0; i < 100; i++ {
+		standIn.lock.Lock()
+		n := len(standIn.batches)
+		standIn.lock.Unlock()
+		if n == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("Expected the incomplete batch to be written after the flush interval!")
+}
+
+func TestInfluxReporterHttpError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database not found", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	r, _ := NewInfluxReporter(ts.URL, InfluxOptions{})
+	r.Update(&Meta{Teststep: "sth", Timestamp: Timestamp(time.Now())})
+	err := r.Close()
+	if err == nil || err.Error() != "influxdb responded 404 Not Found: database not found" {
+		t.Errorf("Error not as expected: %v", err)
+	}
+}
+
+func TestInfluxReporterFile(t *testing.T) {
+	tmp, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	r, err := NewInfluxReporter(tmp.Name(), InfluxOptions{Measurement: "load test",
+		Tags: map[string]string{"run_id": "42"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond),
+		Timestamp: Timestamp(time.Unix(1465313371, 0))})
+	if err := r.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buf, _ := ioutil.ReadFile(tmp.Name())
+	exp := "load\\ test,teststep=sth,run_id=42 elapsed=8,user=0i,error=false 1465313371000000000\n"
+	if string(buf) != exp {
+		t.Errorf("File content expected: %s, but got: %s", exp, string(buf))
+	}
+}
@@ -1,30 +1,145 @@
 package gogrinder
 
 import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
 	"fmt"
-	"os"
+	"io"
+	"net/http"
+	"strconv"
 )
 
-
 // JtlReporter
 // Jtl is the format used by Jmeter to exchange data with other Java tools like Jenkins
 // https://wiki.apache.org/jmeter/JtlFiles
 type JtlReporter struct {
-	logfile *os.File
+	logfile *bufio.Writer
+	threads ThreadCounter // source of the active thread counts (optional)
+	xml     bool          // use the xml variant of the jtl format
+}
+
+// ThreadCounter provides the number of active users of a testcase and of all
+// testcases (e.g. the TestScenario).
+type ThreadCounter interface {
+	Threads(testcase string) (int, int)
+}
+
+// Metrics that carry the testcase and user (e.g. Meta).
+type userMetric interface {
+	GetTestcase() string
+	GetUser() int
+}
+
+// Metrics that carry the active users at the time of the measurement (e.g.
+// Meta, the counts are captured by TestScenario.Update).
+type threadMetric interface {
+	sampleThreads() *threadCount
+	setSampleThreads(c threadCount)
+}
+
+// Metrics that carry http details (e.g. req.HttpMetric).
+type httpMetric interface {
+	GetCode() int
+	GetBytes() int
+	GetFirstByte() Elapsed
 }
 
+// Jtl csv header as written by Jmeter.
+const jtlHeader = "timeStamp,elapsed,label,responseCode,responseMessage,threadName," +
+	"dataType,success,bytes,grpThreads,allThreads,Latency"
+
+// Single sample of the xml variant of the jtl format (<sample> or <httpSample>).
+type jtlSample struct {
+	XMLName    xml.Name
+	Elapsed    string `xml:"t,attr"`
+	Latency    string `xml:"lt,attr,omitempty"`
+	Timestamp  string `xml:"ts,attr"`
+	Success    string `xml:"s,attr"`
+	Label      string `xml:"lb,attr"`
+	Code       string `xml:"rc,attr,omitempty"`
+	Message    string `xml:"rm,attr,omitempty"`
+	ThreadName string `xml:"tn,attr,omitempty"`
+	DataType   string `xml:"dt,attr"`
+	Bytes      string `xml:"by,attr,omitempty"`
+	GrpThreads string `xml:"ng,attr,omitempty"`
+	AllThreads string `xml:"na,attr,omitempty"`
+}
+
+// Create a JtlReporter. The thread counts are captured with the measurements
+// by the TestScenario; for other metrics they are read from <threads>.
+// The csv variant writes the Jmeter header line if <header> is set. The xml
+// variant always writes the document header; use Close to finish the document.
+// The samples are buffered until the collector flushes the reporters.
+func NewJtlReporter(logfile io.Writer, threads ThreadCounter, header bool, xml bool) *JtlReporter {
+	r := &JtlReporter{bufio.NewWriter(logfile), threads, xml}
+	if xml {
+		fmt.Fprint(r.logfile, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<testResults version=\"1.2\">\n")
+	} else if header {
+		fmt.Fprintln(r.logfile, jtlHeader)
+	}
+	return r
+}
+
+// Write the buffered samples to the jtl result file.
+func (r *JtlReporter) Flush() error {
+	return r.logfile.Flush()
+}
+
+// Finish the jtl result file (closing tag of the xml variant).
+func (r *JtlReporter) Close() error {
+	if r.xml {
+		fmt.Fprint(r.logfile, "</testResults>\n")
+	}
+	return r.Flush()
+}
 
 // Write metrics to the jtl result file.
 func (r *JtlReporter) Update(m Metric) {
-	// sample result.jtl file (usually there is no header!)
+	// sample result.jtl file
 	// timeStamp,elapsed,label,responseCode,responseMessage,threadName,dataType,success,bytes,grpThreads,allThreads,Latency
 	// 1461685566118,599,Home page,200,OK,User threads 1-25,text,true,18193,180,180,258
-	// note: this is not a full implementation of the Jtl format
-	// just the generic part we need to transfer results to Jenkins
-	// for a full implementation we need to move this into every Reporter
-	success := "true"
-	if m.GetError() != "" { success = "false"}
-	fmt.Fprintf(r.logfile, "%d,%d,%s,,,,text,%s,,,,\n",
-		(m.GetTimestamp()).UnixNano()/1000000,
-		m.GetElapsed()/1000000, m.GetTeststep(), success)
+	s := jtlSample{
+		XMLName:   xml.Name{Local: "sample"},
+		Elapsed:   strconv.FormatInt(int64(m.GetElapsed()/1000000), 10),
+		Timestamp: strconv.FormatInt(m.GetTimestamp().UnixNano()/1000000, 10),
+		Success:   "true",
+		Label:     m.GetTeststep(),
+		DataType:  "text",
+	}
+	if u, ok := m.(userMetric); ok {
+		s.ThreadName = strconv.Itoa(u.GetUser())
+		if u.GetTestcase() != "" {
+			s.ThreadName = u.GetTestcase() + " " + s.ThreadName
+		}
+		if t, ok := m.(threadMetric); ok && t.sampleThreads() != nil {
+			c := t.sampleThreads()
+			s.GrpThreads, s.AllThreads = strconv.Itoa(c.grp), strconv.Itoa(c.all)
+		} else if r.threads != nil {
+			grp, all := r.threads.Threads(u.GetTestcase())
+			s.GrpThreads, s.AllThreads = strconv.Itoa(grp), strconv.Itoa(all)
+		}
+	}
+	if h, ok := m.(httpMetric); ok {
+		s.XMLName.Local = "httpSample"
+		s.Code = strconv.Itoa(h.GetCode())
+		s.Message = http.StatusText(h.GetCode())
+		s.Bytes = strconv.Itoa(h.GetBytes())
+		s.Latency = strconv.FormatInt(int64(h.GetFirstByte()/1000000), 10)
+	}
+	if m.GetError() != "" {
+		s.Success = "false"
+		s.Message = m.GetError()
+	}
+
+	if r.xml {
+		out, _ := xml.Marshal(s)
+		fmt.Fprintf(r.logfile, "%s\n", out)
+		return
+	}
+	w := csv.NewWriter(r.logfile)
+	w.Write([]string{s.Timestamp, s.Elapsed, s.Label, s.Code, s.Message,
+		s.ThreadName, s.DataType, s.Success, s.Bytes, s.GrpThreads, s.AllThreads,
+		s.Latency})
+	w.Flush()
 }
@@ -1,5 +1,6 @@
 package gogrinder
 import (
+	"bytes"
 	"testing"
 	"io/ioutil"
 	"os"
@@ -19,44 +20,129 @@ func TestJtlReporterUpdateWithSomeMetric(t *testing.T) {
 	fake := NewTest()
 	tmp, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
 	defer os.Remove(tmp.Name())
-	fake.AddReportPlugin(&JtlReporter{tmp})
+	fake.AddReportPlugin(NewJtlReporter(tmp, nil, false, false))
 
 	done := fake.Collect() // this needs a collector to unblock update
 
 	now := time.Now()
 	fake.Update(Metric(&someMetric{Meta{Teststep: "sth", Elapsed: Elapsed(8 *
 		time.Millisecond), Timestamp: Timestamp(now)}, 100}))
-	exp := fmt.Sprintf(`%d,8,sth,,,,text,true,,,,`, now.UnixNano()/1000000)
+	exp := fmt.Sprintf(`%d,8,sth,,,0,text,true,,0,0,`, now.UnixNano()/1000000)
+
+	close(fake.measurements)
+	<-done // the collector flushes the reporter
 
 	buf, _ := ioutil.ReadFile(tmp.Name())
 	last := strings.TrimSpace(string(buf))
 	if last != exp {
 		t.Errorf("Entry for SomeMetric expected: %s, but got: %s", exp, last)
 	}
-
-	close(fake.measurements)
-	<-done
 }
 
 func TestJtlReporterUpdateWithSomeMetricError(t *testing.T) {
 	fake := NewTest()
 	tmp, _ := ioutil.TempFile(os.TempDir(), "gogrinder_test")
 	defer os.Remove(tmp.Name())
-	fake.AddReportPlugin(&JtlReporter{tmp})
+	fake.AddReportPlugin(NewJtlReporter(tmp, nil, false, false))
 
 	done := fake.Collect() // this needs a collector to unblock update
 
 	now := time.Now()
 	fake.Update(Metric(&someMetric{Meta{Teststep: "sth", Elapsed: Elapsed(8 *
 		time.Millisecond), Timestamp: Timestamp(now), Error: "something went wrong!"}, 100}))
-    exp := fmt.Sprintf(`%d,8,sth,,,,text,false,,,,`, now.UnixNano()/1000000)
+    exp := fmt.Sprintf(`%d,8,sth,,something went wrong!,0,text,false,,0,0,`, now.UnixNano()/1000000)
+
+	close(fake.measurements)
+	<-done // the collector flushes the reporter
 
 	buf, _ := ioutil.ReadFile(tmp.Name())
 	last := strings.TrimSpace(string(buf))
 	if last != exp {
 		t.Errorf("Entry for SomeMetric expected: %s, but got: %s", exp, last)
 	}
+}
 
-	close(fake.measurements)
-	<-done
+// metric that provides http details like req.HttpMetric
+type someHttpMetric struct {
+	Meta
+	FirstByte Elapsed
+	Bytes     int
+	Code      int
+}
+
+func (m *someHttpMetric) GetCode() int          { return m.Code }
+func (m *someHttpMetric) GetBytes() int         { return m.Bytes }
+func (m *someHttpMetric) GetFirstByte() Elapsed { return m.FirstByte }
+
+// fake thread counts
+type someThreads struct{}
+
+func (th someThreads) Threads(testcase string) (int, int) {
+	if testcase == "01_tc" {
+		return 3, 5
+	}
+	return 0, 5
+}
+
+func TestJtlReporterUpdateWithHttpMetric(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewJtlReporter(&buf, someThreads{}, true, false)
+
+	now := time.Now()
+	r.Update(&someHttpMetric{Meta{Testcase: "01_tc", Teststep: "Home, page", User: 2,
+		Elapsed: Elapsed(599 * time.Millisecond), Timestamp: Timestamp(now)},
+		Elapsed(258 * time.Millisecond), 18193, 200})
+	r.Close()
+
+	exp := fmt.Sprintf("timeStamp,elapsed,label,responseCode,responseMessage,threadName,"+
+		"dataType,success,bytes,grpThreads,allThreads,Latency\n"+
+		"%d,599,\"Home, page\",200,OK,01_tc 2,text,true,18193,3,5,258\n", now.UnixNano()/1000000)
+	if buf.String() != exp {
+		t.Errorf("Jtl expected: %s, but got: %s", exp, buf.String())
+	}
+}
+
+func TestJtlReporterXml(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewJtlReporter(&buf, someThreads{}, false, true)
+
+	now := time.Now()
+	r.Update(&someHttpMetric{Meta{Testcase: "01_tc", Teststep: "Home page", User: 2,
+		Elapsed: Elapsed(599 * time.Millisecond), Timestamp: Timestamp(now)},
+		Elapsed(258 * time.Millisecond), 18193, 200})
+	r.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond),
+		Timestamp: Timestamp(now), Error: "something went wrong!"})
+	r.Close()
+
+	ts := now.UnixNano() / 1000000
+	exp := fmt.Sprintf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<testResults version=\"1.2\">\n"+
+		"<httpSample t=\"599\" lt=\"258\" ts=\"%d\" s=\"true\" lb=\"Home page\" rc=\"200\" rm=\"OK\" "+
+		"tn=\"01_tc 2\" dt=\"text\" by=\"18193\" ng=\"3\" na=\"5\"></httpSample>\n"+
+		"<sample t=\"8\" ts=\"%d\" s=\"false\" lb=\"sth\" rm=\"something went wrong!\" "+
+		"tn=\"0\" dt=\"text\" ng=\"0\" na=\"5\"></sample>\n"+
+		"</testResults>\n", ts, ts)
+	if buf.String() != exp {
+		t.Errorf("Jtl expected: %s, but got: %s", exp, buf.String())
+	}
+}
+
+func TestJtlReporterUsesThreadsOfTheMeasurement(t *testing.T) {
+	test := NewTest()
+	test.measurements = make(chan Metric, 1)
+	test.startThread("01_tc")
+	test.startThread("01_tc")
+	test.startThread("02_tc")
+	test.Update(&Meta{Testcase: "01_tc", Teststep: "sth", User: 1})
+	// users finish before the collector processes the measurement
+	test.endThread("01_tc")
+	test.endThread("02_tc")
+
+	var buf bytes.Buffer
+	r := NewJtlReporter(&buf, test, false, true)
+	r.Update(<-test.measurements)
+	r.Close()
+
+	if !strings.Contains(buf.String(), "ng=\"2\" na=\"3\"") {
+		t.Errorf("Jtl expected the threads of the measurement, but got: %s", buf.String())
+	}
 }
@@ -0,0 +1,92 @@
+package gogrinder
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// JUnit xml is understood by most CI systems (Jenkins, GitLab, GitHub).
+// Every teststep is represented by a testcase. A testcase fails if the
+// teststep has errors or breaches a threshold.
+// http://llg.cubic.org/docs/junit/
+type junitTestsuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Testcases []junitTestcase `xml:"testcase"`
+}
+
+type junitTestcase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"` // average response time [s]
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// Export the results and thresholds as JUnit xml. The <suite> name is usually
+// the name of the scenario.
+func (test *TestStatistics) JUnit(suite string) (string, error) {
+	results := test.Results("") // get all results
+	thresholds := test.Thresholds()
+
+	ts := junitTestsuite{Name: suite}
+	add := func(teststep string, time string, out string) *junitTestcase {
+		ts.Testcases = append(ts.Testcases, junitTestcase{Name: teststep,
+			Classname: suite, Time: time, SystemOut: out})
+		return &ts.Testcases[len(ts.Testcases)-1]
+	}
+	fail := func(tc *junitTestcase, typ string, msg string) {
+		if tc.Failure == nil {
+			tc.Failure = &junitFailure{Message: msg, Type: typ}
+			ts.Failures++
+		} else {
+			tc.Failure.Message += "; " + msg
+		}
+		tc.Failure.Text += msg + "\n"
+	}
+
+	for _, r := range results {
+		tc := add(r.Teststep, fmt.Sprintf("%f", r.Avg/1000.0),
+			fmt.Sprintf("avg_ms=%f, min_ms=%f, max_ms=%f, count=%d, error=%d",
+				r.Avg, r.Min, r.Max, r.Count, r.Error))
+		if r.Error > 0 {
+			fail(tc, "error", fmt.Sprintf("%d of %d teststeps failed", r.Error, r.Count))
+		}
+	}
+	for _, th := range thresholds {
+		if th.Passed {
+			continue
+		}
+		var tc *junitTestcase
+		for i := range ts.Testcases {
+			if ts.Testcases[i].Name == th.Teststep {
+				tc = &ts.Testcases[i]
+			}
+		}
+		if tc == nil {
+			// teststep without results
+			tc = add(th.Teststep, "0.000000", "")
+		}
+		msg := fmt.Sprintf("threshold %s breached: %f (limit %f)", th.Metric, th.Value, th.Limit)
+		if th.Missing {
+			msg = fmt.Sprintf("threshold %s missing: teststep has no measurements", th.Metric)
+		}
+		fail(tc, "threshold", msg)
+	}
+	ts.Tests = len(ts.Testcases)
+
+	out, err := xml.MarshalIndent(ts, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(out) + "\n", nil
+}
@@ -0,0 +1,62 @@
+package gogrinder
+
+import (
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestJUnit(t *testing.T) {
+	fake := NewTest()
+	done := fake.Collect() // this needs a collector to unblock update
+	fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond),
+		Timestamp: Timestamp(time.Now())})
+	fake.Update(&Meta{Teststep: "else", Elapsed: Elapsed(20 * time.Millisecond),
+		Timestamp: Timestamp(time.Now()), Error: "something went wrong!"})
+	close(fake.measurements)
+	<-done
+
+	fake.setThresholdResults([]ThresholdResult{
+		{"sth", "avg_ms", 5.0, 8.0, false, false},
+		{"else", "error_rate", 0.1, 1.0, false, false},
+		{"missing", "throughput", 1.0, 0.0, false, true},
+		{"sth", "error_rate", 0.1, 0.0, true, false},
+	})
+
+	xml, err := fake.JUnit("scenario1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	exp := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="scenario1" tests="3" failures="3" errors="0">
+  <testcase name="else" classname="scenario1" time="0.020000">
+    <failure message="1 of 1 teststeps failed; threshold error_rate breached: 1.000000 (limit 0.100000)" type="error">1 of 1 teststeps failed&#xA;threshold error_rate breached: 1.000000 (limit 0.100000)&#xA;</failure>
+    <system-out>avg_ms=20.000000, min_ms=20.000000, max_ms=20.000000, count=1, error=1</system-out>
+  </testcase>
+  <testcase name="sth" classname="scenario1" time="0.008000">
+    <failure message="threshold avg_ms breached: 8.000000 (limit 5.000000)" type="threshold">threshold avg_ms breached: 8.000000 (limit 5.000000)&#xA;</failure>
+    <system-out>avg_ms=8.000000, min_ms=8.000000, max_ms=8.000000, count=1, error=0</system-out>
+  </testcase>
+  <testcase name="missing" classname="scenario1" time="0.000000">
+    <failure message="threshold throughput missing: teststep has no measurements" type="threshold">threshold throughput missing: teststep has no measurements&#xA;</failure>
+  </testcase>
+</testsuite>
+`
+	if xml != exp {
+		t.Errorf("JUnit xml not as expected: %s", xml)
+	}
+}
+
+func TestJUnitWithoutResults(t *testing.T) {
+	fake := NewTest()
+
+	xml, err := fake.JUnit("scenario1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	exp := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<testsuite name="scenario1" tests="0" failures="0" errors="0"></testsuite>` + "\n"
+	if xml != exp {
+		t.Errorf("JUnit xml not as expected: %s", xml)
+	}
+}
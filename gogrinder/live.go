@@ -0,0 +1,137 @@
+package gogrinder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	time "github.com/finklabs/ttime"
+)
+
+// Live changes of the loadmodel: if the config is updated while the test is
+// running (e.g. PUT /config from the frontend) the schedulers add or retire
+// virtual users and the users pick up the new pacing, thinktime factor and
+// runfor. Every change is recorded as teststep "<testcase>_config_change"
+// (scenario properties as "config_change"). These events go to the event-log
+// and are markers in the timeseries but are not part of the statistics.
+
+// Properties of the scenario that are applied to the running test.
+var liveScenarioProperties = []string{"ThinkTimeFactor", "ThinkTimeVariance", "PacingVariance"}
+
+// Read and validate the config like TestConfig.ReadConfigValidate. Changes
+// of a running test are recorded as events.
+func (test *TestScenario) ReadConfigValidate(document string, schema string) error {
+	old := test.GetConfigMap()
+	if err := test.TestConfig.ReadConfigValidate(document, schema); err != nil {
+		return err
+	}
+	test.statusLock.RLock()
+	defer test.statusLock.RUnlock()
+	if test.status != Running {
+		return nil
+	}
+	for _, m := range configChanges(old, test.GetConfigMap()) {
+		test.Update(m)
+	}
+	return nil
+}
+
+// Channel that is closed on the next config change.
+func (test *TestConfig) configChanged() <-chan bool {
+	test.configLock.Lock()
+	defer test.configLock.Unlock()
+	if test.changed == nil {
+		test.changed = make(chan bool)
+	}
+	return test.changed
+}
+
+// Wake up the schedulers that wait for a config change.
+// Careful: the caller needs to hold the config lock!
+func (test *TestConfig) notifyChanged() {
+	if test.changed != nil {
+		close(test.changed)
+		test.changed = nil
+	}
+}
+
+// Compare two configs and return the changes as events.
+func configChanges(old map[string]interface{}, config map[string]interface{}) []*Meta {
+	changes := []*Meta{}
+	if event := diffProperties(old, config, liveScenarioProperties); event != "" {
+		changes = append(changes, &Meta{Teststep: "config_change", Event: event})
+	}
+	entries := loadmodelEntries(old)
+	for _, entry := range loadmodelEntries(config) {
+		testcase, _ := entry["Testcase"].(string)
+		if event := diffProperties(entries[testcase], entry, nil); event != "" {
+			changes = append(changes, &Meta{Testcase: testcase,
+				Teststep: testcase + "_config_change", Event: event})
+		}
+	}
+	now := Timestamp(time.Now())
+	for _, m := range changes {
+		m.Timestamp = now
+	}
+	return changes
+}
+
+// Loadmodel entries by testcase.
+func loadmodelEntries(config map[string]interface{}) map[string]map[string]interface{} {
+	entries := make(map[string]map[string]interface{})
+	if conf, ok := config["Loadmodel"].([]interface{}); ok {
+		for _, tc := range conf {
+			if entry, ok := tc.(map[string]interface{}); ok {
+				if testcase, ok := entry["Testcase"].(string); ok {
+					entries[testcase] = entry
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// Describe the changed properties like "Users 2 -> 4, Pacing 1 -> 0.5".
+// Without <keys> all properties are compared.
+func diffProperties(old map[string]interface{}, config map[string]interface{}, keys []string) string {
+	if keys == nil {
+		for k := range old {
+			keys = append(keys, k)
+		}
+		for k := range config {
+			if _, ok := old[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+	}
+	changes := []string{}
+	for _, k := range keys {
+		o, n := old[k], config[k]
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s %s -> %s", k, property(o), property(n)))
+	}
+	return strings.Join(changes, ", ")
+}
+
+// Format a config property for the event.
+func property(v interface{}) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// Runfor and pacing of the testcase. If the testcase is part of the loadmodel
+// the current values are used, otherwise the given ones.
+func (test *TestScenario) liveConfig(testcase string, runfor float64, pacing float64) (float64, float64) {
+	if _, ok := test.getTestcaseEntry(testcase); ok {
+		if _, r, _, _, p, err := test.GetTestcaseConfig(testcase); err == nil {
+			return r, p
+		}
+	}
+	return runfor, pacing
+}
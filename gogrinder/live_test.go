@@ -0,0 +1,132 @@
+package gogrinder
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestConfigChanges(t *testing.T) {
+	old := map[string]interface{}{"Scenario": "scenario1", "ThinkTimeFactor": 1.0,
+		"Loadmodel": []interface{}{
+			map[string]interface{}{"Testcase": "01_tc", "Runfor": 10.0, "Users": 2.0, "Pacing": 1.0},
+			map[string]interface{}{"Testcase": "02_tc", "Runfor": 10.0, "Users": 1.0},
+		}}
+	config := map[string]interface{}{"Scenario": "scenario1", "ThinkTimeFactor": 2.0,
+		"Loadmodel": []interface{}{
+			map[string]interface{}{"Testcase": "01_tc", "Runfor": 10.0, "Users": 4.0},
+			map[string]interface{}{"Testcase": "02_tc", "Runfor": 10.0, "Users": 1.0},
+		}}
+
+	changes := configChanges(old, config)
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes but got: %v", changes)
+	}
+	if changes[0].Teststep != "config_change" || changes[0].Event != "ThinkTimeFactor 1 -> 2" {
+		t.Errorf("Scenario change not as expected: %v", changes[0])
+	}
+	if changes[1].Testcase != "01_tc" || changes[1].Teststep != "01_tc_config_change" ||
+		changes[1].Event != "Pacing 1 -> -, Users 2 -> 4" {
+		t.Errorf("Testcase change not as expected: %v", changes[1])
+	}
+}
+
+func TestReadConfigValidateNotRunning(t *testing.T) {
+	// no events are recorded if the test is not running (no collector)
+	fake := NewTest()
+	changed := fake.configChanged()
+	loadmodel := `{"Scenario": "scenario1", "Loadmodel": [
+		{"Testcase": "01_tc", "Runfor": 10, "Users": 2, "Pacing": 1}]}`
+	if err := fake.ReadConfigValidate(loadmodel, LoadmodelSchema); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Errorf("Expected the config change to be notified!")
+	}
+}
+
+// Helper to wait until the testcase has the expected number of users.
+func waitForUsers(t *testing.T, test *TestScenario, testcase string, users int) {
+	for i := 0; i < 200; i++ {
+		if n, _ := test.Threads(testcase); n == users {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	n, _ := test.Threads(testcase)
+	t.Fatalf("Expected %d users but got %d", users, n)
+}
+
+func TestRunFollowsConfigChanges(t *testing.T) {
+	fake := NewTest()
+	fake.Testscenario("scenario1", func() {
+		fake.Schedule("01_tc", func(meta *Meta, s Settings) {})
+	})
+	loadmodel := `{"Scenario": "scenario1", "Loadmodel": [
+		{"Testcase": "01_tc", "Runfor": 10, "Users": 1, "Pacing": 0.01}]}`
+	if err := fake.ReadConfigValidate(loadmodel, LoadmodelSchema); err != nil {
+		t.Fatal(err)
+	}
+	var events bytes.Buffer
+	fake.AddReportPlugin(NewEventReporter(&events))
+	var jtl bytes.Buffer
+	fake.AddReportPlugin(NewJtlReporter(&jtl, fake, false, false))
+	done := make(chan error)
+	go func() { done <- fake.Exec() }()
+	waitForUsers(t, fake, "01_tc", 1)
+
+	update := func(users int, runfor int) {
+		lm := fmt.Sprintf(`{"Scenario": "scenario1", "Loadmodel": [
+			{"Testcase": "01_tc", "Runfor": %d, "Users": %d, "Pacing": 0.01}]}`, runfor, users)
+		if err := fake.ReadConfigValidate(lm, LoadmodelSchema); err != nil {
+			t.Fatal(err)
+		}
+	}
+	update(3, 10) // add users
+	waitForUsers(t, fake, "01_tc", 3)
+	update(2, 10) // retire a user
+	waitForUsers(t, fake, "01_tc", 2)
+	update(0, 10) // retire all users
+	waitForUsers(t, fake, "01_tc", 0)
+	update(1, 10) // scale up again
+	waitForUsers(t, fake, "01_tc", 1)
+	update(1, 0) // end the testcase
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		fake.Stop()
+		t.Fatalf("Expected the changed runfor to end the test!")
+	}
+	// the config changes are recorded as events but are not part of the stats
+	if n := strings.Count(events.String(), `"teststep":"01_tc_config_change"`); n != 5 {
+		t.Errorf("Expected 5 config changes to be recorded but got: %s", events.String())
+	}
+	if _, ok := fake.stats["01_tc_config_change"]; ok {
+		t.Errorf("Config changes are not expected in the stats: %v", fake.stats)
+	}
+	if strings.Contains(jtl.String(), "config_change") {
+		t.Errorf("Config changes are not expected in the jtl: %s", jtl.String())
+	}
+	// but they are markers in the timeseries
+	markers := []string{}
+	for _, ts := range fake.Timeseries("") {
+		if ts.Teststep == "01_tc_config_change" {
+			if ts.Count != 0 {
+				t.Errorf("Config change markers are no measurements: %v", ts)
+			}
+			markers = append(markers, ts.Event)
+		}
+	}
+	if n := strings.Count(strings.Join(markers, "; "), " -> "); n != 5 {
+		t.Errorf("Expected 5 config changes in the timeseries but got: %v", markers)
+	}
+}
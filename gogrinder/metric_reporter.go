@@ -22,18 +22,54 @@ func NewSummaryVec(name string, help string) *prometheus.SummaryVec {
 	}, []string{"teststep"})
 }
 
+// Default buckets of the histograms in ms.
+var DefBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// helper
+// A histogram with the given buckets or a summary if buckets is nil. Unlike
+// summaries the buckets of histograms can be aggregated across agents.
+func NewObserverVec(name string, help string, buckets []float64) prometheus.ObserverVec {
+	if buckets == nil {
+		return NewSummaryVec(name, help)
+	}
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    name,
+		Help:    help,
+		Buckets: buckets,
+	}, []string{"teststep"})
+}
+
 // Specific prometheus reporter for Meta metric.
 // All metrics are represents as vectors of teststeps
+// Only one of MetricReporter, req.HttpMetricReporter and PrometheusReporter
+// can be exported, they all provide gogrinder_elapsed_ms and
+// gogrinder_error_count.
 type MetricReporter struct {
-	elapsed *prometheus.SummaryVec
+	elapsed prometheus.ObserverVec
 	error   *prometheus.CounterVec
 }
 
 func NewMetricReporter() *MetricReporter {
+	return newMetricReporter(nil)
+}
+
+// MetricReporter that uses histograms with the given buckets [ms] instead of
+// summaries (nil uses the DefBuckets).
+func NewHistogramMetricReporter(buckets []float64) *MetricReporter {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	return newMetricReporter(buckets)
+}
+
+func newMetricReporter(buckets []float64) *MetricReporter {
 	return &MetricReporter{
-		NewSummaryVec(
+		NewObserverVec(
 			"gogrinder_elapsed_ms",
-			"Current time elapsed of gogrinder teststep in ms."),
+			"Current time elapsed of gogrinder teststep in ms.", buckets),
 		prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "gogrinder_error_count",
 			Help: "Current error of gogrinder teststep.",
@@ -41,8 +77,20 @@ func NewMetricReporter() *MetricReporter {
 	}
 }
 
-// We did not find out a way to implement a generic prometheus reporter.
-// So this is a specific prometheus reporter that deals with HttpMetric values.
+// Describe implements prometheus.Collector so the reporter can be registered.
+func (r *MetricReporter) Describe(ch chan<- *prometheus.Desc) {
+	r.elapsed.Describe(ch)
+	r.error.Describe(ch)
+}
+
+// Collect implements prometheus.Collector so the reporter can be registered.
+func (r *MetricReporter) Collect(ch chan<- prometheus.Metric) {
+	r.elapsed.Collect(ch)
+	r.error.Collect(ch)
+}
+
+// Specific prometheus reporter for Meta values. Use the PrometheusReporter for
+// custom Metric types.
 func (r *MetricReporter) Update(m Metric) {
 	r.elapsed.WithLabelValues(m.GetTeststep()).Observe(float64(m.GetElapsed()) /
 		float64(time.Millisecond))
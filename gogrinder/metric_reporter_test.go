@@ -17,9 +17,9 @@ func TestCheckMetricReporterImplementsReporterInterface(t *testing.T) {
 
 // TODO: Pending prometheus/client_golang#58
 // read metric helpers needs rework once testability is improved!
-func readSummaryVec(m *prometheus.SummaryVec, l prometheus.Labels) []*dto.Quantile {
+func readSummaryVec(m prometheus.ObserverVec, l prometheus.Labels) []*dto.Quantile {
 	pb := &dto.Metric{}
-	s := m.With(l)
+	s := m.With(l).(prometheus.Metric)
 	s.Write(pb)
 	return pb.GetSummary().GetQuantile()
 }
@@ -35,14 +35,14 @@ func TestHttpMetricUpdate(t *testing.T) {
 	mr := NewMetricReporter()
 
 	// add datapoint
-	m := &Meta{"01_tc", "01_01_ts", 0, 0, Timestamp(time.Now()),
-		Elapsed(600 * time.Millisecond), "something went wrong!"}
+	m := &Meta{Testcase: "01_tc", Teststep: "01_01_ts", Timestamp: Timestamp(time.Now()),
+		Elapsed: Elapsed(600 * time.Millisecond), Error: "something went wrong!"}
 	mr.Update(m)
 
 	// check that datapoint was reported
 	if exp, got := 600.0, readSummaryVec(mr.elapsed,
 		prometheus.Labels{"teststep": "01_01_ts"})[0].GetValue(); exp != got {
-		t.Errorf("Expected elapsed %d, got %d.", exp, got)
+		t.Errorf("Expected elapsed %f, got %f.", exp, got)
 	}
 	if exp, got := 1.0, readCounterVec(mr.error,
 		prometheus.Labels{"teststep": "01_01_ts"}); exp != got {
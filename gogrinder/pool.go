@@ -0,0 +1,338 @@
+package gogrinder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	time "github.com/finklabs/ttime"
+)
+
+// Data pools exchange test data between virtual users, for example the ids
+// of the records one testcase creates and another testcase deletes:
+//
+//	gg.NewPool("supercars", gogrinder.PoolOptions{Kind: gogrinder.PoolQueue})
+//
+//	func create(m *gogrinder.Meta, s gogrinder.Settings) {
+//	    ...
+//	    gg.Pool("supercars").Put(id)
+//	}
+//
+//	func delete(m *gogrinder.Meta, s gogrinder.Settings) {
+//	    id, err := gg.Pool("supercars").TakeWait(m.Context())
+//	    ...
+//
+// In distributed mode the agents use the pools of the controller if the
+// loadmodel contains "SharedPools": true.
+
+// PoolKind defines the order in which the values are taken from the pool.
+type PoolKind int
+
+const (
+	PoolSet   PoolKind = iota // no duplicates, take a random value
+	PoolQueue                 // take the oldest value first
+	PoolStack                 // take the newest value first
+)
+
+// PoolOptions configure a data pool.
+type PoolOptions struct {
+	Kind    PoolKind
+	MaxSize int           // maximum number of values (0 = unlimited)
+	TTL     time.Duration // values expire after this duration (0 = never)
+}
+
+var (
+	ErrPoolEmpty    = errors.New("pool is empty")
+	ErrPoolFull     = errors.New("pool is full")
+	ErrPoolNotFound = errors.New("pool not found") // the pool server does not have the pool
+)
+
+// DataPool is what you get from test.Pool(name).
+type DataPool interface {
+	Put(value string) error
+	Take() (string, error)                        // returns ErrPoolEmpty if there is no value
+	TakeWait(ctx context.Context) (string, error) // waits for a value until ctx is done
+	Len() int
+}
+
+// Value of the pool.
+type poolItem struct {
+	value   string
+	expires time.Time
+}
+
+// In-process data pool. It is safe for concurrent use.
+type pool struct {
+	opts    PoolOptions
+	lock    sync.Mutex
+	items   []poolItem
+	members map[string]bool // values of a PoolSet
+	changed chan bool       // closed when a value is put into the pool
+	rand    *rand.Rand
+}
+
+// Create an in-process data pool.
+func newPool(opts PoolOptions) *pool {
+	return &pool{opts: opts, members: make(map[string]bool), changed: make(chan bool),
+		rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Remove the expired values.
+// Careful: the caller needs to hold the pool lock!
+func (p *pool) expire() {
+	if p.opts.TTL <= 0 {
+		return
+	}
+	now := time.Now()
+	items := p.items[:0]
+	for _, it := range p.items {
+		if now.Before(it.expires) {
+			items = append(items, it)
+		} else {
+			delete(p.members, it.value)
+		}
+	}
+	p.items = items
+}
+
+// Put a value into the pool. Putting a value that is already contained in a
+// PoolSet renews its TTL.
+func (p *pool) Put(value string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.expire()
+	it := poolItem{value: value, expires: time.Now().Add(p.opts.TTL)}
+	if p.opts.Kind == PoolSet && p.members[value] {
+		for i := range p.items {
+			if p.items[i].value == value {
+				p.items[i] = it
+			}
+		}
+		return nil
+	}
+	if p.opts.MaxSize > 0 && len(p.items) >= p.opts.MaxSize {
+		return ErrPoolFull
+	}
+	p.items = append(p.items, it)
+	if p.opts.Kind == PoolSet {
+		p.members[value] = true
+	}
+	// wake up the waiting users
+	close(p.changed)
+	p.changed = make(chan bool)
+	return nil
+}
+
+// Take a value from the pool.
+func (p *pool) Take() (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	v, _, err := p.take()
+	return v, err
+}
+
+// Take a value from the pool. If the pool is empty it also returns the
+// channel that is closed by the next Put.
+// Careful: the caller needs to hold the pool lock!
+func (p *pool) take() (string, chan bool, error) {
+	p.expire()
+	if len(p.items) == 0 {
+		return "", p.changed, ErrPoolEmpty
+	}
+	i := 0
+	switch p.opts.Kind {
+	case PoolSet:
+		i = p.rand.Intn(len(p.items))
+	case PoolStack:
+		i = len(p.items) - 1
+	}
+	v := p.items[i].value
+	p.items = append(p.items[:i], p.items[i+1:]...)
+	delete(p.members, v)
+	return v, nil, nil
+}
+
+// Take a value from the pool. If the pool is empty it waits for the next value
+// until the context is done (e.g. m.Context() of the iteration).
+func (p *pool) TakeWait(ctx context.Context) (string, error) {
+	for {
+		p.lock.Lock()
+		v, changed, err := p.take()
+		p.lock.Unlock()
+		if err == nil {
+			return v, nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Number of values in the pool.
+func (p *pool) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.expire()
+	return len(p.items)
+}
+
+// Interval in which remote pools are polled while waiting for a value.
+var poolPollInterval = 50 * time.Millisecond
+
+// Data pool that is served by the TestServer of the controller.
+type remotePool struct {
+	url    string // e.g. http://localhost:3030/pools/supercars
+	client *http.Client
+}
+
+// Helper to send a request to the pool server.
+func (p *remotePool) request(method string, path string, body interface{}, result interface{}) error {
+	var b bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, p.url+path, &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusConflict:
+		return ErrPoolFull
+	case http.StatusNotFound:
+		return ErrPoolNotFound
+	default:
+		return fmt.Errorf("pool server responded %s to %s %s", resp.Status, method, p.url+path)
+	}
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
+
+// Put a value into the remote pool.
+func (p *remotePool) Put(value string) error {
+	return p.request("POST", "", map[string]string{"value": value}, nil)
+}
+
+// Take a value from the remote pool.
+func (p *remotePool) Take() (string, error) {
+	var res struct {
+		Value string `json:"value"`
+		Empty bool   `json:"empty"`
+	}
+	if err := p.request("POST", "/take", nil, &res); err != nil {
+		return "", err
+	}
+	if res.Empty {
+		return "", ErrPoolEmpty
+	}
+	return res.Value, nil
+}
+
+// Take a value from the remote pool. If the pool is empty it polls the pool
+// server until the context is done.
+func (p *remotePool) TakeWait(ctx context.Context) (string, error) {
+	for {
+		v, err := p.Take()
+		if err != ErrPoolEmpty {
+			return v, err
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(poolPollInterval):
+		}
+	}
+}
+
+// Number of values in the remote pool (0 if the pool server is not available).
+func (p *remotePool) Len() int {
+	var res struct {
+		Len int `json:"len"`
+	}
+	p.request("GET", "", nil, &res)
+	return res.Len
+}
+
+// Create (or replace) the data pool with the given name.
+func (test *TestScenario) NewPool(name string, opts PoolOptions) DataPool {
+	test.poolsLock.Lock()
+	defer test.poolsLock.Unlock()
+	if test.pools == nil {
+		test.pools = make(map[string]*pool)
+	}
+	p := newPool(opts)
+	test.pools[name] = p
+	if test.poolServer != "" {
+		return test.remotePool(name)
+	}
+	return p
+}
+
+// Data pool with the given name. Pools that do not exist are created as
+// PoolSet without limits. The pool server does not create pools, shared pools
+// need to be created with NewPool on the controller (ErrPoolNotFound).
+func (test *TestScenario) Pool(name string) DataPool {
+	test.poolsLock.Lock()
+	defer test.poolsLock.Unlock()
+	if test.poolServer != "" {
+		return test.remotePool(name)
+	}
+	return test.localPool(name)
+}
+
+// Check if the in-process pool with the given name exists.
+func (test *TestScenario) HasPool(name string) bool {
+	test.poolsLock.Lock()
+	defer test.poolsLock.Unlock()
+	_, ok := test.pools[name]
+	return ok
+}
+
+// In-process pool with the given name.
+// Careful: the caller needs to hold the pools lock!
+func (test *TestScenario) localPool(name string) *pool {
+	if test.pools == nil {
+		test.pools = make(map[string]*pool)
+	}
+	p, ok := test.pools[name]
+	if !ok {
+		p = newPool(PoolOptions{})
+		test.pools[name] = p
+	}
+	return p
+}
+
+// Pool that is served by the pool server.
+// Careful: the caller needs to hold the pools lock!
+func (test *TestScenario) remotePool(name string) DataPool {
+	return &remotePool{test.poolServer + "/pools/" + url.PathEscape(name),
+		&http.Client{Timeout: 10 * time.Second}}
+}
+
+// Use the data pools served by the TestServer with the given base url
+// (e.g. the controller http://localhost:3030). An empty url switches back
+// to the in-process pools.
+func (test *TestScenario) SharePools(server string) {
+	test.poolsLock.Lock()
+	defer test.poolsLock.Unlock()
+	test.poolServer = strings.TrimRight(server, "/")
+}
@@ -0,0 +1,179 @@
+package gogrinder
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestPoolQueue(t *testing.T) {
+	p := newPool(PoolOptions{Kind: PoolQueue})
+	for _, v := range []string{"1", "2", "3"} {
+		p.Put(v)
+	}
+	for _, exp := range []string{"1", "2", "3"} {
+		if v, err := p.Take(); err != nil || v != exp {
+			t.Errorf("Value %s (%v) not as expected: %s", v, err, exp)
+		}
+	}
+	if _, err := p.Take(); err != ErrPoolEmpty {
+		t.Errorf("Expected the pool to be empty but got: %v", err)
+	}
+}
+
+func TestPoolStack(t *testing.T) {
+	p := newPool(PoolOptions{Kind: PoolStack})
+	for _, v := range []string{"1", "2", "3"} {
+		p.Put(v)
+	}
+	for _, exp := range []string{"3", "2", "1"} {
+		if v, err := p.Take(); err != nil || v != exp {
+			t.Errorf("Value %s (%v) not as expected: %s", v, err, exp)
+		}
+	}
+}
+
+func TestPoolSet(t *testing.T) {
+	p := newPool(PoolOptions{Kind: PoolSet})
+	for _, v := range []string{"1", "2", "1"} {
+		p.Put(v)
+	}
+	if p.Len() != 2 {
+		t.Fatalf("Expected no duplicates in the set but got %d values", p.Len())
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		v, _ := p.Take()
+		seen[v] = true
+	}
+	if !seen["1"] || !seen["2"] {
+		t.Errorf("Values not as expected: %v", seen)
+	}
+}
+
+func TestPoolMaxSize(t *testing.T) {
+	p := newPool(PoolOptions{Kind: PoolQueue, MaxSize: 2})
+	p.Put("1")
+	p.Put("2")
+	if err := p.Put("3"); err != ErrPoolFull {
+		t.Errorf("Expected the pool to be full but got: %v", err)
+	}
+}
+
+func TestPoolTTL(t *testing.T) {
+	time.Freeze(time.Now())
+	defer time.Unfreeze()
+
+	p := newPool(PoolOptions{Kind: PoolQueue, TTL: time.Second})
+	p.Put("1")
+	time.Sleep(500 * time.Millisecond)
+	p.Put("2")
+	time.Sleep(600 * time.Millisecond)
+	if p.Len() != 1 {
+		t.Fatalf("Expected the first value to be expired: %d values", p.Len())
+	}
+	if v, _ := p.Take(); v != "2" {
+		t.Errorf("Value %s not as expected!", v)
+	}
+}
+
+func TestPoolTakeWait(t *testing.T) {
+	p := newPool(PoolOptions{Kind: PoolQueue})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		p.Put("1")
+	}()
+	v, err := p.TakeWait(context.Background())
+	if err != nil || v != "1" {
+		t.Errorf("Value %s (%v) not as expected!", v, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.TakeWait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected TakeWait to end with the context but got: %v", err)
+	}
+}
+
+func TestPoolConcurrent(t *testing.T) {
+	p := newPool(PoolOptions{Kind: PoolQueue})
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	taken := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			p.Put(string(rune('a' + i)))
+		}(i)
+		go func() {
+			defer wg.Done()
+			v, _ := p.TakeWait(context.Background())
+			lock.Lock()
+			taken[v] = true
+			lock.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(taken) != 10 || p.Len() != 0 {
+		t.Errorf("Expected every value to be taken once: %v", taken)
+	}
+}
+
+func TestTestPool(t *testing.T) {
+	fake := NewTest()
+	p := fake.NewPool("ids", PoolOptions{Kind: PoolStack})
+	if fake.Pool("ids") != p {
+		t.Errorf("Expected the pool to be registered by name!")
+	}
+	if fake.Pool("other") == nil || fake.Pool("other") != fake.Pool("other") {
+		t.Errorf("Expected the pool to be created on demand!")
+	}
+}
+
+func TestSharedPools(t *testing.T) {
+	controller := NewTest()
+	controller.NewPool("ids", PoolOptions{Kind: PoolQueue})
+	srv := TestServer{test: controller}
+	ts := httptest.NewServer(srv.Router())
+	defer ts.Close()
+
+	agent := NewTest()
+	agent.SharePools(ts.URL + "/")
+	p := agent.Pool("ids")
+	if err := p.Put("1"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if controller.Pool("ids").Len() != 1 || p.Len() != 1 {
+		t.Fatalf("Expected the value in the pool of the controller!")
+	}
+	if v, err := p.Take(); err != nil || v != "1" {
+		t.Errorf("Value %s (%v) not as expected!", v, err)
+	}
+	if _, err := p.Take(); err != ErrPoolEmpty {
+		t.Errorf("Expected the pool to be empty but got: %v", err)
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		controller.Pool("ids").Put("2")
+	}()
+	if v, err := p.TakeWait(context.Background()); err != nil || v != "2" {
+		t.Errorf("Value %s (%v) not as expected!", v, err)
+	}
+
+	// the pool server does not create pools
+	unknown := agent.Pool("unknown")
+	if err := unknown.Put("1"); err != ErrPoolNotFound {
+		t.Errorf("Expected ErrPoolNotFound but got: %v", err)
+	}
+	if _, err := unknown.TakeWait(context.Background()); err != ErrPoolNotFound {
+		t.Errorf("Expected ErrPoolNotFound but got: %v", err)
+	}
+	if controller.HasPool("unknown") {
+		t.Errorf("Pool is not expected to be created by the pool server!")
+	}
+}
@@ -0,0 +1,178 @@
+package gogrinder
+
+import (
+	"net/http"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/finklabs/graceful"
+	time "github.com/finklabs/ttime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// PrometheusOptions configure the PrometheusExporter.
+type PrometheusOptions struct {
+	Addr         string        // listen address of the /metrics endpoint (e.g. ":9110"; "" = no endpoint)
+	PushURL      string        // url of the Pushgateway (e.g. http://localhost:9091; "" = no push)
+	PushInterval time.Duration // interval of the pushes during the test (default 10s)
+	Job          string        // job name used for the Pushgateway (default "gogrinder")
+	RunID        string        // value of the run_id label of all metrics ("" = no label)
+	ScrapeWait   time.Duration // wait at most this long for the final scrape of the endpoint (0 = no wait)
+}
+
+// PrometheusExporter exposes the metrics of the prometheus reporters (e.g.
+// MetricReporter, req.HttpMetricReporter) on the /metrics endpoint and / or
+// pushes them to a Pushgateway. Pushing is useful for short-lived runs that
+// end before Prometheus scrapes the endpoint.
+type PrometheusExporter struct {
+	opts    PrometheusOptions
+	pull    prometheus.Gatherer  // metrics of the endpoint (with the run_id label)
+	push    *prometheus.Registry // metrics of the pushes (run_id is part of the grouping key)
+	srv     *graceful.Server
+	scraped chan bool // signals the scrapes of the endpoint to WaitScrape
+	quit    chan bool // stop the pushes
+	done    chan bool // closed when the pushes stopped
+}
+
+// Create a PrometheusExporter for the given collectors. Collectors that can
+// not be registered (e.g. duplicate metrics) are logged and skipped.
+func NewPrometheusExporter(opts PrometheusOptions, collectors ...prometheus.Collector) (*PrometheusExporter, error) {
+	if opts.PushInterval <= 0 {
+		opts.PushInterval = 10 * time.Second
+	}
+	if opts.Job == "" {
+		opts.Job = "gogrinder"
+	}
+	reg := prometheus.NewRegistry()
+	e := &PrometheusExporter{opts: opts, pull: reg, push: prometheus.NewRegistry(),
+		scraped: make(chan bool)}
+	var pull prometheus.Registerer = reg
+	if opts.RunID != "" {
+		pull = prometheus.WrapRegistererWith(prometheus.Labels{"run_id": opts.RunID}, reg)
+	}
+	for _, c := range collectors {
+		if err := pull.Register(c); err != nil {
+			log.Errorf("can not export the metrics of %T: %v", c, err)
+			continue
+		}
+		e.push.Register(c) // same collectors as the pull registry
+	}
+	return e, nil
+}
+
+// Assemble the Server for the Prometheus reporter. It serves the metrics of the
+// default prometheus registry on the given address (e.g. ":9110").
+//
+// Deprecated: use NewPrometheusExporter with PrometheusOptions.Addr instead.
+func NewPrometheusReporterServer(addr ...string) *graceful.Server {
+	e := &PrometheusExporter{pull: prometheus.DefaultGatherer, scraped: make(chan bool)}
+	handler := e.Handler()
+	// register the /metrics route
+	http.Handle("/metrics", handler)
+
+	srv := &graceful.Server{
+		Timeout: 5 * time.Second,
+		Server: &http.Server{
+			Handler: handler,
+		},
+	}
+	if len(addr) > 0 {
+		srv.Addr = addr[0]
+	}
+	return srv
+}
+
+// Handler of the /metrics endpoint.
+func (e *PrometheusExporter) Handler() http.Handler {
+	h := promhttp.HandlerFor(e.pull, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r)
+		select {
+		case e.scraped <- true:
+		default: // nobody is waiting
+		}
+	})
+}
+
+// Wait until the endpoint is scraped once more so Prometheus reads in the
+// final metrics of the test. Returns false if there was no scrape within the
+// timeout.
+func (e *PrometheusExporter) WaitScrape(timeout time.Duration) bool {
+	select {
+	case <-e.scraped:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// Start the /metrics endpoint and the pushes (depending on the options).
+func (e *PrometheusExporter) Start() {
+	if e.opts.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", e.Handler())
+		e.srv = &graceful.Server{
+			Timeout: 5 * time.Second,
+			Server: &http.Server{
+				Addr:    e.opts.Addr,
+				Handler: mux,
+			},
+		}
+		go func(srv *graceful.Server) {
+			if err := srv.ListenAndServe(); err != nil {
+				// if for example the port is in use we continue...
+				log.Errorf("can not start the prometheus endpoint: %v", err)
+			}
+		}(e.srv)
+	}
+	if e.opts.PushURL != "" {
+		e.quit = make(chan bool)
+		e.done = make(chan bool)
+		go e.pusher()
+	}
+}
+
+// Push the metrics every PushInterval.
+func (e *PrometheusExporter) pusher() {
+	defer close(e.done)
+	t := time.NewTicker(e.opts.PushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := e.Push(); err != nil {
+				log.Errorf("can not push metrics: %v", err)
+			}
+		case <-e.quit:
+			return
+		}
+	}
+}
+
+// Push the metrics to the Pushgateway. The metrics of the job (and run) are
+// replaced.
+func (e *PrometheusExporter) Push() error {
+	p := push.New(e.opts.PushURL, e.opts.Job).Gatherer(e.push)
+	if e.opts.RunID != "" {
+		p = p.Grouping("run_id", e.opts.RunID)
+	}
+	return p.Push()
+}
+
+// Stop the pushes and the endpoint. If pushing is enabled the final metrics
+// are pushed at the end of the test.
+func (e *PrometheusExporter) Close() error {
+	var err error
+	if e.quit != nil {
+		close(e.quit)
+		<-e.done
+		e.quit = nil
+		err = e.Push()
+	}
+	if e.srv != nil {
+		e.srv.Stop(time.Second)
+		e.srv = nil
+	}
+	return err
+}
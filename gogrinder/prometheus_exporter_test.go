@@ -0,0 +1,163 @@
+package gogrinder
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPrometheusExporterHandlerRunID(t *testing.T) {
+	mr := NewMetricReporter()
+	e, err := NewPrometheusExporter(PrometheusOptions{RunID: "42"}, mr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mr.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond)})
+
+	ts := httptest.NewServer(e.Handler())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `gogrinder_elapsed_ms_count{run_id="42",teststep="sth"} 1`) {
+		t.Errorf("Metrics do not contain the run_id label: %s", body)
+	}
+}
+
+func TestNewPrometheusReporterServer(t *testing.T) {
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: "gogrinder_test_total", Help: "test"})
+	prometheus.MustRegister(c)
+	defer prometheus.Unregister(c)
+	c.Inc()
+
+	srv := NewPrometheusReporterServer(":9111")
+	if srv.Addr != ":9111" {
+		t.Errorf("Address %s not as expected!", srv.Addr)
+	}
+	rec := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "gogrinder_test_total 1") {
+		t.Errorf("Metrics of the default registry expected but got: %s", rec.Body.String())
+	}
+}
+
+func TestPrometheusExporterHistogram(t *testing.T) {
+	mr := NewHistogramMetricReporter([]float64{10, 100})
+	e, _ := NewPrometheusExporter(PrometheusOptions{}, mr)
+	mr.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond)})
+	mr.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(80 * time.Millisecond)})
+
+	rec := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, exp := range []string{
+		`gogrinder_elapsed_ms_bucket{teststep="sth",le="10"} 1`,
+		`gogrinder_elapsed_ms_bucket{teststep="sth",le="100"} 2`,
+		`gogrinder_elapsed_ms_count{teststep="sth"} 2`,
+	} {
+		if !strings.Contains(body, exp) {
+			t.Errorf("Metrics do not contain %s: %s", exp, body)
+		}
+	}
+}
+
+func TestPrometheusExporterSkipsDuplicateCollector(t *testing.T) {
+	mr := NewMetricReporter()
+	// both provide gogrinder_elapsed_ms
+	e, err := NewPrometheusExporter(PrometheusOptions{}, mr, NewMetricReporter())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mr.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond)})
+
+	rec := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `gogrinder_elapsed_ms_count{teststep="sth"} 1`) {
+		t.Errorf("Metrics of the first collector expected: %s", rec.Body.String())
+	}
+}
+
+func TestPrometheusExporterWaitScrape(t *testing.T) {
+	e, _ := NewPrometheusExporter(PrometheusOptions{}, NewMetricReporter())
+	if e.WaitScrape(10 * time.Millisecond) {
+		t.Errorf("WaitScrape expected to time out without a scrape!")
+	}
+
+	scraped := make(chan bool)
+	go func() { scraped <- e.WaitScrape(5 * time.Second) }()
+	for i := 0; i < 500; i++ {
+		// scrapes before WaitScrape waits are not counted, so retry
+		e.Handler().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics", nil))
+		select {
+		case ok := <-scraped:
+			if !ok {
+				t.Errorf("WaitScrape expected to return after the scrape!")
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatalf("WaitScrape did not return after the scrape!")
+}
+
+// Local stand-in for the Pushgateway.
+type pushgatewayStandIn struct {
+	lock   sync.Mutex
+	pushes []string // method and path of the pushes
+}
+
+func (s *pushgatewayStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ioutil.ReadAll(r.Body)
+	s.lock.Lock()
+	s.pushes = append(s.pushes, r.Method+" "+r.URL.Path)
+	s.lock.Unlock()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func TestPrometheusExporterPush(t *testing.T) {
+	standIn := &pushgatewayStandIn{}
+	ts := httptest.NewServer(standIn)
+	defer ts.Close()
+
+	mr := NewMetricReporter()
+	e, err := NewPrometheusExporter(PrometheusOptions{PushURL: ts.URL, PushInterval: time.Hour,
+		RunID: "42"}, mr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	e.Start()
+	mr.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond)})
+	if err := e.Push(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Close pushes the final metrics
+	if err := e.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	exp := "PUT /metrics/job/gogrinder/run_id/42"
+	if len(standIn.pushes) != 2 || standIn.pushes[0] != exp || standIn.pushes[1] != exp {
+		t.Errorf("Pushes expected 2x %s but got: %v", exp, standIn.pushes)
+	}
+}
+
+func TestPrometheusExporterPushError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not available", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	e, _ := NewPrometheusExporter(PrometheusOptions{PushURL: ts.URL, Job: "loadtest"}, NewMetricReporter())
+	if err := e.Push(); err == nil {
+		t.Errorf("Expected a push error!")
+	}
+}
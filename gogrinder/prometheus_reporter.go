@@ -0,0 +1,264 @@
+package gogrinder
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	time "github.com/finklabs/ttime"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusReporter is a generic prometheus reporter for custom Metric types.
+// The prometheus metrics are derived from the struct tags of the metric type
+// (fields of embedded structs like Meta are included):
+//
+//	type MqttMetric struct {
+//		gogrinder.Meta                    // gogrinder_elapsed_ms, gogrinder_error_count
+//		Topic   string            `prometheus:"topic,label"`
+//		Payload int               `prometheus:"mqtt_payload_bytes,summary"`
+//		Ack     gogrinder.Elapsed `prometheus:"mqtt_ack_ms,histogram" help:"Time until the ack in ms."`
+//		QoS     int               `prometheus:"mqtt_qos_count,counter,qos"`
+//	}
+//
+// The tag is `prometheus:"<name>,<type>[,<label>|kb]"` with the types:
+//
+//	summary    observe the value (Elapsed and time.Duration in ms); with kb
+//	           the value (bytes) is observed in kb
+//	histogram  same as summary but with the DefBuckets
+//	counter    add numbers, count true bools and non-empty strings; with
+//	           <label> count the value (every element of slices) as label
+//	label      use the value as additional label of all metrics of the type
+//
+// All metrics have the label teststep. The help text can be given with the
+// tag `help:"..."`. Metrics of other types are ignored by the reporter.
+//
+// The fields of Meta are reported as gogrinder_elapsed_ms and
+// gogrinder_error_count like MetricReporter and req.HttpMetricReporter do, so
+// only one of these reporters can be exported at a time.
+type PrometheusReporter struct {
+	typ    reflect.Type
+	labels []promLabel
+	fields []promField
+}
+
+// Field that is used as additional label.
+type promLabel struct {
+	name  string
+	index []int
+}
+
+// Field that is reported as summary, histogram or counter.
+type promField struct {
+	index    []int
+	label    string  // counter: label for the value ("" = count the value)
+	scale    float64 // summary, histogram: divisor of the value (e.g. 1024 for kb)
+	observer prometheus.ObserverVec
+	counter  *prometheus.CounterVec
+}
+
+var (
+	elapsedType  = reflect.TypeOf(Elapsed(0))
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// Create a PrometheusReporter for the type of the given metric (e.g.
+// &MqttMetric{}). Invalid tags are reported as error.
+func NewPrometheusReporter(metric Metric) (*PrometheusReporter, error) {
+	typ := reflect.TypeOf(metric)
+	st := typ
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("metric type %s is not a struct", typ)
+	}
+	r := &PrometheusReporter{typ: typ}
+	type tagged struct {
+		field reflect.StructField
+		tag   []string
+	}
+	var metrics []tagged
+	for _, f := range promFields(st, nil) {
+		tag := strings.Split(f.Tag.Get("prometheus"), ",")
+		if len(tag) < 2 || len(tag) > 3 || tag[0] == "" {
+			return nil, fmt.Errorf("field %s: invalid prometheus tag %q", f.Name, f.Tag.Get("prometheus"))
+		}
+		if tag[1] == "label" {
+			if len(tag) != 2 || !promLabelKind(f.Type.Kind()) {
+				return nil, fmt.Errorf("field %s: can not be used as label", f.Name)
+			}
+			r.labels = append(r.labels, promLabel{tag[0], f.Index})
+			continue
+		}
+		metrics = append(metrics, tagged{f, tag})
+	}
+
+	labels := []string{"teststep"}
+	for _, l := range r.labels {
+		labels = append(labels, l.name)
+	}
+	for _, m := range metrics {
+		f, tag := m.field, m.tag
+		help := f.Tag.Get("help")
+		if help == "" {
+			help = fmt.Sprintf("Current %s of gogrinder teststep.", f.Name)
+		}
+		pf := promField{index: f.Index}
+		switch tag[1] {
+		case "summary", "histogram":
+			if !promValueKind(f.Type.Kind()) {
+				return nil, fmt.Errorf("field %s: can not be observed by a %s", f.Name, tag[1])
+			}
+			if len(tag) == 3 {
+				if tag[2] != "kb" {
+					return nil, fmt.Errorf("field %s: unknown unit %q", f.Name, tag[2])
+				}
+				pf.scale = 1024
+			}
+			if tag[1] == "summary" {
+				pf.observer = prometheus.NewSummaryVec(prometheus.SummaryOpts{
+					Name:       tag[0],
+					Help:       help,
+					Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.95: 0.005, 0.99: 0.001},
+				}, labels)
+			} else {
+				pf.observer = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+					Name:    tag[0],
+					Help:    help,
+					Buckets: DefBuckets,
+				}, labels)
+			}
+		case "counter":
+			l := labels
+			if len(tag) == 3 {
+				pf.label = tag[2]
+				l = append(append([]string{}, labels...), pf.label)
+				if !promLabelKind(f.Type.Kind()) && !(f.Type.Kind() == reflect.Slice &&
+					promLabelKind(f.Type.Elem().Kind())) {
+					return nil, fmt.Errorf("field %s: can not be used as label", f.Name)
+				}
+			} else if !promValueKind(f.Type.Kind()) && f.Type.Kind() != reflect.String {
+				return nil, fmt.Errorf("field %s: can not be counted", f.Name)
+			}
+			pf.counter = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: tag[0],
+				Help: help,
+			}, l)
+		default:
+			return nil, fmt.Errorf("field %s: unknown prometheus type %q", f.Name, tag[1])
+		}
+		r.fields = append(r.fields, pf)
+	}
+	return r, nil
+}
+
+// Exported fields with prometheus tag including the fields of embedded structs.
+func promFields(st reflect.Type, index []int) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		f.Index = append(append([]int{}, index...), i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, promFields(f.Type, f.Index)...)
+			continue
+		}
+		if f.PkgPath != "" || f.Tag.Get("prometheus") == "" {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+func promValueKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Bool:
+		return true
+	}
+	return false
+}
+
+func promLabelKind(k reflect.Kind) bool {
+	return k == reflect.String || promValueKind(k)
+}
+
+// Value of the field (Elapsed and time.Duration in ms).
+func promValue(v reflect.Value) float64 {
+	if v.Type() == elapsedType || v.Type() == durationType {
+		return float64(v.Int()) / float64(time.Millisecond)
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Describe implements prometheus.Collector so the reporter can be registered.
+func (r *PrometheusReporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, f := range r.fields {
+		f.collector().Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector so the reporter can be registered.
+func (r *PrometheusReporter) Collect(ch chan<- prometheus.Metric) {
+	for _, f := range r.fields {
+		f.collector().Collect(ch)
+	}
+}
+
+func (f promField) collector() prometheus.Collector {
+	if f.observer != nil {
+		return f.observer
+	}
+	return f.counter
+}
+
+// Update the prometheus metrics from the tagged fields of the metric.
+func (r *PrometheusReporter) Update(m Metric) {
+	if reflect.TypeOf(m) != r.typ {
+		return
+	}
+	v := reflect.Indirect(reflect.ValueOf(m))
+	labels := []string{m.GetTeststep()}
+	for _, l := range r.labels {
+		labels = append(labels, fmt.Sprint(v.FieldByIndex(l.index).Interface()))
+	}
+	for _, f := range r.fields {
+		fv := v.FieldByIndex(f.index)
+		switch {
+		case f.observer != nil:
+			value := promValue(fv)
+			if f.scale > 0 {
+				value /= f.scale
+			}
+			f.observer.WithLabelValues(labels...).Observe(value)
+		case f.label != "" && fv.Kind() == reflect.Slice:
+			for i := 0; i < fv.Len(); i++ {
+				f.counter.WithLabelValues(append(labels, fmt.Sprint(fv.Index(i).Interface()))...).Inc()
+			}
+		case f.label != "":
+			f.counter.WithLabelValues(append(labels, fmt.Sprint(fv.Interface()))...).Inc()
+		case fv.Kind() == reflect.String:
+			if fv.Len() > 0 {
+				f.counter.WithLabelValues(labels...).Inc()
+			}
+		default:
+			if value := promValue(fv); value > 0 {
+				f.counter.WithLabelValues(labels...).Add(value)
+			}
+		}
+	}
+}
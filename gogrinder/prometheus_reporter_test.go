@@ -0,0 +1,128 @@
+package gogrinder
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestCheckPrometheusReporterImplementsReporterInterface(t *testing.T) {
+	s := &PrometheusReporter{}
+	if _, ok := interface{}(s).(Reporter); !ok {
+		t.Errorf("PrometheusReporter does not implement the Reporter interface!")
+	}
+}
+
+// Custom metric of a fictional protocol.
+type mqttMetric struct {
+	Meta
+	Topic    string   `prometheus:"topic,label"`
+	Payload  int      `prometheus:"mqtt_payload_bytes,summary"`
+	Ack      Elapsed  `prometheus:"mqtt_ack_ms,histogram" help:"Time until the ack in ms."`
+	QoS      int      `prometheus:"mqtt_qos_count,counter,qos"`
+	Retries  int      `prometheus:"mqtt_retry_count,counter"`
+	Retained bool     `prometheus:"mqtt_retained_count,counter"`
+	Reasons  []string `prometheus:"mqtt_reason_count,counter,reason"`
+	Ignored  string
+}
+
+// Text exposition of the collected metrics.
+func exposition(t *testing.T, c prometheus.Collector) string {
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestPrometheusReporterStructTags(t *testing.T) {
+	r, err := NewPrometheusReporter(&mqttMetric{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r.Update(&mqttMetric{Meta{Teststep: "01_publish", Elapsed: Elapsed(8 * time.Millisecond)},
+		"cars", 512, Elapsed(4 * time.Millisecond), 1, 2, true, []string{"quota", "busy"}, "x"})
+	r.Update(&mqttMetric{Meta{Teststep: "01_publish", Elapsed: Elapsed(12 * time.Millisecond),
+		Error: "timeout"}, "cars", 256, Elapsed(40 * time.Millisecond), 1, 0, false, nil, ""})
+	// other metric types are ignored
+	r.Update(&Meta{Teststep: "01_publish", Error: "ignored"})
+
+	body := exposition(t, r)
+	for _, exp := range []string{
+		`gogrinder_elapsed_ms_sum{teststep="01_publish",topic="cars"} 20`,
+		`gogrinder_elapsed_ms_count{teststep="01_publish",topic="cars"} 2`,
+		`gogrinder_error_count{teststep="01_publish",topic="cars"} 1`,
+		`mqtt_payload_bytes_sum{teststep="01_publish",topic="cars"} 768`,
+		`# HELP mqtt_ack_ms Time until the ack in ms.`,
+		`mqtt_ack_ms_bucket{teststep="01_publish",topic="cars",le="5"} 1`,
+		`mqtt_ack_ms_bucket{teststep="01_publish",topic="cars",le="50"} 2`,
+		`mqtt_qos_count{qos="1",teststep="01_publish",topic="cars"} 2`,
+		`mqtt_retry_count{teststep="01_publish",topic="cars"} 2`,
+		`mqtt_retained_count{teststep="01_publish",topic="cars"} 1`,
+		`mqtt_reason_count{reason="busy",teststep="01_publish",topic="cars"} 1`,
+		`mqtt_reason_count{reason="quota",teststep="01_publish",topic="cars"} 1`,
+	} {
+		if !strings.Contains(body, exp) {
+			t.Errorf("Metrics do not contain %s: %s", exp, body)
+		}
+	}
+}
+
+func TestPrometheusReporterMeta(t *testing.T) {
+	r, _ := NewPrometheusReporter(&Meta{})
+	r.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond), Error: "sth went wrong"})
+
+	body := exposition(t, r)
+	for _, exp := range []string{
+		`gogrinder_elapsed_ms_count{teststep="sth"} 1`,
+		`gogrinder_error_count{teststep="sth"} 1`,
+	} {
+		if !strings.Contains(body, exp) {
+			t.Errorf("Metrics do not contain %s: %s", exp, body)
+		}
+	}
+}
+
+func TestPrometheusReporterInvalidTags(t *testing.T) {
+	type invalidType struct {
+		Meta
+		Value float64 `prometheus:"value,gauge"`
+	}
+	type invalidLabel struct {
+		Meta
+		Values []string `prometheus:"values,label"`
+	}
+	type invalidSummary struct {
+		Meta
+		Value string `prometheus:"value,summary"`
+	}
+	type invalidUnit struct {
+		Meta
+		Value int `prometheus:"value,summary,mb"`
+	}
+	type invalidTag struct {
+		Meta
+		Value int `prometheus:"value"`
+	}
+	for _, c := range []struct {
+		metric Metric
+		err    string
+	}{
+		{&invalidType{}, `field Value: unknown prometheus type "gauge"`},
+		{&invalidLabel{}, "field Values: can not be used as label"},
+		{&invalidSummary{}, "field Value: can not be observed by a summary"},
+		{&invalidUnit{}, `field Value: unknown unit "mb"`},
+		{&invalidTag{}, `field Value: invalid prometheus tag "value"`},
+	} {
+		_, err := NewPrometheusReporter(c.metric)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("Error expected %s but was: %v", c.err, err)
+		}
+	}
+}
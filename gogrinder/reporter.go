@@ -0,0 +1,95 @@
+package gogrinder
+
+import (
+	"io"
+
+	log "github.com/Sirupsen/logrus"
+	time "github.com/finklabs/ttime"
+)
+
+// The Reporter interface only needs Update. Reporters can implement the
+// optional lifecycle interfaces below to buffer their output, write headers and
+// footers or summarise the run. Exec calls Start before the first measurement
+// and End after the last one. The collector calls Flush every flush interval
+// and when it finishes. GoGrinder closes the reporters (io.Closer) at the end.
+
+// Metadata of the test run.
+type RunInfo struct {
+	Scenario  string                 // selected scenario
+	Start     time.Time              // start of the run
+	Loadmodel map[string]interface{} // loadmodel.json of the run
+}
+
+// Reporters that need to know the start of the run (e.g. to write a header).
+type StartReporter interface {
+	Start(run RunInfo) error
+}
+
+// Reporters that buffer their output (e.g. EventReporter). Reporters with their
+// own flush interval (e.g. InfluxReporter) take care of it themselves.
+type FlushReporter interface {
+	Flush() error
+}
+
+// Reporters that need the final statistics at the end of the run (e.g. to
+// write a summary). The thresholds are already checked.
+type EndReporter interface {
+	End(stats Statistics) error
+}
+
+// Default interval of the collector to flush the reporters.
+const DefaultFlushInterval = time.Second
+
+// Set the interval of the collector to flush the reporters.
+func (test *TestStatistics) SetFlushInterval(interval time.Duration) {
+	test.lock.Lock()
+	test.flushInterval = interval
+	test.lock.Unlock()
+}
+
+// Inform the reporters about the start of the run.
+func (test *TestStatistics) startReporters(run RunInfo) {
+	for _, r := range test.reporters {
+		if s, ok := r.(StartReporter); ok {
+			if err := s.Start(run); err != nil {
+				log.Errorf("can not start reporter: %v", err)
+			}
+		}
+	}
+}
+
+// Careful: only the collector flushes the reporters (no concurrent Updates)!
+func (test *TestStatistics) flushReporters() {
+	for _, r := range test.reporters {
+		if f, ok := r.(FlushReporter); ok {
+			if err := f.Flush(); err != nil {
+				log.Errorf("can not flush reporter: %v", err)
+			}
+		}
+	}
+}
+
+// Hand the final statistics to the reporters.
+func (test *TestStatistics) endReporters() {
+	for _, r := range test.reporters {
+		if e, ok := r.(EndReporter); ok {
+			if err := e.End(test); err != nil {
+				log.Errorf("can not end reporter: %v", err)
+			}
+		}
+	}
+}
+
+// Close the reporters that implement io.Closer (e.g. write the last batch or
+// the footer). The first error is returned.
+func (test *TestStatistics) CloseReportPlugins() error {
+	var err error
+	for _, r := range test.reporters {
+		if c, ok := r.(io.Closer); ok {
+			if cerr := c.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	}
+	return err
+}
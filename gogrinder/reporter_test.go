@@ -0,0 +1,114 @@
+package gogrinder
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestCheckBufferedReportersImplementFlushReporterInterface(t *testing.T) {
+	for _, r := range []Reporter{&EventReporter{}, &JtlReporter{}} {
+		if _, ok := r.(FlushReporter); !ok {
+			t.Errorf("%T does not implement the FlushReporter interface!", r)
+		}
+	}
+	// reporters with their own flush interval are not flushed by the collector
+	for _, r := range []Reporter{&InfluxReporter{}, &StatsdReporter{}, &GraphiteReporter{}} {
+		if _, ok := r.(FlushReporter); ok {
+			t.Errorf("%T is not expected to implement the FlushReporter interface!", r)
+		}
+	}
+}
+
+// Reporter that records the calls of the lifecycle.
+type lifecycleReporter struct {
+	lock    sync.Mutex
+	calls   []string
+	flushes int
+}
+
+func (r *lifecycleReporter) record(call string) {
+	r.lock.Lock()
+	r.calls = append(r.calls, call)
+	r.lock.Unlock()
+}
+
+func (r *lifecycleReporter) Start(run RunInfo) error {
+	r.record("start " + run.Scenario + " " + run.Loadmodel["Scenario"].(string))
+	return nil
+}
+
+func (r *lifecycleReporter) Update(m Metric) {
+	r.record("update " + m.GetTeststep())
+}
+
+func (r *lifecycleReporter) Flush() error {
+	r.lock.Lock()
+	r.flushes++
+	r.lock.Unlock()
+	return nil
+}
+
+func (r *lifecycleReporter) End(stats Statistics) error {
+	results := stats.Results("")
+	r.record(fmt.Sprintf("end %s %d", results[0].Teststep, results[0].Count))
+	return nil
+}
+
+func (r *lifecycleReporter) Close() error {
+	r.record("close")
+	return fmt.Errorf("already closed")
+}
+
+func TestReporterLifecycle(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	r := &lifecycleReporter{}
+	fake.AddReportPlugin(r)
+	fake.AddReportPlugin(NewMetricReporter()) // Update-only reporters keep working
+	fake.Testscenario("scenario1", func() {
+		fake.Update(&Meta{Teststep: "sth", Timestamp: Timestamp(time.Now())})
+		fake.Update(&Meta{Teststep: "sth", Timestamp: Timestamp(time.Now())})
+	})
+
+	if err := fake.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := fake.CloseReportPlugins(); err == nil || err.Error() != "already closed" {
+		t.Errorf("Close error not as expected: %v", err)
+	}
+
+	exp := "[start scenario1 scenario1 update sth update sth end sth 2 close]"
+	if fmt.Sprint(r.calls) != exp {
+		t.Errorf("Lifecycle expected %s but was: %v", exp, r.calls)
+	}
+	if r.flushes < 1 {
+		t.Errorf("Reporter is expected to be flushed when the collector finishes!")
+	}
+}
+
+func TestReporterFlushInterval(t *testing.T) {
+	fake := NewTest()
+	r := &lifecycleReporter{}
+	fake.AddReportPlugin(r)
+	fake.SetFlushInterval(10 * time.Millisecond)
+
+	done := fake.Collect()
+	for i := 0; i < 100; i++ {
+		r.lock.Lock()
+		n := r.flushes
+		r.lock.Unlock()
+		if n >= 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(fake.measurements)
+	<-done
+
+	if r.flushes < 3 {
+		t.Errorf("Reporter is expected to be flushed every interval, but was flushed %d times", r.flushes)
+	}
+}
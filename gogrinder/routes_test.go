@@ -80,6 +80,52 @@ func TestRouteGetStatistics(t *testing.T) {
 	}
 }
 
+func TestRouteGetTimeseries(t *testing.T) {
+	fake := NewTest()
+	srv := TestServer{}
+	srv.test = fake
+	done := fake.Collect() // this needs a collector to unblock update
+	ts := time.Date(2016, 5, 1, 10, 0, 0, 0, time.UTC)
+	fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond), Timestamp: Timestamp(ts)})
+	fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(2 * time.Millisecond), Timestamp: Timestamp(ts)})
+	close(fake.measurements)
+	<-done
+
+	// invoke REST service
+	req, _ := http.NewRequest("GET", "/timeseries?since=2016-05-01T10:00:00Z", nil)
+	rsp := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusOK {
+		t.Fatalf("Status code expected: %s but was: %v", "200", rsp.Code)
+	}
+
+	body := rsp.Body.String()
+	if body != `{"results":[{"ts":"2016-05-01T10:00:00Z","teststep":"sth","avg_ms":5,`+
+		`"min_ms":2,"max_ms":8,"count":2,"error":0,"throughput":0.4,"error_rate":0}],`+
+		`"running":false}` {
+		t.Fatalf("Response not as expected: %s", body)
+	}
+}
+
+func TestRouteGetTimeseriesCsv(t *testing.T) {
+	fake := NewTest()
+	srv := TestServer{}
+	srv.test = fake
+
+	// invoke REST service
+	req, _ := http.NewRequest("GET", "/timeseries/csv", nil)
+	rsp := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusOK {
+		t.Fatalf("Status code expected: %v but was: %v", http.StatusOK, rsp.Code)
+	}
+
+	body := rsp.Body.String()
+	if body != "ts, teststep, avg_ms, min_ms, max_ms, count, error, throughput, error_rate, event\n" {
+		t.Fatalf("Response not as expected: %s!", body)
+	}
+}
+
 func TestRouteHandlerStatisticsWithQuery(t *testing.T) {
 	// test with 3 measurements (two stats)
 	srv := TestServer{}
@@ -274,3 +320,53 @@ func TestRouteWebserverStop(t *testing.T) {
 		t.Fatalf("Status code expected: %s but was: %v", "200", rsp.Code)
 	}
 }
+
+func TestRouteAgents(t *testing.T) {
+	fake := NewTest()
+	fake.EnableController()
+	srv := TestServer{}
+	srv.test = fake
+
+	req, _ := http.NewRequest("POST", "/agents", strings.NewReader(`{"host":"host1"}`))
+	rsp := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusOK {
+		t.Fatalf("Status code expected: %v but was: %v", http.StatusOK, rsp.Code)
+	}
+	if body := rsp.Body.String(); body != `{"id":"agent-1"}` {
+		t.Fatalf("Response not as expected: %s!", body)
+	}
+
+	req, _ = http.NewRequest("GET", "/agents/agent-1/command", nil)
+	rsp = httptest.NewRecorder()
+	srv.Router().ServeHTTP(rsp, req)
+	if body := rsp.Body.String(); body != `{"command":"wait"}` {
+		t.Fatalf("Response not as expected: %s!", body)
+	}
+
+	req, _ = http.NewRequest("GET", "/agents/agent-2/command", nil)
+	rsp = httptest.NewRecorder()
+	srv.Router().ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusNotFound {
+		t.Fatalf("Status code expected: %v but was: %v", http.StatusNotFound, rsp.Code)
+	}
+
+	req, _ = http.NewRequest("POST", "/agents/agent-1/measurements", strings.NewReader(`[]`))
+	rsp = httptest.NewRecorder()
+	srv.Router().ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusConflict {
+		t.Fatalf("Status code expected: %v but was: %v", http.StatusConflict, rsp.Code)
+	}
+}
+
+func TestRouteAgentsWithoutController(t *testing.T) {
+	srv := TestServer{}
+	srv.test = NewTest()
+
+	req, _ := http.NewRequest("POST", "/agents", strings.NewReader(`{"host":"host1"}`))
+	rsp := httptest.NewRecorder()
+	srv.Router().ServeHTTP(rsp, req)
+	if rsp.Code != http.StatusNotFound {
+		t.Fatalf("Status code expected: %v but was: %v", http.StatusNotFound, rsp.Code)
+	}
+}
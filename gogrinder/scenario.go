@@ -1,8 +1,11 @@
 package gogrinder
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
 	"reflect"
 	"strconv"
@@ -15,6 +18,7 @@ import (
 type Scenario interface {
 	Config
 	Statistics
+	Controller
 	Testscenario(name string, scenario interface{})
 	NewBracket(name string) *Bracket
 	Schedule(name string, testcase func(*Meta, Settings)) error
@@ -23,9 +27,25 @@ type Scenario interface {
 	Run(name string, testcase func(*Meta, Settings),
 		delay float64, runfor float64, rampup float64, users int, pacing float64,
 		settings Settings)
+	RunRate(name string, testcase func(*Meta, Settings),
+		delay float64, runfor float64, rate float64, users int, settings Settings)
+	RunStages(name string, testcase func(*Meta, Settings),
+		delay float64, runfor float64, stages []Stage, pacing float64, settings Settings)
+	BeforeScenario(hook func() error)
+	AfterScenario(hook func() error)
+	TestcaseHooks(testcase string, hooks Hooks)
+	NewPool(name string, opts PoolOptions) DataPool
+	Pool(name string) DataPool
+	HasPool(name string) bool
+	SharePools(server string)
 	Exec() error
+	ExecContext(ctx context.Context) error
+	Context() context.Context
+	ReadEventLog(r io.Reader, filter EventLogFilter) error
+	HtmlReport(dir string) error
 	Thinktime(tt float64)
 	Status() Status
+	Threads(testcase string) (int, int)
 	Stop()
 	Wait()
 }
@@ -42,6 +62,19 @@ func (ts Timestamp) MarshalJSON() ([]byte, error) {
 	return []byte(t.Format(`"` + time.RFC3339Nano + `"`)), nil
 }
 
+func (ts *Timestamp) UnmarshalJSON(data []byte) error {
+	str, err := strconv.Unquote(string(data))
+	if err != nil {
+		return err
+	}
+	t, err := time.Parse(time.RFC3339Nano, str)
+	if err != nil {
+		return err
+	}
+	*ts = Timestamp(t)
+	return nil
+}
+
 func (ts Timestamp) Unix() int64 {
 	return time.Time(ts).Unix()
 }
@@ -59,6 +92,16 @@ func (e Elapsed) MarshalJSON() ([]byte, error) {
 		float64(time.Millisecond), 'f', 6, 64), nil
 }
 
+func (e *Elapsed) UnmarshalJSON(data []byte) error {
+	// elapsed is marshaled in ms
+	ms, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return err
+	}
+	*e = Elapsed(math.Floor(ms*float64(time.Millisecond) + 0.5))
+	return nil
+}
+
 // Datatype to collect reference information about the execution of a teststep
 type Meta struct {
 	Testcase  string    `json:"testcase"`
@@ -66,8 +109,18 @@ type Meta struct {
 	User      int       `json:"user"`
 	Iteration int       `json:"iteration"`
 	Timestamp Timestamp `json:"ts"`
-	Elapsed   Elapsed   `json:"elapsed"` // elapsed time [ns]
-	Error     string    `json:"error,omitempty"`
+	Elapsed   Elapsed   `json:"elapsed" prometheus:"gogrinder_elapsed_ms,summary" help:"Current time elapsed of gogrinder teststep in ms."` // elapsed time [ns]
+	Error     string    `json:"error,omitempty" prometheus:"gogrinder_error_count,counter" help:"Current error of gogrinder teststep."`
+	Cancelled bool      `json:"cancelled,omitempty"` // aborted because the test was stopped
+	Event     string    `json:"event,omitempty"`     // e.g. loadmodel change of the running test
+	session   *Session     // virtual user that executes the iteration
+	threads   *threadCount // active users when the measurement was taken
+}
+
+// Active users of the testcase and of all testcases.
+type threadCount struct {
+	grp int
+	all int
 }
 
 // I think these should be pointer receivers!
@@ -106,6 +159,36 @@ func (m *Meta) GetError() string {
 	return m.Error
 }
 
+// Access for reporters (e.g. JtlReporter)
+func (m *Meta) GetTestcase() string {
+	return m.Testcase
+}
+
+// Access for reporters (e.g. JtlReporter)
+func (m *Meta) GetUser() int {
+	return m.User
+}
+
+// Access for the default reporter (events are not measurements)
+func (m *Meta) GetEvent() string {
+	return m.Event
+}
+
+// Access for the Bracket
+func (m *Meta) GetCancelled() bool {
+	return m.Cancelled
+}
+
+// Access for the JtlReporter
+func (m *Meta) sampleThreads() *threadCount {
+	return m.threads
+}
+
+// Access for TestScenario.Update
+func (m *Meta) setSampleThreads(c threadCount) {
+	m.threads = &c
+}
+
 // TestScenario datastructure that brings all the GoGrinder functionality together.
 // TestScenario supports multiple interfaces (TestConfig, TestStatistics).
 type TestScenario struct {
@@ -114,6 +197,20 @@ type TestScenario struct {
 	testscenarios map[string]interface{}
 	wg     sync.WaitGroup // waitgroup for testcases
 	status Status         // status (stopped, running, stopping)
+	statusLock sync.RWMutex       // lock that is used on status and ctx
+	ctx        context.Context    // context of the test run (cancelled on Stop)
+	cancel     context.CancelFunc // cancel the context of the test run
+	threadsLock sync.Mutex     // lock that is used on threads
+	threads     map[string]int // active users per testcase
+	cluster     *cluster       // agents of the controller (distributed mode)
+	hooksLock      sync.Mutex       // lock that is used on hooks
+	hooks          map[string]Hooks // hooks per testcase
+	beforeScenario []func() error   // hooks before the scenario
+	afterScenario  []func() error   // hooks after the scenario
+	hookErr        error            // hook that aborted the test
+	poolsLock  sync.Mutex       // lock that is used on pools
+	pools      map[string]*pool // in-process data pools
+	poolServer string           // base url of the server of shared pools
 }
 
 // Constants of internal test status.
@@ -127,9 +224,13 @@ const (
 
 // Constructor takes care of initializing the TestScenario datastructure.
 func NewTest() *TestScenario {
+	ctx, cancel := context.WithCancel(context.Background())
 	t := TestScenario{
 		testscenarios: make(map[string]interface{}),
 		status: Stopped,
+		ctx:    ctx,
+		cancel: cancel,
+		threads: make(map[string]int),
 
 		TestConfig: TestConfig{
 			config: make(map[string]interface{}),
@@ -138,6 +239,8 @@ func NewTest() *TestScenario {
 		TestStatistics: TestStatistics{
 			stats:        make(map[string]stats_value),
 			measurements: make(chan Metric),
+			interval:     defaultTimeseriesInterval,
+			timeseries:   make(map[time.Time]map[string]ts_value),
 		},
 	}
 	return &t
@@ -147,7 +250,6 @@ func NewTest() *TestScenario {
 // Parameter <pace> is given in nanoseconds.
 func (test *TestScenario) paceMaker(pacing time.Duration, elapsed time.Duration) {
 	_, _, _, pv := test.GetScenarioConfig()
-	const small = 2 * time.Second
 
 	// calculate the variable pacing
 	r := (rand.Float64() * 2.0) - 1.0 // r in [-1.0 - 1.0)
@@ -156,17 +258,16 @@ func (test *TestScenario) paceMaker(pacing time.Duration, elapsed time.Duration)
 	if p < 0 {
 		return
 	}
-
-	// split up in small intervals so we can stop out of this
-	for ; p > small; p = p - small {
-		if test.status != Running {
-			break
-		}
-		time.Sleep(small)
+	if test.Status() == Running {
+		test.sleep(p)
 	}
-	// remaining sleep time
-	if test.status == Running {
-		time.Sleep(p)
+}
+
+// Sleep for the given duration. The sleep ends early if the test is stopped.
+func (test *TestScenario) sleep(d time.Duration) {
+	select {
+	case <-test.Context().Done():
+	case <-time.After(d):
 	}
 }
 
@@ -191,11 +292,20 @@ func (test *TestScenario) NewBracket(name string) *Bracket {
 	return &Bracket{name, time.Now(), test.Update}
 }
 
-// End forms the closing bracket of a test-step
+// Metrics that know if they have been aborted by stopping the test.
+type cancelledMetric interface {
+	GetCancelled() bool
+}
+
+// End forms the closing bracket of a test-step. Test-steps that have been
+// aborted by stopping the test are reported as "<name>_cancelled".
 func (b *Bracket) End(m Metric) {
 	m.SetTimestamp(Timestamp(b.start))
 	m.SetElapsed(Elapsed(time.Now().Sub(b.start)))
 	m.SetTeststep(b.name)
+	if c, ok := m.(cancelledMetric); ok && c.GetCancelled() {
+		m.SetTeststep(b.name + "_cancelled")
+	}
 	b.update(m)
 }
 
@@ -206,6 +316,25 @@ func (test *TestScenario) Schedule(name string, testcase func(*Meta, Settings))
 	if err != nil {
 		return err
 	}
+	rate, err := test.GetTestcaseRate(name)
+	if err != nil {
+		return err
+	}
+	stages, err := test.GetTestcaseStages(name)
+	if err != nil {
+		return err
+	}
+	if len(stages) > 0 {
+		if rate > 0.0 {
+			return fmt.Errorf("config for testcase %s can not combine Rate and Stages", name)
+		}
+		test.RunStages(name, testcase, delay, runfor, stages, pacing, settings)
+		return nil
+	}
+	if rate > 0.0 {
+		test.RunRate(name, testcase, delay, runfor, rate, users, settings)
+		return nil
+	}
 	test.Run(name, testcase, delay, runfor, rampup, users, pacing, settings)
 	return nil
 }
@@ -215,15 +344,27 @@ func (test *TestScenario) DoIterations(testcase func(*Meta, Settings),
 	f := func(test *TestScenario) {
 		settings := test.GetSettings()
 		defer test.wg.Done()
+		test.startThread("")
+		defer test.endThread("")
+		session := test.newSession("", 0)
+		hooks := test.testcaseHooks("")
+		if !test.runHook("before_user", hooks.BeforeUser, hooks.Abort,
+			&Meta{session: session}, settings) {
+			return
+		}
+		defer test.runHook("after_user", hooks.AfterUser, hooks.Abort,
+			&Meta{session: session}, settings)
 
+		failures := 0
 		for i := 0; i < iterations; i++ {
 			start := time.Now()
-			meta := &Meta{Iteration: i, User: 0}
-			if test.status == Stopping {
+			meta := session.next("", i)
+			if test.Status() == Stopping {
 				break
 			}
-			testcase(meta, settings)
-			if test.status == Stopping {
+			ok := test.iteration(testcase, hooks, meta, settings)
+			if !test.failures("", 0, ok, &failures) || session.Stopped() ||
+				test.Status() == Stopping {
 				break
 			}
 			test.paceMaker(time.Duration(pacing*float64(time.Second)), time.Now().Sub(start))
@@ -240,6 +381,9 @@ func (test *TestScenario) DoIterations(testcase func(*Meta, Settings),
 }
 
 // Run a testcase. Settings are specified in Seconds!
+// If the Users of the testcase change in the loadmodel while the test is running
+// users are added (using the rampup) or retired. The testcase ends once all
+// of its users finished.
 func (test *TestScenario) Run(name string, testcase func(*Meta, Settings),
 	delay float64, runfor float64, rampup float64, users int, pacing float64,
 	settings Settings) {
@@ -247,72 +391,399 @@ func (test *TestScenario) Run(name string, testcase func(*Meta, Settings),
 	go func(test *TestScenario) {
 		// ramp up the users
 		defer test.wg.Done()
-		time.Sleep(time.Duration(delay * float64(time.Second)))
+		test.sleep(time.Duration(delay * float64(time.Second)))
 		userStart := time.Now()
 
-		test.wg.Add(int(users))
-		for i := 0; i < users; i++ {
-			// start user
+		active := []chan bool{}        // quit channels of the active users
+		exited := make(chan chan bool) // users send their quit channel when they finish
+		running := 0
+		scaledDown := false // all users retired by a config change
+		nbr := 0
+		start := func(wait float64) {
+			quit := make(chan bool)
+			active = append(active, quit)
+			running++
+			test.wg.Add(1)
 			go func(nbr int) {
 				defer test.wg.Done()
-				time.Sleep(time.Duration(float64(nbr) * rampup * float64(time.Second)))
-
-				for j := 0; time.Now().Sub(userStart) <
-					time.Duration(runfor*float64(time.Second)); j++ {
-					// next iteration
-					start := time.Now()
-					meta := &Meta{Testcase: name, Iteration: j, User: nbr}
-					if test.status == Stopping {
+				defer func() { exited <- quit }()
+				test.sleep(time.Duration(wait * float64(time.Second)))
+				test.iterate(name, testcase, nbr, userStart, runfor, pacing, settings, quit)
+			}(nbr)
+			nbr++
+		}
+		for i := 0; i < users; i++ {
+			start(float64(i) * rampup)
+		}
+
+		// follow the changes of the loadmodel. Testcases that are scaled down
+		// to 0 users wait for new users until the end of runfor.
+		changed := test.configChanged()
+		ctx := test.Context()
+		for {
+			var idle <-chan time.Time // end of runfor while there are no users
+			var stopped <-chan struct{}
+			if running == 0 {
+				r, _ := test.liveConfig(name, runfor, pacing)
+				left := time.Duration(r*float64(time.Second)) - time.Now().Sub(userStart)
+				if !scaledDown || left <= 0 || test.Status() != Running {
+					break
+				}
+				idle, stopped = time.After(left), ctx.Done()
+			}
+			select {
+			case <-idle:
+			case <-stopped:
+			case quit := <-exited:
+				running--
+				for i, q := range active {
+					if q == quit {
+						active = append(active[:i], active[i+1:]...)
 						break
 					}
-					testcase(meta, settings)
-					if test.status == Stopping {
-						break
+				}
+			case <-changed:
+				changed = test.configChanged()
+				_, _, rampup, target, _, err := test.GetTestcaseConfig(name)
+				if err != nil || test.Status() != Running {
+					continue
+				}
+				for i := 0; len(active) < target; i++ {
+					start(float64(i) * rampup)
+				}
+				for len(active) > target {
+					// retire the user that was started last
+					close(active[len(active)-1])
+					active = active[:len(active)-1]
+				}
+				scaledDown = target == 0
+			}
+		}
+	}(test)
+}
+
+// Iterations of a single virtual user. The user stops at the end of the <runfor>
+// period or, after finishing the current iteration, once <quit> is closed.
+// Runfor and pacing follow the changes of the loadmodel.
+func (test *TestScenario) iterate(name string, testcase func(*Meta, Settings),
+	nbr int, userStart time.Time, runfor float64, pacing float64,
+	settings Settings, quit <-chan bool) {
+	test.startThread(name)
+	defer test.endThread(name)
+	session := test.newSession(name, nbr)
+	hooks := test.testcaseHooks(name)
+	if !test.runHook("before_user", hooks.BeforeUser, hooks.Abort,
+		&Meta{Testcase: name, User: nbr, session: session}, settings) {
+		return
+	}
+	defer test.runHook("after_user", hooks.AfterUser, hooks.Abort,
+		&Meta{Testcase: name, User: nbr, session: session}, settings)
+	failures := 0
+	for j := 0; ; j++ {
+		runfor, pacing := test.liveConfig(name, runfor, pacing)
+		if time.Now().Sub(userStart) >= time.Duration(runfor*float64(time.Second)) {
+			break
+		}
+		// next iteration
+		start := time.Now()
+		meta := session.next(name, j)
+		if test.Status() == Stopping {
+			break
+		}
+		select {
+		case <-quit:
+			return // user retired
+		default:
+		}
+		ok := test.iteration(testcase, hooks, meta, settings)
+		if !test.failures(name, nbr, ok, &failures) || session.Stopped() ||
+			test.Status() == Stopping {
+			break
+		}
+		test.paceMaker(time.Duration(pacing*float64(time.Second)), time.Now().Sub(start))
+	}
+}
+
+// Record the measurement (see TestStatistics.Update). The active users are
+// captured with the measurement so the reporters (e.g. JtlReporter) do not
+// depend on when the collector processes it.
+func (test *TestScenario) Update(m Metric) {
+	if t, ok := m.(threadMetric); ok {
+		testcase := ""
+		if u, ok := m.(userMetric); ok {
+			testcase = u.GetTestcase()
+		}
+		grp, all := test.Threads(testcase)
+		t.setSampleThreads(threadCount{grp, all})
+	}
+	test.TestStatistics.Update(m)
+}
+
+// Count a user as active for the testcase.
+func (test *TestScenario) startThread(testcase string) {
+	test.threadsLock.Lock()
+	if test.threads == nil {
+		test.threads = make(map[string]int)
+	}
+	test.threads[testcase]++
+	test.threadsLock.Unlock()
+}
+
+// The user of the testcase finished.
+func (test *TestScenario) endThread(testcase string) {
+	test.threadsLock.Lock()
+	test.threads[testcase]--
+	test.threadsLock.Unlock()
+}
+
+// Give me the number of active users of the testcase and the number
+// of active users of all testcases.
+func (test *TestScenario) Threads(testcase string) (int, int) {
+	test.threadsLock.Lock()
+	defer test.threadsLock.Unlock()
+	all := 0
+	for _, n := range test.threads {
+		all += n
+	}
+	return test.threads[testcase], all
+}
+
+// Interval in which RunStages adjusts the number of users to the load profile.
+var stageInterval = 100 * time.Millisecond
+
+// Target number of users of the load profile at <elapsed> Seconds.
+func stagesTarget(stages []Stage, elapsed float64) int {
+	from := 0
+	for _, s := range stages {
+		if elapsed < s.Duration {
+			return from + int(float64(s.Users-from)*elapsed/s.Duration)
+		}
+		elapsed -= s.Duration
+		from = s.Users
+	}
+	return from // hold the target of the last stage
+}
+
+// RunStages executes a testcase following a load profile. Users are added and
+// retired so the number of users follows the stages. After the last stage its
+// target is kept until the end of the <runfor> period.
+// Settings are specified in Seconds!
+func (test *TestScenario) RunStages(name string, testcase func(*Meta, Settings),
+	delay float64, runfor float64, stages []Stage, pacing float64, settings Settings) {
+	test.wg.Add(1) // the "Scheduler" itself is a goroutine!
+	go func(test *TestScenario) {
+		defer test.wg.Done()
+		test.sleep(time.Duration(delay * float64(time.Second)))
+		userStart := time.Now()
+
+		users := []chan bool{} // quit channels of the active users
+		nbr := 0
+		for elapsed := time.Duration(0); ; elapsed = time.Now().Sub(userStart) {
+			// runfor and stages follow the changes of the loadmodel
+			runfor, _ := test.liveConfig(name, runfor, pacing)
+			if elapsed >= time.Duration(runfor*float64(time.Second)) || test.Status() == Stopping {
+				break
+			}
+			if _, ok := test.getTestcaseEntry(name); ok {
+				if s, err := test.GetTestcaseStages(name); err == nil && len(s) > 0 {
+					stages = s
+				}
+			}
+			target := stagesTarget(stages, elapsed.Seconds())
+			for len(users) < target {
+				// start user
+				quit := make(chan bool)
+				users = append(users, quit)
+				test.wg.Add(1)
+				go func(nbr int) {
+					defer test.wg.Done()
+					test.iterate(name, testcase, nbr, userStart, runfor, pacing, settings, quit)
+				}(nbr)
+				nbr++
+			}
+			for len(users) > target {
+				// retire the user that was started last
+				close(users[len(users)-1])
+				users = users[:len(users)-1]
+			}
+			test.sleep(stageInterval) // wakes up when the test is stopped
+		}
+	}(test)
+}
+
+// RunRate executes a testcase at a constant arrival rate (open model). Iterations
+// are started <rate> times per second by a pool of <users> workers. If all workers
+// are busy when an iteration is due the iteration is dropped. Dropped and late
+// iterations are reported as teststeps "<name>_dropped" and "<name>_late".
+// Settings are specified in Seconds!
+func (test *TestScenario) RunRate(name string, testcase func(*Meta, Settings),
+	delay float64, runfor float64, rate float64, users int, settings Settings) {
+	test.wg.Add(1) // the "Scheduler" itself is a goroutine!
+	go func(test *TestScenario) {
+		defer test.wg.Done()
+		test.sleep(time.Duration(delay * float64(time.Second)))
+
+		// start the worker pool (idle holds one token per idle worker)
+		iterations := make(chan int, users)
+		idle := make(chan bool, users)
+		test.wg.Add(users)
+		for i := 0; i < users; i++ {
+			idle <- true
+			go func(nbr int) {
+				defer test.wg.Done()
+				test.startThread(name)
+				defer test.endThread(name)
+				session := test.newSession(name, nbr)
+				hooks := test.testcaseHooks(name)
+				ok := test.runHook("before_user", hooks.BeforeUser, hooks.Abort,
+					&Meta{Testcase: name, User: nbr, session: session}, settings)
+				failures := 0
+				for j := range iterations {
+					if ok && (!test.failures(name, nbr,
+						test.iteration(testcase, hooks, session.next(name, j), settings), &failures) ||
+						session.Stopped()) {
+						break // the worker stops and does not take further iterations
 					}
-					test.paceMaker(time.Duration(pacing*float64(time.Second)), time.Now().Sub(start))
+					idle <- true
+				}
+				if ok {
+					test.runHook("after_user", hooks.AfterUser, hooks.Abort,
+						&Meta{Testcase: name, User: nbr, session: session}, settings)
 				}
 			}(i)
 		}
+
+		// feed the worker pool
+		interval := time.Duration(float64(time.Second) / rate)
+		if interval < 1 {
+			interval = 1 // rates above 1e9/s
+		}
+		duration := time.Duration(runfor * float64(time.Second))
+		start := time.Now()
+		for j := 0; time.Duration(j)*interval < duration; j++ {
+			due := start.Add(time.Duration(j) * interval)
+			if wait := due.Sub(time.Now()); wait > 0 {
+				test.sleep(wait)
+			}
+			if test.Status() == Stopping {
+				break
+			}
+			// the scheduler itself could not keep up
+			if late := time.Now().Sub(due); late > interval {
+				test.Update(&Meta{Testcase: name, Teststep: name + "_late",
+					Iteration: j, Timestamp: Timestamp(due), Elapsed: Elapsed(late)})
+			}
+			select {
+			case <-idle:
+				iterations <- j
+			default:
+				// no idle worker available
+				test.Update(&Meta{Testcase: name, Teststep: name + "_dropped",
+					Iteration: j, Timestamp: Timestamp(due),
+					Error: "worker pool exhausted"})
+			}
+		}
+		close(iterations) // stop the workers
 	}(test)
 }
 
 // Execute the scenario set in the loadmodel.json file.
 func (test *TestScenario) Exec() error {
+	return test.ExecContext(context.Background())
+}
+
+// Execute the scenario like Exec. The virtual users and their requests are
+// cancelled when the given context is done (e.g. timeout or signal).
+func (test *TestScenario) ExecContext(parent context.Context) error {
 	sel, _, _, _ := test.GetScenarioConfig()
 	// check that the scenario exists
 	if scenario, ok := test.testscenarios[sel]; ok {
+		thresholds, err := test.GetThresholds()
+		if err != nil {
+			return err
+		}
 		test.Reset()           // clear stats from previous run
+		test.SetPercentiles(test.GetPercentiles()...)
+		test.SetTimeseriesInterval(time.Duration(test.GetTimeseriesInterval() * float64(time.Second)))
+		start := time.Now()
+		test.startReporters(RunInfo{Scenario: sel, Start: start, Loadmodel: test.GetConfigMap()})
 		done := test.Collect() // start the collector
+		test.statusLock.Lock()
+		test.ctx, test.cancel = context.WithCancel(parent)
 		test.status = Running
-
-		fn := reflect.ValueOf(scenario)
-		fnType := fn.Type()
-		// some magic so we can call scenarios OR single testcases
-		if fnType.Kind() == reflect.Func && fnType.NumOut() == 0 {
-			if fnType.NumIn() == 0 {
-				// execute the selected scenario
-				fn.Call([]reflect.Value{})
-			}
-			if fnType.NumIn() == 2 {
-				// debugging of single testcase executions
-				meta := &Meta{}
-				settings := test.GetSettings()
-				fn.Call([]reflect.Value{reflect.ValueOf(meta),
-					reflect.ValueOf(settings)},
-				)
+		ctx := test.ctx
+		test.statusLock.Unlock()
+		go func() {
+			<-ctx.Done()
+			if parent.Err() != nil {
+				test.Stop() // the caller cancelled the run
 			}
-			if fnType.NumIn() != 0 && fnType.NumIn() != 2 {
-				return fmt.Errorf("expected a function with zero or two parameters to implement %s", sel)
+		}()
+		test.setHookError(nil)
+		go test.watchThresholds(thresholds)
+		// stop the run if it can not be started
+		abort := func(err error) error {
+			test.Wait()
+			<-done
+			test.endReporters()
+			return err
+		}
+
+		if err := test.runScenarioHooks(sel, "before_scenario"); err != nil {
+			return abort(err)
+		}
+		if test.cluster != nil {
+			// distributed mode: the agents execute the scenario
+			if err := test.startAgents(); err != nil {
+				return abort(err)
 			}
-		} else {
-			return fmt.Errorf("expected a function without return value to implement %s", sel)
+		} else if err := test.call(scenario, sel); err != nil {
+			return abort(err)
 		}
 		// wait for testcases to finish
 		// note: keep this in the foreground - do not put any of this into a goroutine!
+		test.wg.Wait()
+		if err := test.runScenarioHooks(sel, "after_scenario"); err != nil {
+			test.setHookError(err)
+		}
 		test.Wait()
 		<-done // wait for collector to finish
+
+		// check the results against the thresholds
+		results := test.checkThresholds(thresholds, time.Now().Sub(start), 0)
+		test.setThresholdResults(results)
+		test.endReporters()
+		if err := test.hookError(); err != nil {
+			return err
+		}
+		return thresholdError(results)
+	}
+	return fmt.Errorf("scenario %s does not exist", sel)
+}
+
+// Call the scenario function (or single testcase).
+func (test *TestScenario) call(scenario interface{}, sel string) error {
+	fn := reflect.ValueOf(scenario)
+	fnType := fn.Type()
+	// some magic so we can call scenarios OR single testcases
+	if fnType.Kind() == reflect.Func && fnType.NumOut() == 0 {
+		if fnType.NumIn() == 0 {
+			// execute the selected scenario
+			fn.Call([]reflect.Value{})
+		}
+		if fnType.NumIn() == 2 {
+			// debugging of single testcase executions
+			meta := &Meta{}
+			settings := test.GetSettings()
+			fn.Call([]reflect.Value{reflect.ValueOf(meta),
+				reflect.ValueOf(settings)},
+			)
+		}
+		if fnType.NumIn() != 0 && fnType.NumIn() != 2 {
+			return fmt.Errorf("expected a function with zero or two parameters to implement %s", sel)
+		}
 	} else {
-		return fmt.Errorf("scenario %s does not exist", sel)
+		return fmt.Errorf("expected a function without return value to implement %s", sel)
 	}
 	return nil
 }
@@ -320,30 +791,49 @@ func (test *TestScenario) Exec() error {
 // Thinktime takes ThinkTimeFactor and ThinkTimeVariance into account.
 // tt is given in Seconds. So for example 3.0 equates to 3 seconds; 0.3 to 300ms.
 func (test *TestScenario) Thinktime(tt float64) {
-	if test.status == Running {
+	if test.Status() == Running {
 		_, ttf, ttv, _ := test.GetScenarioConfig()
 		r := (rand.Float64() * 2.0) - 1.0 // r in [-1.0 - 1.0)
 		v := float64(tt) * ttf * ((r * ttv) + 1.0) * float64(time.Second)
-		time.Sleep(time.Duration(v))
+		test.sleep(time.Duration(v))
 	}
 }
 
 // Read the Status of the test: Running, Stopping, Stopped
 func (test *TestScenario) Status() Status {
+	test.statusLock.RLock()
+	defer test.statusLock.RUnlock()
+	if test.status == Running && test.ctx.Err() != nil {
+		return Stopping // the context of the run is cancelled by the caller
+	}
 	return test.status
 }
 
-// Initiate scenario stopping.
+// Context of the current test run. It is cancelled when the test is stopped.
+func (test *TestScenario) Context() context.Context {
+	test.statusLock.RLock()
+	defer test.statusLock.RUnlock()
+	return test.ctx
+}
+
+// Initiate scenario stopping. Thinktimes and requests of the virtual users
+// are cancelled.
 func (test *TestScenario) Stop() {
-	if test.Status() != Stopped {
+	test.statusLock.Lock()
+	defer test.statusLock.Unlock()
+	if test.status != Stopped {
 		test.status = Stopping
+		test.cancel()
 	}
 }
 
 // Careful this is an internal exposed to ease testing.
 // you need to also pull from the Collectors done channel!
 func (test *TestScenario) Wait() {
-	test.wg.Wait()           // wait till end
+	test.wg.Wait() // wait till end
+	test.statusLock.Lock()
 	close(test.measurements) // need to close the channel so that collect can exit, too
 	test.status = Stopped
+	test.cancel() // release the context of the run
+	test.statusLock.Unlock()
 }
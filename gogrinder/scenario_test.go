@@ -1,8 +1,11 @@
 package gogrinder
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	time "github.com/finklabs/ttime"
@@ -405,6 +408,157 @@ func TestRunSequential(t *testing.T) {
 	}
 }
 
+func TestRunRate(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	done := fake.Collect() // this needs a collector to unblock update
+	fake.status = Running
+
+	var counter int64
+	tc1 := func(meta *Meta, s Settings) {
+		atomic.AddInt64(&counter, 1)
+	}
+
+	// 100 iterations per second for 200ms
+	fake.RunRate("01_testcase", tc1, 0, 0.2, 100, 2, fake.GetSettings())
+	fake.Wait()
+	<-done
+
+	if c := atomic.LoadInt64(&counter); c < 15 || c > 20 {
+		t.Errorf("Testcase iteration counter %d not as expected!", c)
+	}
+	if _, ok := fake.stats["01_testcase_dropped"]; ok {
+		t.Errorf("Expected no dropped iterations!")
+	}
+}
+
+func TestRunRateAboveOneIterationPerNanosecond(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	done := fake.Collect() // this needs a collector to unblock update
+	fake.status = Running
+
+	finished := make(chan bool)
+	go func() {
+		// 1e10 iterations per second for 1µs
+		fake.RunRate("01_testcase", func(meta *Meta, s Settings) {}, 0, 0.000001, 1e10, 1,
+			fake.GetSettings())
+		fake.Wait()
+		<-done
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("RunRate did not finish!")
+	}
+}
+
+func TestRunRateDropsIterations(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	done := fake.Collect() // this needs a collector to unblock update
+	fake.status = Running
+
+	// a single worker can not keep up with the arrival rate
+	tc1 := func(meta *Meta, s Settings) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	fake.RunRate("01_testcase", tc1, 0, 0.2, 100, 1, fake.GetSettings())
+	fake.Wait()
+	<-done
+
+	if v, ok := fake.stats["01_testcase_dropped"]; ok {
+		if v.count < 10 || v.count != v.error {
+			t.Errorf("Dropped iterations %d (errors %d) not as expected!", v.count, v.error)
+		}
+	} else {
+		t.Errorf("Expected dropped iterations to be reported!")
+	}
+}
+
+func TestStagesTarget(t *testing.T) {
+	// ramp-up, plateau, spike, ramp-down
+	stages := []Stage{{10, 10}, {10, 10}, {0, 30}, {5, 30}, {20, 0}}
+
+	for _, c := range []struct {
+		elapsed float64
+		users   int
+	}{{0, 0}, {5, 5}, {10, 10}, {15, 10}, {20, 30}, {24.9, 30},
+		{25, 30}, {35, 15}, {45, 0}, {100, 0}} {
+		if users := stagesTarget(stages, c.elapsed); users != c.users {
+			t.Errorf("Target at %fs expected %d users but was %d!", c.elapsed, c.users, users)
+		}
+	}
+
+	// without stages there are no users
+	if users := stagesTarget([]Stage{}, 10); users != 0 {
+		t.Errorf("Target without stages expected 0 users but was %d!", users)
+	}
+}
+
+func TestRunStages(t *testing.T) {
+	bak := stageInterval
+	stageInterval = 5 * time.Millisecond
+	defer func() { stageInterval = bak }()
+
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.status = Running
+
+	var lock sync.Mutex
+	seen := make(map[int]time.Time) // last iteration of each user
+	tc1 := func(meta *Meta, s Settings) {
+		lock.Lock()
+		seen[meta.User] = time.Now()
+		lock.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// jump to 3 users, retire them after 100ms
+	start := time.Now()
+	stages := []Stage{{0, 3}, {0.1, 3}, {0, 0}}
+	fake.RunStages("01_testcase", tc1, 0, 0.2, stages, 0, fake.GetSettings())
+	fake.wg.Wait()
+
+	if len(seen) != 3 {
+		t.Errorf("Expected 3 users but got %d!", len(seen))
+	}
+	for user, last := range seen {
+		if last.Sub(start) > 150*time.Millisecond {
+			t.Errorf("User %d was not retired: %v!", user, last.Sub(start))
+		}
+	}
+}
+
+func TestRunStagesStop(t *testing.T) {
+	bak := stageInterval
+	stageInterval = time.Hour
+	defer func() { stageInterval = bak }()
+
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.status = Running
+
+	stages := []Stage{{0, 1}, {10, 1}}
+	fake.RunStages("01_testcase", func(meta *Meta, s Settings) {}, 0, 10, stages, 0.01,
+		fake.GetSettings())
+	time.Sleep(20 * time.Millisecond)
+	fake.Stop()
+
+	done := make(chan bool)
+	go func() {
+		fake.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the stages to stop without waiting for the stage interval!")
+	}
+}
+
 func TestScheduleErrorUnknownTestcase(t *testing.T) {
 	fake := NewTest()
 	err := fake.Schedule("unknown_testcase", func(*Meta, Settings) {})
@@ -441,6 +595,13 @@ func TestExecErrorFunctionWithReturnValue(t *testing.T) {
 	if e != "expected a function without return value to implement 01_testcase" {
 		t.Errorf("Error msg for function with return value not as expected: %s", e)
 	}
+	// the run is cleaned up
+	if fake.Status() != Stopped {
+		t.Errorf("Test status expected Stopped, but was: %d", fake.Status())
+	}
+	if fake.Context().Err() == nil {
+		t.Errorf("Context of the run expected to be cancelled!")
+	}
 }
 
 func TestExecErrorFunctionWithOneParams(t *testing.T) {
@@ -492,6 +653,101 @@ func TestGetScenarioStatus(t *testing.T) {
 	}
 }
 
+func TestStopCancelsContext(t *testing.T) {
+	var fake = NewTest()
+	fake.status = Running
+	ctx := fake.Context()
+	fake.Stop()
+
+	select {
+	case <-ctx.Done():
+	default:
+		t.Errorf("Context of the test run expected to be cancelled by Stop!")
+	}
+}
+
+func TestThinktimeCancelledByStop(t *testing.T) {
+	var fake = NewTest()
+	fake.status = Running
+	fake.config["Scenario"] = "scenario1"
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		fake.Stop()
+	}()
+	start := time.Now()
+	fake.Thinktime(10.0)
+	if d := time.Now().Sub(start); d > time.Second {
+		t.Errorf("Thinktime did not stop! It sleept: %v\n", d)
+	}
+}
+
+func TestPaceMakerCancelledByStop(t *testing.T) {
+	var fake = NewTest()
+	fake.status = Running
+	fake.config["Scenario"] = "scenario1"
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		fake.Stop()
+	}()
+	start := time.Now()
+	fake.paceMaker(10*time.Second, 0)
+	if d := time.Now().Sub(start); d > time.Second {
+		t.Errorf("PaceMaker did not stop! It sleept: %v\n", d)
+	}
+}
+
+func TestExecContextTimeout(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.config["Loadmodel"] = []interface{}{
+		map[string]interface{}{"Testcase": "01_tc", "Runfor": 100.0, "Users": 2.0, "Pacing": 0.0}}
+
+	var iterations int32
+	fake.Testscenario("scenario1", func() {
+		fake.Schedule("01_tc", func(meta *Meta, s Settings) {
+			atomic.AddInt32(&iterations, 1)
+			if meta.Context() != fake.Context() {
+				t.Errorf("Iteration expected to run in the context of the test run!")
+			}
+			fake.Thinktime(10.0)
+		})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := fake.ExecContext(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d := time.Now().Sub(start); d > 5*time.Second {
+		t.Errorf("Test run did not stop on timeout! It took: %v", d)
+	}
+	if fake.Status() != Stopped {
+		t.Errorf("Test scenario status expected Stopped, but was: %d", fake.Status())
+	}
+	if atomic.LoadInt32(&iterations) != 2 {
+		t.Errorf("Expected one iteration per user, but got %d", iterations)
+	}
+}
+
+func TestBracketEndCancelled(t *testing.T) {
+	var fake = NewTest()
+	done := fake.Collect()
+	b := fake.NewBracket("sth")
+	b.End(&Meta{Testcase: "01_tc", Cancelled: true})
+	close(fake.measurements)
+	<-done
+
+	if _, ok := fake.stats["sth_cancelled"]; !ok {
+		t.Errorf("Cancelled teststep expected to be reported as sth_cancelled!")
+	}
+	if _, ok := fake.stats["sth"]; ok {
+		t.Errorf("Cancelled teststep not expected to be reported as sth!")
+	}
+}
+
 func TestTimestampMarshalJSON(t *testing.T) {
 	tt := time.Now()
 	ts := Timestamp(tt)
@@ -519,3 +775,20 @@ func TestElapsedMarshalJSON(t *testing.T) {
 // TODO: TestRampupWorksForMultipleUsers
 
 // TODO: add test for Meta getter and setter...
+
+func TestThreads(t *testing.T) {
+	fake := NewTest()
+	fake.startThread("01_tc")
+	fake.startThread("01_tc")
+	fake.startThread("02_tc")
+	fake.endThread("01_tc")
+
+	grp, all := fake.Threads("01_tc")
+	if grp != 1 || all != 2 {
+		t.Errorf("Threads %d, %d not as expected!", grp, all)
+	}
+	grp, all = fake.Threads("03_tc")
+	if grp != 0 || all != 2 {
+		t.Errorf("Threads %d, %d not as expected!", grp, all)
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/finklabs/graceful"
 	time "github.com/finklabs/ttime"
 	"github.com/gorilla/mux"
-	"github.com/prometheus/client_golang/prometheus"
 	"io"
 )
 
@@ -123,6 +122,23 @@ func (srv *TestServer) getCsv(r *http.Request) (interface{}, *handlerError) {
 	return csv, e
 }
 
+func (srv *TestServer) getTimeseries(r *http.Request) (interface{}, *handlerError) {
+	since := r.URL.Query().Get("since")
+	res := make(map[string]interface{})
+	res["results"] = srv.test.Timeseries(since)
+	res["running"] = srv.test.Status() != Stopped // could be stopping or running
+	return res, nil
+}
+
+func (srv *TestServer) getTimeseriesCsv(r *http.Request) (interface{}, *handlerError) {
+	var e *handlerError
+	csv, err := srv.test.TimeseriesCsv()
+	if err != nil {
+		e = &handlerError{err, "error encoding csv:", 500}
+	}
+	return csv, e
+}
+
 func (srv *TestServer) startTest(r *http.Request) (interface{}, *handlerError) {
 	if srv.test.Status() == Stopped {
 		srv.test.Exec()
@@ -163,6 +179,103 @@ func (srv *TestServer) getConfig(r *http.Request) (interface{}, *handlerError) {
 	return res, nil
 }
 
+// register an agent with the controller.
+func (srv *TestServer) registerAgent(r *http.Request) (interface{}, *handlerError) {
+	var body struct {
+		Host string `json:"host"`
+	}
+	json.NewDecoder(r.Body).Decode(&body) // the host is optional
+	id, err := srv.test.RegisterAgent(body.Host)
+	if err != nil {
+		return nil, &handlerError{err, err.Error(), 404}
+	}
+	return map[string]string{"id": id}, nil
+}
+
+// list the registered agents.
+func (srv *TestServer) getAgents(r *http.Request) (interface{}, *handlerError) {
+	res := make(map[string]interface{})
+	res["agents"] = srv.test.Agents()
+	return res, nil
+}
+
+// next command for the agent.
+func (srv *TestServer) getAgentCommand(r *http.Request) (interface{}, *handlerError) {
+	cmd, err := srv.test.AgentCommand(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, &handlerError{err, err.Error(), 404}
+	}
+	return cmd, nil
+}
+
+// measurements of the agent.
+func (srv *TestServer) postAgentMeasurements(r *http.Request) (interface{}, *handlerError) {
+	measurements := []Meta{}
+	if err := json.NewDecoder(r.Body).Decode(&measurements); err != nil {
+		return nil, &handlerError{err, "error while parsing the measurements", 400}
+	}
+	if err := srv.test.AgentMeasurements(mux.Vars(r)["id"], measurements); err != nil {
+		return nil, &handlerError{err, err.Error(), 409}
+	}
+	return make(map[string]string), nil
+}
+
+// the agent finished the run.
+func (srv *TestServer) agentDone(r *http.Request) (interface{}, *handlerError) {
+	if err := srv.test.AgentDone(mux.Vars(r)["id"]); err != nil {
+		return nil, &handlerError{err, err.Error(), 404}
+	}
+	return make(map[string]string), nil
+}
+
+// data pool of the request. The pool server does not create pools so agents do
+// not end up with a pool that lacks the options of the controller.
+func (srv *TestServer) pool(r *http.Request) (DataPool, *handlerError) {
+	name := mux.Vars(r)["name"]
+	if !srv.test.HasPool(name) {
+		return nil, &handlerError{ErrPoolNotFound, ErrPoolNotFound.Error(), 404}
+	}
+	return srv.test.Pool(name), nil
+}
+
+// number of values in the data pool.
+func (srv *TestServer) getPool(r *http.Request) (interface{}, *handlerError) {
+	p, e := srv.pool(r)
+	if e != nil {
+		return nil, e
+	}
+	return map[string]int{"len": p.Len()}, nil
+}
+
+// put a value into the data pool.
+func (srv *TestServer) putPool(r *http.Request) (interface{}, *handlerError) {
+	var body struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, &handlerError{err, "error while parsing the value", 400}
+	}
+	p, e := srv.pool(r)
+	if e != nil {
+		return nil, e
+	}
+	if err := p.Put(body.Value); err != nil {
+		return nil, &handlerError{err, err.Error(), 409}
+	}
+	return make(map[string]string), nil
+}
+
+// take a value from the data pool (an empty pool is not an error so the
+// polling agents do not flood the log).
+func (srv *TestServer) takePool(r *http.Request) (interface{}, *handlerError) {
+	p, e := srv.pool(r)
+	if e != nil {
+		return nil, e
+	}
+	v, err := p.Take()
+	return map[string]interface{}{"value": v, "empty": err == ErrPoolEmpty}, nil
+}
+
 // Stop the web server.
 func (srv *TestServer) stopWebserver(r *http.Request) (interface{}, *handlerError) {
 	// e.g. curl -X "DELETE" http://localhost:3030/stop
@@ -189,28 +302,21 @@ func (srv *TestServer) Router() *mux.Router {
 	// REST routes
 	router.Handle("/statistics", handler(srv.getStatistics)).Methods("GET")
 	router.Handle("/csv", csvHandler(srv.getCsv)).Methods("GET")
+	router.Handle("/timeseries", handler(srv.getTimeseries)).Methods("GET")
+	router.Handle("/timeseries/csv", csvHandler(srv.getTimeseriesCsv)).Methods("GET")
 	router.Handle("/config", handler(srv.getConfig)).Methods("GET")
 	router.Handle("/config", handler(srv.updateConfig)).Methods("PUT")
 	router.Handle("/test", handler(srv.startTest)).Methods("POST")
 	router.Handle("/test", handler(srv.stopTest)).Methods("DELETE")
 	router.Handle("/stop", handler(srv.stopWebserver)).Methods("DELETE")
+	router.Handle("/agents", handler(srv.getAgents)).Methods("GET")
+	router.Handle("/agents", handler(srv.registerAgent)).Methods("POST")
+	router.Handle("/agents/{id}/command", handler(srv.getAgentCommand)).Methods("GET")
+	router.Handle("/agents/{id}/measurements", handler(srv.postAgentMeasurements)).Methods("POST")
+	router.Handle("/agents/{id}/done", handler(srv.agentDone)).Methods("POST")
+	router.Handle("/pools/{name}", handler(srv.getPool)).Methods("GET")
+	router.Handle("/pools/{name}", handler(srv.putPool)).Methods("POST")
+	router.Handle("/pools/{name}/take", handler(srv.takePool)).Methods("POST")
 
 	return router
 }
-
-// Assemble the Server for the Prometheus reporter
-func NewPrometheusReporterServer() *graceful.Server {
-	//handler := prometheus.Handler()
-	// how to disable default go_collector metrics?
-	handler := prometheus.UninstrumentedHandler()
-	// register the /metrics route
-	http.Handle("/metrics", handler)
-
-	srv := &graceful.Server{
-		Timeout: 5 * time.Second,
-		Server: &http.Server{
-			Handler: handler,
-		},
-	}
-	return srv
-}
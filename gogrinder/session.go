@@ -0,0 +1,105 @@
+package gogrinder
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"net/http/cookiejar"
+
+	time "github.com/finklabs/ttime"
+)
+
+// Session keeps the state of a virtual user. The scheduler creates a session
+// when the user starts and hands it to every iteration of that user:
+//
+//	func tc1(m *gogrinder.Meta, s gogrinder.Settings) {
+//	    session := m.Session()
+//	    _, _, mm = req.DoRaw(session.Client, r, m)
+//	    ...
+//
+// The session is used by the goroutine of its user only so it is not
+// synchronized.
+type Session struct {
+	Testcase   string
+	User       int
+	Client     *http.Client           // http client with cookiejar (keeps cookies and keep-alive connections)
+	Vars       map[string]interface{} // variables of the user (also see req.UserVars)
+	Rand       *rand.Rand             // random source of the user
+	Iterations int                    // iterations the user started
+	Start      time.Time              // start of the user
+	ctx        context.Context        // context of the test run
+	stopTest   func()                 // stop the test run
+	stopped    bool                   // the user stops after the current iteration
+}
+
+// Create the session of a virtual user.
+func NewSession(testcase string, user int) *Session {
+	jar, _ := cookiejar.New(nil)
+	return &Session{
+		Testcase: testcase,
+		User:     user,
+		Client:   &http.Client{Jar: jar},
+		Vars:     make(map[string]interface{}),
+		Rand:     rand.New(rand.NewSource(time.Now().UnixNano() + int64(user))),
+		Start:    time.Now(),
+	}
+}
+
+// Create the session of a virtual user of the test run.
+func (test *TestScenario) newSession(testcase string, user int) *Session {
+	s := NewSession(testcase, user)
+	s.ctx = test.Context()
+	s.stopTest = test.Stop
+	return s
+}
+
+// Stop the user after the current iteration (e.g. the test data of the user
+// is used up).
+func (s *Session) Stop() {
+	s.stopped = true
+}
+
+// Check if the user is stopped.
+func (s *Session) Stopped() bool {
+	return s.stopped
+}
+
+// Stop the test run the user belongs to.
+func (s *Session) StopTest() {
+	if s.stopTest != nil {
+		s.stopTest()
+	}
+}
+
+// Context of the test run the user belongs to. It is cancelled when the test
+// is stopped.
+func (s *Session) Context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// Start the next iteration of the user.
+func (s *Session) next(testcase string, iteration int) *Meta {
+	s.Iterations++
+	return &Meta{Testcase: testcase, Iteration: iteration, User: s.User, session: s}
+}
+
+// Session of the virtual user that executes the iteration. Outside of the
+// scheduler (e.g. calling a testcase directly) a new session is created.
+func (m *Meta) Session() *Session {
+	if m.session == nil {
+		m.session = NewSession(m.Testcase, m.User)
+	}
+	return m.session
+}
+
+// Context of the test run (see Session.Context). Requests use it so they are
+// cancelled when the test is stopped.
+func (m *Meta) Context() context.Context {
+	if m.session == nil {
+		return context.Background()
+	}
+	return m.session.Context()
+}
@@ -0,0 +1,146 @@
+package gogrinder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestNewSession(t *testing.T) {
+	s := NewSession("01_tc", 3)
+	if s.Testcase != "01_tc" || s.User != 3 || s.Iterations != 0 {
+		t.Errorf("Session not as expected: %v", s)
+	}
+	if s.Client == nil || s.Client.Jar == nil || s.Vars == nil || s.Rand == nil {
+		t.Errorf("Session is expected to have a client with cookiejar, vars and rand!")
+	}
+}
+
+func TestMetaSessionOutsideOfScheduler(t *testing.T) {
+	m := &Meta{Testcase: "01_tc", User: 2}
+	s := m.Session()
+	if s == nil || s.Testcase != "01_tc" || s.User != 2 {
+		t.Fatalf("Session not as expected: %v", s)
+	}
+	if m.Session() != s {
+		t.Errorf("Session of the meta is expected to stay the same!")
+	}
+}
+
+func TestSessionPersistsAcrossIterations(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.status = Running
+
+	var lock sync.Mutex
+	sessions := make(map[int]*Session)
+	tc1 := func(meta *Meta, s Settings) {
+		session := meta.Session()
+		n, _ := session.Vars["n"].(int)
+		session.Vars["n"] = n + 1
+		lock.Lock()
+		if prev, ok := sessions[meta.User]; ok && prev != session {
+			t.Errorf("User %d got a new session in iteration %d!", meta.User, meta.Iteration)
+		}
+		sessions[meta.User] = session
+		lock.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	fake.Run("01_testcase", tc1, 0, 0.05, 0, 2, 0, fake.GetSettings())
+	fake.wg.Wait()
+
+	if len(sessions) != 2 || sessions[0] == sessions[1] {
+		t.Fatalf("Expected a session per user: %v", sessions)
+	}
+	for user, s := range sessions {
+		if s.User != user || s.Iterations < 2 || s.Vars["n"] != s.Iterations {
+			t.Errorf("Session of user %d not as expected: %d iterations, vars %v",
+				user, s.Iterations, s.Vars)
+		}
+	}
+}
+
+func TestSessionRunRateWorkers(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	done := fake.Collect() // this needs a collector to unblock update
+	fake.status = Running
+
+	var lock sync.Mutex
+	sessions := make(map[*Session]bool)
+	tc1 := func(meta *Meta, s Settings) {
+		lock.Lock()
+		sessions[meta.Session()] = true
+		lock.Unlock()
+	}
+
+	fake.RunRate("01_testcase", tc1, 0, 0.1, 100, 2, fake.GetSettings())
+	fake.Wait()
+	<-done
+
+	if len(sessions) < 1 || len(sessions) > 2 {
+		t.Errorf("Expected a session per worker but got %d!", len(sessions))
+	}
+}
+
+func TestSessionKeepsCookies(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err != nil {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t"})
+			w.Write([]byte("login"))
+			return
+		}
+		w.Write([]byte("welcome back"))
+	}))
+	defer ts.Close()
+
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.status = Running
+	var bodies []string
+	tc1 := func(meta *Meta, s Settings) {
+		resp, err := meta.Session().Client.Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b := make([]byte, 20)
+		n, _ := resp.Body.Read(b)
+		bodies = append(bodies, string(b[:n]))
+	}
+	fake.DoIterations(tc1, 2, 0, false)
+	fake.wg.Wait()
+
+	if len(bodies) != 2 || bodies[0] != "login" || bodies[1] != "welcome back" {
+		t.Errorf("Cookies are expected to persist across iterations: %v", bodies)
+	}
+}
+
+func TestSessionStopUser(t *testing.T) {
+	fake := NewTest()
+	fake.config["Scenario"] = "scenario1"
+	fake.status = Running
+
+	var lock sync.Mutex
+	iterations := make(map[int]int)
+	tc1 := func(meta *Meta, s Settings) {
+		lock.Lock()
+		iterations[meta.User]++
+		lock.Unlock()
+		if meta.User == 0 && meta.Iteration == 1 {
+			meta.Session().Stop() // user 0 runs out of data
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	fake.Run("01_testcase", tc1, 0, 0.05, 0, 2, 0, fake.GetSettings())
+	fake.wg.Wait()
+
+	if iterations[0] != 2 || iterations[1] < 3 {
+		t.Errorf("Expected user 0 to stop after 2 iterations: %v", iterations)
+	}
+}
@@ -19,7 +19,17 @@ type Statistics interface {
 	Report(io.Writer)
 	SetReportPlugins(reporters ...Reporter)
 	AddReportPlugin(reporter Reporter)
+	ReportPlugins() []Reporter
+	CloseReportPlugins() error
+	SetPercentiles(percentiles ...float64)
 	Csv() (string, error)
+	Timeseries(since string) []TimeseriesResult
+	SetTimeseriesInterval(interval time.Duration)
+	TimeseriesCsv() (string, error)
+	Thresholds() []ThresholdResult
+	JUnit(suite string) (string, error)
+	Errors() []ErrorResult
+	Runtime() (time.Time, time.Time)
 }
 
 // Every type implements the Metric type since it is so simple.
@@ -35,10 +45,18 @@ type Metric interface {
 }
 
 type TestStatistics struct {
-	lock         sync.RWMutex           // lock that is used on stats
-	stats        map[string]stats_value // collect and aggregate results
-	measurements chan Metric
-	reporters    []Reporter
+	lock             sync.RWMutex           // lock that is used on stats
+	stats            map[string]stats_value // collect and aggregate results
+	measurements     chan Metric
+	reporters        []Reporter
+	percentiles      []float64                         // percentiles contained in the results
+	interval         time.Duration                     // interval of the timeseries buckets
+	timeseries       map[time.Time]map[string]ts_value // aggregate results per interval
+	thresholdResults []ThresholdResult                 // outcome of the threshold check
+	errors           map[string]map[string]int64       // error count per teststep and message
+	first            time.Time                         // start of the first measurement
+	end              time.Time                         // end of the last measurement
+	flushInterval    time.Duration                     // interval of the collector to flush the reporters
 }
 
 // Internal datastructure to collect and aggregate measurements.
@@ -49,6 +67,9 @@ type stats_value struct {
 	count int64
 	error int64
 	last  time.Time
+	hist  *histogram
+	// recorded by gogrinder itself (e.g. hooks), excluded from global thresholds
+	internal bool
 }
 
 // []Result is what is what you get from test.Results().
@@ -61,6 +82,8 @@ type Result struct {
 	Count    int64   `json:"count"`
 	Error    int64   `json:"error"`
 	Last     string  `json:"last"`
+	// percentiles [ms] by name e.g. "p90"
+	Percentiles map[string]float64 `json:"percentiles_ms,omitempty"`
 }
 
 // Simple approach to sorting of the results.
@@ -84,26 +107,106 @@ func (test *TestStatistics) AddReportPlugin(reporter Reporter) {
 	test.reporters = append(test.reporters, reporter)
 }
 
+func (test *TestStatistics) ReportPlugins() []Reporter {
+	return test.reporters
+}
+
+// Select the percentiles (e.g. 90, 95, 99) that are contained in the results.
+func (test *TestStatistics) SetPercentiles(percentiles ...float64) {
+	test.lock.Lock()
+	test.percentiles = percentiles
+	test.lock.Unlock()
+}
+
 // Collect all measurements. It blocks until measurements channel is closed.
+// The reporters are flushed every flush interval and when the collector finishes.
 func (test *TestStatistics) Collect() <-chan bool {
 	done := make(chan bool)
-	go func(test *TestStatistics) {
-		for metric := range test.measurements {
-			// call the default reporter
-			test.default_reporter(metric)
-			// call the plugged in reporters
-			for _, reporter := range test.reporters {
-				reporter.Update(metric)
+	test.lock.RLock()
+	interval := test.flushInterval
+	test.lock.RUnlock()
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	go func(test *TestStatistics, measurements <-chan Metric) {
+		flush := time.NewTicker(interval)
+		defer flush.Stop()
+		for {
+			select {
+			case metric, ok := <-measurements:
+				if !ok {
+					test.flushReporters()
+					done <- true
+					return
+				}
+				// call the default reporter
+				test.default_reporter(metric)
+				// call the plugged in reporters (events only go to the event-log)
+				event := isEvent(metric)
+				for _, reporter := range test.reporters {
+					if event && !logsEvents(reporter) {
+						continue
+					}
+					reporter.Update(metric)
+				}
+			case <-flush.C:
+				test.flushReporters()
 			}
 		}
-		done <- true
-	}(test)
+	}(test, test.measurements)
 	return done
 }
 
+// Metrics that record an event (e.g. a config change) instead of a measurement.
+type eventMetric interface {
+	GetEvent() string
+}
+
+// Is the metric an event instead of a measurement?
+func isEvent(m Metric) bool {
+	e, ok := m.(eventMetric)
+	return ok && e.GetEvent() != ""
+}
+
+// Reporters that receive the events: the event-log and the agent that forwards
+// them to the event-log of the controller.
+func logsEvents(r Reporter) bool {
+	switch r.(type) {
+	case *EventReporter, *Agent:
+		return true
+	}
+	return false
+}
+
+// Teststeps that gogrinder records itself as "<testcase>_<suffix>".
+var internalSteps = []string{"late", "dropped", "panic", "before_user", "after_user",
+	"before_iteration", "after_iteration", "before_scenario", "after_scenario"}
+
+// Is the measurement one of the teststeps that gogrinder records itself?
+func internalStep(m Metric) bool {
+	u, ok := m.(userMetric)
+	if !ok {
+		return false
+	}
+	for _, s := range internalSteps {
+		if m.GetTeststep() == u.GetTestcase()+"_"+s {
+			return true
+		}
+	}
+	return false
+}
+
 // function to process the incoming measurements and update the stats
 // this is also the default-reporter. All other reporters are in reporter.go
+// Events are not part of the statistics, they are markers in the timeseries.
 func (test *TestStatistics) default_reporter(m Metric) {
+	if isEvent(m) {
+		test.lock.Lock()
+		test.timeseriesEvent(m.GetTeststep(), time.Time(m.GetTimestamp()),
+			m.(eventMetric).GetEvent())
+		test.lock.Unlock()
+		return
+	}
 	teststep := m.GetTeststep()
 	elapsed := time.Duration(m.GetElapsed())
 	timestamp := time.Time(m.GetTimestamp())
@@ -111,9 +214,25 @@ func (test *TestStatistics) default_reporter(m Metric) {
 	if len(m.GetError()) > 0 {
 		err_count = 1
 	}
-	test.lock.RLock()
+	test.lock.Lock()
+	defer test.lock.Unlock()
+	test.updateTimeseries(teststep, timestamp, elapsed, err_count)
+	if test.first.IsZero() || timestamp.Before(test.first) {
+		test.first = timestamp
+	}
+	if timestamp.Add(elapsed).After(test.end) {
+		test.end = timestamp.Add(elapsed)
+	}
+	if err_count > 0 {
+		if test.errors == nil {
+			test.errors = make(map[string]map[string]int64)
+		}
+		if test.errors[teststep] == nil {
+			test.errors[teststep] = make(map[string]int64)
+		}
+		test.errors[teststep][m.GetError()]++
+	}
 	val, exists := test.stats[teststep]
-	test.lock.RUnlock()
 	if exists {
 		val.avg = (time.Duration(val.count)*val.avg +
 			elapsed) / time.Duration(val.count+1)
@@ -126,14 +245,14 @@ func (test *TestStatistics) default_reporter(m Metric) {
 		val.last = timestamp
 		val.count++
 		val.error += err_count
-		test.lock.Lock()
+		val.hist.record(elapsed)
 		test.stats[teststep] = val
-		test.lock.Unlock()
 	} else {
 		// create a new statistic for t
-		test.lock.Lock()
-		test.stats[teststep] = stats_value{elapsed, elapsed, elapsed, 1, err_count, timestamp}
-		test.lock.Unlock()
+		hist := newHistogram()
+		hist.record(elapsed)
+		test.stats[teststep] = stats_value{elapsed, elapsed, elapsed, 1, err_count, timestamp, hist,
+			internalStep(m)}
 	}
 }
 
@@ -141,10 +260,59 @@ func (test *TestStatistics) default_reporter(m Metric) {
 func (test *TestStatistics) Reset() {
 	test.lock.Lock()
 	test.stats = make(map[string]stats_value)
+	test.timeseries = make(map[time.Time]map[string]ts_value)
+	test.thresholdResults = nil
+	test.errors = make(map[string]map[string]int64)
+	test.first, test.end = time.Time{}, time.Time{}
 	test.lock.Unlock()
 	test.measurements = make(chan Metric)
 }
 
+// []ErrorResult is what you get from test.Errors().
+type ErrorResult struct {
+	Teststep string `json:"teststep"`
+	Error    string `json:"error"`
+	Count    int64  `json:"count"`
+}
+
+// byErrorCount implements sort.Interface for []ErrorResult based on the
+// Teststep and Count fields.
+type byErrorCount []ErrorResult
+
+func (a byErrorCount) Len() int      { return len(a) }
+func (a byErrorCount) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byErrorCount) Less(i, j int) bool {
+	if a[i].Teststep != a[j].Teststep {
+		return a[i].Teststep < a[j].Teststep
+	}
+	if a[i].Count != a[j].Count {
+		return a[i].Count > a[j].Count
+	}
+	return a[i].Error < a[j].Error
+}
+
+// Give me the number of occurrences of every error message per teststep.
+// The errors are sorted by teststep and most frequent errors first.
+func (test *TestStatistics) Errors() []ErrorResult {
+	test.lock.RLock()
+	defer test.lock.RUnlock()
+	errors := []ErrorResult{}
+	for step, msgs := range test.errors {
+		for msg, count := range msgs {
+			errors = append(errors, ErrorResult{step, msg, count})
+		}
+	}
+	sort.Sort(byErrorCount(errors))
+	return errors
+}
+
+// Give me the start of the first and the end of the last measurement.
+func (test *TestStatistics) Runtime() (time.Time, time.Time) {
+	test.lock.RLock()
+	defer test.lock.RUnlock()
+	return test.first, test.end
+}
+
 // Helper to convert time.Duration to ms in float64.
 func d2f(d time.Duration) float64 {
 	return float64(d) / float64(time.Millisecond)
@@ -154,27 +322,53 @@ func d2f(d time.Duration) float64 {
 // In case since can not be parsed it returns all available results!
 func (test *TestStatistics) Results(since string) []Result {
 	test.lock.RLock()
-	copy := []Result{}
 	defer test.lock.RUnlock()
+	return test.results(since, test.percentiles)
+}
+
+// Results containing the given percentiles.
+// Careful: the caller needs to hold the lock!
+func (test *TestStatistics) results(since string, ps []float64) []Result {
+	copy := []Result{}
 
 	s, err := time.Parse(ISO8601, since)
 	all := (err != nil)
 	for k, v := range test.stats {
 		if all || (v.last.After(s)) {
+			var percentiles map[string]float64
+			if len(ps) > 0 {
+				percentiles = make(map[string]float64)
+				for _, p := range ps {
+					percentiles[pname(p)] = d2f(v.hist.percentile(p))
+				}
+			}
 			copy = append(copy, Result{k, d2f(v.avg), d2f(v.min), d2f(v.max),
-				v.count, v.error, v.last.UTC().Format(ISO8601)})
+				v.count, v.error, v.last.UTC().Format(ISO8601), percentiles})
 		}
 	}
 	sort.Sort(byTeststep(copy))
 	return copy
 }
 
-// Format the statistics to stdout.
+// Format the statistics and the threshold results to stdout.
 func (test *TestStatistics) Report(w io.Writer) {
+	test.reportResults(w)
+	test.reportThresholds(w)
+}
+
+// Format the statistics (one line per teststep).
+func (test *TestStatistics) reportResults(w io.Writer) {
 	res := test.Results("") // get all results
+	test.lock.RLock()
+	percentiles := test.percentiles
+	test.lock.RUnlock()
 	for _, s := range res {
-		fmt.Fprintf(w, "%s, %f, %f, %f, %d, %d\n", s.Teststep, s.Avg,
+		fmt.Fprintf(w, "%s, %f, %f, %f, %d, %d", s.Teststep, s.Avg,
 			s.Min, s.Max, s.Count, s.Error)
+		for _, p := range percentiles {
+			fmt.Fprintf(w, ", %f", s.Percentiles[pname(p)])
+		}
+		fmt.Fprintln(w)
 	}
 }
 
@@ -191,13 +385,19 @@ func (test *TestStatistics) Csv() (string, error) {
 	var b bytes.Buffer
 
 	// write the header (using json tags)
-	_, err := fmt.Fprintf(&b, "%s, %s, %s, %s, %s, %s\n", f2j("Teststep"), f2j("Avg"),
+	_, err := fmt.Fprintf(&b, "%s, %s, %s, %s, %s, %s", f2j("Teststep"), f2j("Avg"),
 		f2j("Min"), f2j("Max"), f2j("Count"), f2j("Error"))
 	if err != nil {
 		return b.String(), err
 	}
+	test.lock.RLock()
+	for _, p := range test.percentiles {
+		fmt.Fprintf(&b, ", %s_ms", pname(p))
+	}
+	test.lock.RUnlock()
+	fmt.Fprintln(&b)
 
 	// write the lines
-	test.Report(&b)
+	test.reportResults(&b)
 	return b.String(), nil
 }
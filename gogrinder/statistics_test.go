@@ -2,6 +2,7 @@ package gogrinder
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 
 	time "github.com/finklabs/ttime"
@@ -104,6 +105,46 @@ func TestReport(t *testing.T) {
 	}
 }
 
+func TestReportWithPercentiles(t *testing.T) {
+	var b bytes.Buffer
+	fake := NewTest()
+	fake.SetPercentiles(50, 90)
+	done := fake.Collect() // this needs a collector to unblock update
+	for i := 1; i <= 10; i++ {
+		fake.Update(&Meta{Teststep: "tc1", Elapsed: Elapsed(time.Duration(i) * time.Millisecond),
+			Timestamp: Timestamp(time.Now())})
+	}
+	close(fake.measurements)
+	<-done
+
+	res := fake.Results("")
+	if p := res[0].Percentiles["p90"]; p < 9.0 || p > 9.2 {
+		t.Errorf("Result percentile p90 %f not as expected!", p)
+	}
+
+	fake.Report(&b) // run the report
+	report := b.String()
+	if report != "tc1, 5.500000, 1.000000, 10.000000, 10, 0, 5.046271, 9.043967\n" {
+		t.Fatalf("Report output not as expected: %s", report)
+	}
+}
+
+func TestCsvWithPercentiles(t *testing.T) {
+	fake := NewTest()
+	fake.SetPercentiles(95, 99.9)
+	done := fake.Collect() // this needs a collector to unblock update
+	fake.Update(&Meta{Teststep: "tc1", Elapsed: Elapsed(8 * time.Millisecond),
+		Timestamp: Timestamp(time.Now())})
+	close(fake.measurements)
+	<-done
+
+	csv, _ := fake.Csv()
+	if csv != "teststep, avg_ms, min_ms, max_ms, count, error, p95_ms, p99.9_ms\n"+
+		"tc1, 8.000000, 8.000000, 8.000000, 1, 0, 8.060927, 8.060927\n" {
+		t.Fatalf("Csv output not as expected: %s", csv)
+	}
+}
+
 func TestDuration2Float(t *testing.T) {
 	f := d2f(20 * time.Microsecond)
 	if f != 0.020 {
@@ -221,3 +262,31 @@ func TestCollectCallsReporterUpdate(t *testing.T) {
 	close(fake.measurements)
 	<-done
 }
+
+func TestErrors(t *testing.T) {
+	fake := NewTest()
+	done := fake.Collect() // this needs a collector to unblock update
+	now := time.Now()
+	for _, e := range []string{"timeout", "", "404", "timeout"} {
+		fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond),
+			Timestamp: Timestamp(now), Error: e})
+	}
+	fake.Update(&Meta{Teststep: "else", Elapsed: Elapsed(2 * time.Millisecond),
+		Timestamp: Timestamp(now.Add(-time.Second)), Error: "timeout"})
+	close(fake.measurements)
+	<-done
+
+	exp := []ErrorResult{{"else", "timeout", 1}, {"sth", "timeout", 2}, {"sth", "404", 1}}
+	if errors := fake.Errors(); !reflect.DeepEqual(errors, exp) {
+		t.Errorf("Errors %v not as expected: %v", errors, exp)
+	}
+	first, end := fake.Runtime()
+	if !first.Equal(now.Add(-time.Second)) || !end.Equal(now.Add(8*time.Millisecond)) {
+		t.Errorf("Runtime %s - %s not as expected!", first, end)
+	}
+
+	fake.Reset()
+	if errors := fake.Errors(); len(errors) != 0 {
+		t.Errorf("Errors %v not as expected after reset!", errors)
+	}
+}
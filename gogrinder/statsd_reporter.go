@@ -0,0 +1,235 @@
+package gogrinder
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	time "github.com/finklabs/ttime"
+)
+
+// Measurements of a teststep within the flush interval.
+type aggregate struct {
+	count  int
+	errors int
+	sum    float64 // elapsed [ms]
+	min    float64
+	max    float64
+	sample []float64 // elapsed [ms] of a random sample of the measurements
+}
+
+// Client-side aggregation of the metrics per teststep. The StatsdReporter and
+// the GraphiteReporter send the aggregates every flush interval so high-rate
+// tests do not flood the network.
+type aggregator struct {
+	lock      sync.Mutex
+	flushLock sync.Mutex // only one flush at a time
+	steps     map[string]*aggregate
+	samples   int // size of the sample of the elapsed times per teststep (0 = none)
+	interval  time.Duration
+	flush     func(steps map[string]*aggregate) // send the aggregates
+	quit      chan bool                         // stop the flush ticker
+	done      chan bool                         // closed when the ticker finished
+	closed    bool
+}
+
+// Start the aggregation. <flush> is called every <interval> by the ticker.
+func (a *aggregator) start(interval time.Duration, flush func(map[string]*aggregate)) {
+	if interval <= 0 {
+		interval = 10 * time.Second // the default flush interval of StatsD
+	}
+	a.steps = make(map[string]*aggregate)
+	a.interval = interval
+	a.flush = flush
+	a.quit = make(chan bool)
+	a.done = make(chan bool)
+	go a.ticker()
+}
+
+// Flush the aggregates every interval.
+func (a *aggregator) ticker() {
+	defer close(a.done)
+	t := time.NewTicker(a.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			a.Flush()
+		case <-a.quit:
+			return
+		}
+	}
+}
+
+// Add the metric to the aggregate of its teststep.
+func (a *aggregator) Update(m Metric) {
+	elapsed := float64(m.GetElapsed()) / float64(time.Millisecond)
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.closed {
+		return
+	}
+	s, ok := a.steps[m.GetTeststep()]
+	if !ok {
+		s = &aggregate{min: math.Inf(1), max: math.Inf(-1)}
+		a.steps[m.GetTeststep()] = s
+	}
+	s.count++
+	if m.GetError() != "" {
+		s.errors++
+	}
+	s.sum += elapsed
+	s.min = math.Min(s.min, elapsed)
+	s.max = math.Max(s.max, elapsed)
+	// reservoir sampling: every measurement has the same chance to be sampled
+	if len(s.sample) < a.samples {
+		s.sample = append(s.sample, elapsed)
+	} else if i := rand.Intn(s.count); i < a.samples {
+		s.sample[i] = elapsed
+	}
+}
+
+// Send the aggregates of the current interval.
+func (a *aggregator) Flush() {
+	a.flushLock.Lock()
+	defer a.flushLock.Unlock()
+	a.lock.Lock()
+	steps := a.steps
+	a.steps = make(map[string]*aggregate)
+	a.lock.Unlock()
+	if len(steps) > 0 {
+		a.flush(steps)
+	}
+}
+
+// Stop the ticker and send the last aggregates.
+func (a *aggregator) Close() error {
+	a.lock.Lock()
+	if a.closed {
+		a.lock.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.lock.Unlock()
+	close(a.quit)
+	<-a.done
+	a.Flush()
+	return nil
+}
+
+// Sorted teststeps so the output is stable.
+func sortedSteps(steps map[string]*aggregate) []string {
+	names := make([]string, 0, len(steps))
+	for name := range steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Metric name of the teststep. Characters other than letters, digits, "_" and
+// "-" are replaced by "_" (e.g. "01_02 home.page" -> "01_02_home_page").
+func metricName(prefix string, teststep string) string {
+	var b bytes.Buffer
+	if prefix != "" {
+		b.WriteString(prefix)
+		b.WriteByte('.')
+	}
+	if teststep == "" {
+		teststep = "_"
+	}
+	for _, c := range teststep {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// Format a float value without exponent.
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Max. size of the StatsD packets (fits into the MTU of the ethernet).
+const statsdPacketSize = 1432
+
+// Max. number of StatsD timers per teststep and flush interval.
+const statsdSamples = 1000
+
+// StatsdReporter sends the metrics as StatsD timers and counters over UDP. The
+// metrics are collected per teststep and sent every flush interval:
+//
+//	<prefix>.<teststep>.count:<count>|c
+//	<prefix>.<teststep>.errors:<errors>|c
+//	<prefix>.<teststep>.elapsed:<elapsed>|ms
+//
+// Every measurement is sent as timer so StatsD calculates the percentiles. If
+// a teststep has more than statsdSamples measurements within the interval a
+// random sample of them is sent with the sample rate (e.g. "|ms|@0.5").
+type StatsdReporter struct {
+	aggregator
+	prefix string
+	conn   net.Conn
+}
+
+// Create a StatsdReporter that sends to the given address (e.g.
+// localhost:8125). The prefix is prepended to the metric names (e.g.
+// "gogrinder"). Use Close to send the last aggregates.
+func NewStatsdReporter(addr string, prefix string, interval time.Duration) (*StatsdReporter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	r := &StatsdReporter{prefix: prefix, conn: conn}
+	r.samples = statsdSamples
+	r.start(interval, r.send)
+	return r, nil
+}
+
+// Send the aggregates in packets of statsdPacketSize.
+func (r *StatsdReporter) send(steps map[string]*aggregate) {
+	var packet bytes.Buffer
+	write := func() {
+		if _, err := r.conn.Write(packet.Bytes()); err != nil {
+			log.Errorf("can not send metrics to statsd: %v", err)
+		}
+		packet.Reset()
+	}
+	add := func(line string) {
+		if packet.Len() > 0 && packet.Len()+len(line) > statsdPacketSize {
+			write()
+		}
+		packet.WriteString(line)
+	}
+	for _, name := range sortedSteps(steps) {
+		s := steps[name]
+		metric := metricName(r.prefix, name)
+		add(fmt.Sprintf("%s.count:%d|c\n", metric, s.count))
+		add(fmt.Sprintf("%s.errors:%d|c\n", metric, s.errors))
+		rate := ""
+		if len(s.sample) < s.count {
+			rate = "|@" + formatValue(float64(len(s.sample))/float64(s.count))
+		}
+		for _, elapsed := range s.sample {
+			add(fmt.Sprintf("%s.elapsed:%s|ms%s\n", metric, formatValue(elapsed), rate))
+		}
+	}
+	if packet.Len() > 0 {
+		write()
+	}
+}
+
+// Send the last aggregates and close the connection.
+func (r *StatsdReporter) Close() error {
+	r.aggregator.Close()
+	return r.conn.Close()
+}
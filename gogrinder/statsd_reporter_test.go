@@ -0,0 +1,146 @@
+package gogrinder
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestCheckStatsdReporterImplementsReporterInterface(t *testing.T) {
+	s := &StatsdReporter{}
+	if _, ok := interface{}(s).(Reporter); !ok {
+		t.Errorf("StatsdReporter does not implement the Reporter interface!")
+	}
+}
+
+func TestMetricName(t *testing.T) {
+	if n := metricName("gogrinder", "01_02 home.page"); n != "gogrinder.01_02_home_page" {
+		t.Errorf("Metric name not as expected: %s", n)
+	}
+	if n := metricName("", "sth-else"); n != "sth-else" {
+		t.Errorf("Metric name not as expected: %s", n)
+	}
+}
+
+func TestAggregatorUpdate(t *testing.T) {
+	var flushed map[string]*aggregate
+	a := &aggregator{}
+	a.start(time.Hour, func(steps map[string]*aggregate) { flushed = steps })
+	a.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond)})
+	a.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(2 * time.Millisecond), Error: "failed"})
+	a.Close()
+
+	s, ok := flushed["sth"]
+	if !ok || s.count != 2 || s.errors != 1 || s.sum != 10.0 || s.min != 2.0 || s.max != 8.0 {
+		t.Errorf("Aggregate not as expected: %v", s)
+	}
+	a.Update(&Meta{Teststep: "sth"}) // closed
+	if len(a.steps) != 0 {
+		t.Errorf("Expected no aggregation after Close!")
+	}
+}
+
+func TestStatsdReporterSend(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	r, err := NewStatsdReporter(pc.LocalAddr().String(), "gogrinder", time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r.Update(&Meta{Teststep: "01_home page", Elapsed: Elapsed(8 * time.Millisecond)})
+	r.Update(&Meta{Teststep: "01_home page", Elapsed: Elapsed(2 * time.Millisecond), Error: "failed"})
+	r.Flush()
+
+	buf := make([]byte, statsdPacketSize)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	exp := "gogrinder.01_home_page.count:2|c\ngogrinder.01_home_page.errors:1|c\n" +
+		"gogrinder.01_home_page.elapsed:8|ms\ngogrinder.01_home_page.elapsed:2|ms\n"
+	if string(buf[:n]) != exp {
+		t.Errorf("Packet expected: %s, but got: %s", exp, string(buf[:n]))
+	}
+	r.Close()
+}
+
+func TestAggregatorSample(t *testing.T) {
+	var flushed map[string]*aggregate
+	a := &aggregator{samples: 10}
+	a.start(time.Hour, func(steps map[string]*aggregate) { flushed = steps })
+	for i := 0; i < 100; i++ {
+		a.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(time.Duration(i) * time.Millisecond)})
+	}
+	a.Close()
+
+	if s := flushed["sth"]; s.count != 100 || len(s.sample) != 10 {
+		t.Errorf("Sample of 10 measurements expected: %v", s)
+	}
+}
+
+func TestStatsdReporterSampleRate(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	r, _ := NewStatsdReporter(pc.LocalAddr().String(), "", time.Hour)
+	for i := 0; i < 4*statsdSamples; i++ {
+		r.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(time.Millisecond)})
+	}
+	r.Close()
+
+	timers := 0
+	buf := make([]byte, 64*1024)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for timers < statsdSamples {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(string(buf[:n])), "\n") {
+			if strings.HasPrefix(line, "sth.elapsed:") {
+				if line != "sth.elapsed:1|ms|@0.25" {
+					t.Fatalf("Timer with sample rate expected but got: %s", line)
+				}
+				timers++
+			}
+		}
+	}
+}
+
+func TestStatsdReporterPacketSize(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	r, _ := NewStatsdReporter(pc.LocalAddr().String(), "gogrinder", time.Hour)
+	for i := 0; i < 100; i++ {
+		r.Update(&Meta{Teststep: strings.Repeat("x", 10) + string(rune('a'+i%26)) + string(rune('a'+i/26))})
+	}
+	r.Close()
+
+	lines := 0
+	buf := make([]byte, 64*1024)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for lines < 300 {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if n > statsdPacketSize {
+			t.Errorf("Packet exceeds %d bytes: %d", statsdPacketSize, n)
+		}
+		lines += strings.Count(string(buf[:n]), "\n")
+	}
+}
@@ -0,0 +1,171 @@
+package gogrinder
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	log "github.com/Sirupsen/logrus"
+	time "github.com/finklabs/ttime"
+)
+
+// Threshold defines a service level for a teststep e.g. a maximum p95 response time.
+// Thresholds are configured in the Thresholds section of the loadmodel.
+type Threshold struct {
+	Teststep   string  // teststep name. An empty name applies to every teststep (except hooks, late, dropped and panic).
+	Metric     string  // avg_ms, p<percentile>_ms, error_rate or throughput
+	Percentile float64 // percentile for p<percentile>_ms metrics
+	Limit      float64 // maximum (minimum for throughput)
+	Abort      bool    // stop the test once the threshold is breached
+}
+
+// ThresholdResult is the outcome of checking a threshold against the statistics.
+type ThresholdResult struct {
+	Teststep string  `json:"teststep"`
+	Metric   string  `json:"metric"`
+	Limit    float64 `json:"limit"`
+	Value    float64 `json:"value"`
+	Passed   bool    `json:"passed"`
+	Missing  bool    `json:"missing,omitempty"` // no measurements of the teststep (e.g. typo)
+}
+
+// ThresholdError is returned from Exec if the results breach thresholds.
+type ThresholdError struct {
+	Breaches []ThresholdResult
+}
+
+func (e *ThresholdError) Error() string {
+	msg := fmt.Sprintf("%d threshold(s) breached:", len(e.Breaches))
+	for _, b := range e.Breaches {
+		if b.Missing {
+			msg += fmt.Sprintf("\n- %s %s missing (no measurements)", b.Teststep, b.Metric)
+			continue
+		}
+		msg += fmt.Sprintf("\n- %s %s %f (limit %f)", b.Teststep, b.Metric, b.Value, b.Limit)
+	}
+	return msg
+}
+
+// Minimum number of measurements of a teststep before its thresholds can
+// abort the test (the first measurements are often slow or failing).
+const abortMinCount = 100
+
+// Check the thresholds against the current statistics. The <runtime> of the
+// test is used to calculate the throughput. Teststeps with less than <minCount>
+// measurements are skipped, with minCount 0 thresholds on teststeps without
+// measurements fail as missing.
+func (test *TestStatistics) checkThresholds(thresholds []Threshold, runtime time.Duration, minCount int64) []ThresholdResult {
+	test.lock.RLock()
+	defer test.lock.RUnlock()
+	results := []ThresholdResult{}
+
+	for _, th := range thresholds {
+		steps := []string{th.Teststep}
+		if th.Teststep == "" {
+			// applies to every teststep but the ones gogrinder records itself
+			steps = []string{}
+			for k, v := range test.stats {
+				if !v.internal {
+					steps = append(steps, k)
+				}
+			}
+			sort.Strings(steps)
+		}
+		for _, step := range steps {
+			v, exists := test.stats[step] // teststep could be missing
+			if minCount > 0 && (!exists || v.count < minCount) {
+				continue
+			}
+			if !exists {
+				results = append(results, ThresholdResult{step, th.Metric, th.Limit, 0, false, true})
+				continue
+			}
+			value := 0.0
+			switch th.Metric {
+			case "avg_ms":
+				value = d2f(v.avg)
+			case "error_rate":
+				if v.count > 0 {
+					value = float64(v.error) / float64(v.count)
+				}
+			case "throughput":
+				if runtime > 0 {
+					value = float64(v.count) / runtime.Seconds()
+				}
+			default:
+				value = d2f(v.hist.percentile(th.Percentile))
+			}
+			passed := value <= th.Limit
+			if th.Metric == "throughput" {
+				passed = value >= th.Limit
+			}
+			results = append(results, ThresholdResult{step, th.Metric, th.Limit, value, passed, false})
+		}
+	}
+	return results
+}
+
+// Set the results of the threshold check that are contained in the report.
+func (test *TestStatistics) setThresholdResults(results []ThresholdResult) {
+	test.lock.Lock()
+	test.thresholdResults = results
+	test.lock.Unlock()
+}
+
+// Give me the results of the threshold check at the end of the test.
+func (test *TestStatistics) Thresholds() []ThresholdResult {
+	test.lock.RLock()
+	defer test.lock.RUnlock()
+	return test.thresholdResults
+}
+
+// Format the threshold results.
+func (test *TestStatistics) reportThresholds(w io.Writer) {
+	for _, r := range test.Thresholds() {
+		state := "passed"
+		if r.Missing {
+			state = "MISSING"
+		} else if !r.Passed {
+			state = "BREACHED"
+		}
+		fmt.Fprintf(w, "threshold %s %s: %f (limit %f) %s\n", r.Teststep, r.Metric,
+			r.Value, r.Limit, state)
+	}
+}
+
+// Collect the breached thresholds into a ThresholdError.
+func thresholdError(results []ThresholdResult) error {
+	breaches := []ThresholdResult{}
+	for _, r := range results {
+		if !r.Passed {
+			breaches = append(breaches, r)
+		}
+	}
+	if len(breaches) == 0 {
+		return nil
+	}
+	return &ThresholdError{breaches}
+}
+
+// Stop the test as soon as a threshold marked for abort is breached.
+// Thresholds on the throughput are only checked at the end of the test, the
+// others once the teststep has abortMinCount measurements.
+func (test *TestScenario) watchThresholds(thresholds []Threshold) {
+	abort := []Threshold{}
+	for _, th := range thresholds {
+		if th.Abort && th.Metric != "throughput" {
+			abort = append(abort, th)
+		}
+	}
+	if len(abort) == 0 {
+		return
+	}
+	for test.Status() == Running {
+		time.Sleep(time.Second)
+		if err := thresholdError(test.checkThresholds(abort, 0, abortMinCount)); err != nil {
+			log.Errorf("stopping the test: %s", err.Error())
+			test.Stop()
+			return
+		}
+	}
+}
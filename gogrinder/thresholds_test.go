@@ -0,0 +1,218 @@
+package gogrinder
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestCheckThresholds(t *testing.T) {
+	fake := NewTest()
+	done := fake.Collect() // this needs a collector to unblock update
+	for i := 1; i <= 10; i++ {
+		fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(time.Duration(i) * time.Millisecond),
+			Timestamp: Timestamp(time.Now())})
+	}
+	fake.Update(&Meta{Teststep: "else", Elapsed: Elapsed(20 * time.Millisecond),
+		Timestamp: Timestamp(time.Now()), Error: "something went wrong!"})
+	// recorded by gogrinder and not covered by the global thresholds
+	fake.Update(&Meta{Testcase: "01_tc", Teststep: "01_tc_dropped",
+		Timestamp: Timestamp(time.Now()), Error: "worker pool exhausted"})
+	close(fake.measurements)
+	<-done
+
+	thresholds := []Threshold{
+		{"sth", "avg_ms", 0, 5.0, false},
+		{"sth", "p90_ms", 90, 10.0, false},
+		{"", "error_rate", 0, 0.1, false},
+		{"sth", "throughput", 0, 2.0, false},
+		{"missing", "throughput", 0, 1.0, false},
+		{"typo", "avg_ms", 0, 5.0, false},
+	}
+	results := fake.checkThresholds(thresholds, 10*time.Second, 0)
+	exp := []ThresholdResult{
+		{"sth", "avg_ms", 5.0, 5.5, false, false},
+		{"sth", "p90_ms", 10.0, 9.043967, true, false},
+		{"else", "error_rate", 0.1, 1.0, false, false},
+		{"sth", "error_rate", 0.1, 0.0, true, false},
+		{"sth", "throughput", 2.0, 1.0, false, false},
+		{"missing", "throughput", 1.0, 0.0, false, true},
+		{"typo", "avg_ms", 5.0, 0.0, false, true},
+	}
+	if len(results) != len(exp) {
+		t.Fatalf("Threshold results %v not as expected!", results)
+	}
+	for i := range exp {
+		if results[i] != exp[i] {
+			t.Errorf("Threshold result %v not as expected: %v!", results[i], exp[i])
+		}
+	}
+}
+
+func TestThresholdError(t *testing.T) {
+	results := []ThresholdResult{
+		{"sth", "avg_ms", 5.0, 5.5, false, false},
+		{"typo", "avg_ms", 5.0, 0.0, false, true},
+		{"sth", "p90_ms", 10.0, 9.5, true, false},
+	}
+	err := thresholdError(results)
+	if _, ok := err.(*ThresholdError); !ok {
+		t.Fatalf("Expected a ThresholdError but got: %v", err)
+	}
+	if err.Error() != "2 threshold(s) breached:\n- sth avg_ms 5.500000 (limit 5.000000)\n"+
+		"- typo avg_ms missing (no measurements)" {
+		t.Errorf("Error msg not as expected: %s", err.Error())
+	}
+
+	if err := thresholdError(results[2:]); err != nil {
+		t.Errorf("Expected no error for passed thresholds but got: %s", err.Error())
+	}
+}
+
+func TestReportWithThresholds(t *testing.T) {
+	var b bytes.Buffer
+	fake := NewTest()
+	done := fake.Collect() // this needs a collector to unblock update
+	fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond),
+		Timestamp: Timestamp(time.Now())})
+	close(fake.measurements)
+	<-done
+	fake.setThresholdResults([]ThresholdResult{{"sth", "avg_ms", 5.0, 8.0, false, false},
+		{"typo", "avg_ms", 5.0, 0.0, false, true}})
+
+	fake.Report(&b)
+	if b.String() != "sth, 8.000000, 8.000000, 8.000000, 1, 0\n"+
+		"threshold sth avg_ms: 8.000000 (limit 5.000000) BREACHED\n"+
+		"threshold typo avg_ms: 0.000000 (limit 5.000000) MISSING\n" {
+		t.Errorf("Report output not as expected: %s", b.String())
+	}
+
+	// the csv contains the statistics only
+	csv, _ := fake.Csv()
+	if csv != "teststep, avg_ms, min_ms, max_ms, count, error\n"+
+		"sth, 8.000000, 8.000000, 8.000000, 1, 0\n" {
+		t.Errorf("Csv output not as expected: %s", csv)
+	}
+}
+
+func TestExecWithThresholds(t *testing.T) {
+	time.Freeze(time.Now())
+	defer time.Unfreeze()
+
+	fake := NewTest()
+	loadmodel := `{
+	  "Scenario": "01_testcase",
+	  "Thresholds": [
+		{"Teststep": "sth", "MaxAvg": 40},
+		{"MaxErrorRate": 0}
+	  ]
+	}`
+	err := fake.ReadConfigValidate(loadmodel, LoadmodelSchema)
+	if err != nil {
+		t.Fatalf("Error while reading loadmodel config: %s!", err.Error())
+	}
+	fake.Testscenario("01_testcase", func(m *Meta, s Settings) {
+		b := fake.NewBracket("sth")
+		time.Sleep(50 * time.Millisecond)
+		b.End(m)
+	})
+
+	err = fake.Exec()
+	if e, ok := err.(*ThresholdError); !ok || len(e.Breaches) != 1 ||
+		e.Breaches[0].Metric != "avg_ms" {
+		t.Errorf("Expected a ThresholdError for avg_ms but got: %v", err)
+	}
+	if len(fake.Thresholds()) != 2 {
+		t.Errorf("Expected 2 threshold results but got: %v", fake.Thresholds())
+	}
+}
+
+func TestGetThresholds(t *testing.T) {
+	fake := NewTest()
+	loadmodel := `{
+	  "Scenario": "scenario1",
+	  "Thresholds": [
+		{"Teststep": "sth", "MaxAvg": 200, "MaxPercentiles": {"99": 800, "95": 500},
+		 "Abort": true},
+		{"MaxErrorRate": 0.01, "MinThroughput": 10}
+	  ]
+	}`
+	err := fake.ReadConfigValidate(loadmodel, LoadmodelSchema)
+	if err != nil {
+		t.Fatalf("Error while reading loadmodel config: %s!", err.Error())
+	}
+
+	thresholds, err := fake.GetThresholds()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s!", err.Error())
+	}
+	exp := []Threshold{
+		{"sth", "avg_ms", 0, 200, true},
+		{"sth", "p95_ms", 95, 500, true},
+		{"sth", "p99_ms", 99, 800, true},
+		{"", "error_rate", 0, 0.01, false},
+		{"", "throughput", 0, 10, false},
+	}
+	if !reflect.DeepEqual(thresholds, exp) {
+		t.Errorf("Thresholds %v not as expected!", thresholds)
+	}
+	if _, ok := fake.GetSettings()["Thresholds"]; ok {
+		t.Errorf("Error: additional properties must not contain 'Thresholds'!")
+	}
+}
+
+func TestReadLoadmodelSchemaInvalidThresholds(t *testing.T) {
+	fake := NewTest()
+	invalid := `{
+	  "Scenario": "scenario1",
+	  "Thresholds": [{"MaxPercentiles": {"p95": 500}}]
+	}`
+
+	err := fake.ReadConfigValidate(invalid, LoadmodelSchema)
+	if err == nil {
+		t.Fatalf("Expected an error for an invalid threshold percentile!")
+	}
+}
+
+func TestCheckThresholdsMinCount(t *testing.T) {
+	fake := NewTest()
+	done := fake.Collect() // this needs a collector to unblock update
+	for i := 0; i < 3; i++ {
+		fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond),
+			Timestamp: Timestamp(time.Now())})
+	}
+	fake.Update(&Meta{Teststep: "warmup", Elapsed: Elapsed(800 * time.Millisecond),
+		Timestamp: Timestamp(time.Now()), Error: "something went wrong!"})
+	close(fake.measurements)
+	<-done
+
+	// the teststeps with less measurements and the missing teststeps are skipped
+	thresholds := []Threshold{
+		{"", "avg_ms", 0, 5.0, true},
+		{"typo", "avg_ms", 0, 5.0, true},
+	}
+	results := fake.checkThresholds(thresholds, 0, 3)
+	exp := []ThresholdResult{{"sth", "avg_ms", 5.0, 8.0, false, false}}
+	if !reflect.DeepEqual(results, exp) {
+		t.Errorf("Threshold results %v not as expected: %v!", results, exp)
+	}
+}
+
+func TestWatchThresholdsStopsTest(t *testing.T) {
+	fake := NewTest()
+	done := fake.Collect() // this needs a collector to unblock update
+	fake.status = Running
+	for i := 0; i < abortMinCount; i++ {
+		fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond),
+			Timestamp: Timestamp(time.Now()), Error: "something went wrong!"})
+	}
+
+	fake.watchThresholds([]Threshold{{"sth", "error_rate", 0, 0.0, true}})
+	if fake.Status() != Stopping {
+		t.Errorf("Test status expected Stopping, but was: %d", fake.Status())
+	}
+	close(fake.measurements)
+	<-done
+}
@@ -0,0 +1,151 @@
+package gogrinder
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	time "github.com/finklabs/ttime"
+)
+
+// Default interval of the timeseries buckets.
+const defaultTimeseriesInterval = 5 * time.Second
+
+// Internal datastructure to aggregate the measurements of a teststep within
+// one interval of the timeseries.
+type ts_value struct {
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+	count  int64
+	error  int64
+	events []string // e.g. config changes within the interval
+}
+
+// []TimeseriesResult is what you get from test.Timeseries().
+type TimeseriesResult struct {
+	Timestamp  string  `json:"ts"`
+	Teststep   string  `json:"teststep"`
+	Avg        float64 `json:"avg_ms"`
+	Min        float64 `json:"min_ms"`
+	Max        float64 `json:"max_ms"`
+	Count      int64   `json:"count"`
+	Error      int64   `json:"error"`
+	Throughput float64 `json:"throughput"`      // teststeps per second
+	ErrorRate  float64 `json:"error_rate"`      // errors per teststep
+	Event      string  `json:"event,omitempty"` // events within the interval (e.g. config change)
+}
+
+// byTimestamp implements sort.Interface for []TimeseriesResult based on the
+// Timestamp and Teststep fields.
+type byTimestamp []TimeseriesResult
+
+func (a byTimestamp) Len() int      { return len(a) }
+func (a byTimestamp) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byTimestamp) Less(i, j int) bool {
+	if a[i].Timestamp == a[j].Timestamp {
+		return a[i].Teststep < a[j].Teststep
+	}
+	return a[i].Timestamp < a[j].Timestamp
+}
+
+// Set the interval of the timeseries buckets (clears the timeseries).
+func (test *TestStatistics) SetTimeseriesInterval(interval time.Duration) {
+	test.lock.Lock()
+	test.interval = interval
+	test.timeseries = make(map[time.Time]map[string]ts_value)
+	test.lock.Unlock()
+}
+
+// Add the measurement to its timeseries bucket.
+// Careful: the caller needs to hold the lock!
+func (test *TestStatistics) updateTimeseries(teststep string, timestamp time.Time,
+	elapsed time.Duration, err_count int64) {
+	bucket := timestamp.Truncate(test.interval)
+	steps, exists := test.timeseries[bucket]
+	if !exists {
+		steps = make(map[string]ts_value)
+		test.timeseries[bucket] = steps
+	}
+	val, exists := steps[teststep]
+	if exists {
+		val.sum += elapsed
+		if elapsed > val.max {
+			val.max = elapsed
+		}
+		if elapsed < val.min {
+			val.min = elapsed
+		}
+		val.count++
+		val.error += err_count
+	} else {
+		val = ts_value{elapsed, elapsed, elapsed, 1, err_count, nil}
+	}
+	steps[teststep] = val
+}
+
+// Add the event as marker to its timeseries bucket. Events are no measurements
+// so they do not count.
+// Careful: the caller needs to hold the lock!
+func (test *TestStatistics) timeseriesEvent(teststep string, timestamp time.Time, event string) {
+	bucket := timestamp.Truncate(test.interval)
+	steps, exists := test.timeseries[bucket]
+	if !exists {
+		steps = make(map[string]ts_value)
+		test.timeseries[bucket] = steps
+	}
+	val := steps[teststep]
+	val.events = append(val.events, event)
+	steps[teststep] = val
+}
+
+// Give me the timeseries buckets that end after <since> in ISO8601.
+// The bucket containing <since> is included so you can poll using the
+// timestamp of the last (possibly incomplete) bucket you received.
+// In case since can not be parsed it returns all available buckets!
+func (test *TestStatistics) Timeseries(since string) []TimeseriesResult {
+	test.lock.RLock()
+	defer test.lock.RUnlock()
+	copy := []TimeseriesResult{}
+
+	s, err := time.Parse(ISO8601, since)
+	all := (err != nil)
+	seconds := test.interval.Seconds()
+	for bucket, steps := range test.timeseries {
+		if all || bucket.Add(test.interval).After(s) {
+			for k, v := range steps {
+				r := TimeseriesResult{Timestamp: bucket.UTC().Format(ISO8601), Teststep: k,
+					Event: strings.Join(v.events, "; ")}
+				if v.count > 0 {
+					r.Avg, r.Min, r.Max = d2f(v.sum/time.Duration(v.count)), d2f(v.min), d2f(v.max)
+					r.Count, r.Error = v.count, v.error
+					r.Throughput = float64(v.count) / seconds
+					r.ErrorRate = float64(v.error) / float64(v.count)
+				}
+				copy = append(copy, r)
+			}
+		}
+	}
+	sort.Sort(byTimestamp(copy))
+	return copy
+}
+
+// Export the timeseries in csv format.
+func (test *TestStatistics) TimeseriesCsv() (string, error) {
+	var b bytes.Buffer
+
+	// write the header
+	_, err := fmt.Fprintln(&b, "ts, teststep, avg_ms, min_ms, max_ms, count, error, "+
+		"throughput, error_rate, event")
+	if err != nil {
+		return b.String(), err
+	}
+
+	// write the lines
+	for _, s := range test.Timeseries("") {
+		fmt.Fprintf(&b, "%s, %s, %f, %f, %f, %d, %d, %f, %f, %s\n", s.Timestamp,
+			s.Teststep, s.Avg, s.Min, s.Max, s.Count, s.Error, s.Throughput, s.ErrorRate, s.Event)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,75 @@
+package gogrinder
+
+import (
+	"testing"
+
+	time "github.com/finklabs/ttime"
+)
+
+func TestTimeseriesBuckets(t *testing.T) {
+	fake := NewTest()
+	fake.SetTimeseriesInterval(5 * time.Second)
+	done := fake.Collect() // this needs a collector to unblock update
+	t1 := time.Date(2016, 5, 1, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(6 * time.Second)
+	fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond), Timestamp: Timestamp(t1)})
+	fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(2 * time.Millisecond),
+		Timestamp: Timestamp(t1.Add(time.Second)), Error: "something went wrong!"})
+	fake.Update(&Meta{Teststep: "else", Elapsed: Elapsed(4 * time.Millisecond), Timestamp: Timestamp(t1)})
+	fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(10 * time.Millisecond), Timestamp: Timestamp(t2)})
+	close(fake.measurements)
+	<-done
+
+	res := fake.Timeseries("")
+	if len(res) != 3 {
+		t.Fatalf("Expected 3 timeseries results but got %d!", len(res))
+	}
+	exp := []TimeseriesResult{
+		{"2016-05-01T10:00:00Z", "else", 4, 4, 4, 1, 0, 0.2, 0, ""},
+		{"2016-05-01T10:00:00Z", "sth", 5, 2, 8, 2, 1, 0.4, 0.5, ""},
+		{"2016-05-01T10:00:05Z", "sth", 10, 10, 10, 1, 0, 0.2, 0, ""},
+	}
+	for i := range exp {
+		if res[i] != exp[i] {
+			t.Errorf("Timeseries result %v not as expected: %v!", res[i], exp[i])
+		}
+	}
+
+	// only buckets ending after since
+	res = fake.Timeseries(t2.Format(ISO8601))
+	if len(res) != 1 || res[0].Timestamp != "2016-05-01T10:00:05Z" {
+		t.Errorf("Timeseries since %s not as expected: %v!", t2.Format(ISO8601), res)
+	}
+}
+
+func TestTimeseriesReset(t *testing.T) {
+	fake := NewTest()
+	done := fake.Collect() // this needs a collector to unblock update
+	fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond), Timestamp: Timestamp(time.Now())})
+	close(fake.measurements)
+	<-done
+
+	fake.Reset()
+	if res := fake.Timeseries(""); len(res) != 0 {
+		t.Errorf("Reset failed to clear the timeseries: %v!", res)
+	}
+}
+
+func TestTimeseriesCsv(t *testing.T) {
+	fake := NewTest()
+	fake.SetTimeseriesInterval(10 * time.Second)
+	done := fake.Collect() // this needs a collector to unblock update
+	t1 := time.Date(2016, 5, 1, 10, 0, 3, 0, time.UTC)
+	fake.Update(&Meta{Teststep: "sth", Elapsed: Elapsed(8 * time.Millisecond), Timestamp: Timestamp(t1)})
+	fake.Update(&Meta{Testcase: "01_tc", Teststep: "01_tc_config_change", Timestamp: Timestamp(t1),
+		Event: "Users 2 -> 4"})
+	close(fake.measurements)
+	<-done
+
+	csv, _ := fake.TimeseriesCsv()
+	if csv != "ts, teststep, avg_ms, min_ms, max_ms, count, error, throughput, error_rate, event\n"+
+		"2016-05-01T10:00:00Z, 01_tc_config_change, 0.000000, 0.000000, 0.000000, 0, 0, 0.000000, 0.000000, Users 2 -> 4\n"+
+		"2016-05-01T10:00:00Z, sth, 8.000000, 8.000000, 8.000000, 1, 0, 0.100000, 0.000000, \n" {
+		t.Errorf("Timeseries csv not as expected: %s", csv)
+	}
+}
@@ -0,0 +1,196 @@
+package req
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/finklabs/GoGrinder/gogrinder"
+	time "github.com/finklabs/ttime"
+)
+
+// Response is what the assertions check. Do, DoRaw and DoJson assemble it
+// once the response body is read.
+type Response struct {
+	Code    int
+	Header  http.Header
+	Body    []byte
+	Bytes   int
+	Elapsed gogrinder.Elapsed // start of the request until the body is read
+	doc     interface{}       // parsed json body (lazy)
+	docErr  error
+}
+
+// Parse the body as json (only once for all assertions).
+func (r *Response) json() (interface{}, error) {
+	if r.doc == nil && r.docErr == nil {
+		r.docErr = json.Unmarshal(r.Body, &r.doc)
+	}
+	return r.doc, r.docErr
+}
+
+// Assertion checks the response of a request. Failed assertions are
+// recorded in the Error of the HttpMetric and counted per Name by the
+// HttpMetricReporter. Pass the assertions to Do, DoRaw or DoJson:
+//
+//	_, _, mm = req.DoJson(c, r, m, req.Status(200), req.JsonPath("name", "Enzo"))
+type Assertion struct {
+	Name  string
+	Check func(r *Response) error
+}
+
+// Same assertion but counted under a different name.
+func (a Assertion) Named(name string) Assertion {
+	return Assertion{name, a.Check}
+}
+
+// Assertion for a custom check.
+func AssertFunc(name string, check func(r *Response) error) Assertion {
+	return Assertion{name, check}
+}
+
+// Status code is one of the given codes.
+func Status(codes ...int) Assertion {
+	return Assertion{"status", func(r *Response) error {
+		for _, c := range codes {
+			if r.Code == c {
+				return nil
+			}
+		}
+		return fmt.Errorf("status code %d not in %v", r.Code, codes)
+	}}
+}
+
+// Status code is within [min, max] (e.g. 200, 299).
+func StatusRange(min int, max int) Assertion {
+	return Assertion{"status", func(r *Response) error {
+		if r.Code < min || r.Code > max {
+			return fmt.Errorf("status code %d not in range %d-%d", r.Code, min, max)
+		}
+		return nil
+	}}
+}
+
+// Body contains the given string.
+func BodyContains(s string) Assertion {
+	return Assertion{"body-contains", func(r *Response) error {
+		if !bytes.Contains(r.Body, []byte(s)) {
+			return fmt.Errorf("body does not contain %q", s)
+		}
+		return nil
+	}}
+}
+
+// Body matches the regular expression. It panics if the expression can not
+// be compiled (like regexp.MustCompile).
+func BodyMatches(expr string) Assertion {
+	re := regexp.MustCompile(expr)
+	return Assertion{"body-matches", func(r *Response) error {
+		if !re.Match(r.Body) {
+			return fmt.Errorf("body does not match %q", expr)
+		}
+		return nil
+	}}
+}
+
+// Value of the json body at the given path equals the expected value. The path
+// is a dot separated list of object keys and array indices (e.g. "data.0.name").
+func JsonPath(path string, value interface{}) Assertion {
+	// normalize the expected value so numbers compare as float64
+	var exp interface{}
+	buf, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(buf, &exp)
+	}
+	return Assertion{"json-path", func(r *Response) error {
+		if err != nil {
+			return fmt.Errorf("invalid expected value for %s: %v", path, err)
+		}
+		doc, jerr := r.json()
+		if jerr != nil {
+			return fmt.Errorf("body is not valid json: %v", jerr)
+		}
+		got, ok := lookupJsonPath(doc, path)
+		if !ok {
+			return fmt.Errorf("json path %s not found", path)
+		}
+		if !reflect.DeepEqual(got, exp) {
+			return fmt.Errorf("json path %s is %v, expected %v", path, got, exp)
+		}
+		return nil
+	}}
+}
+
+// Helper to find the value of a path in a json document.
+func lookupJsonPath(doc interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return doc, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			v, ok := d[key]
+			if !ok {
+				return nil, false
+			}
+			doc = v
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(d) {
+				return nil, false
+			}
+			doc = d[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// Response contains the given header.
+func Header(name string) Assertion {
+	return Assertion{"header", func(r *Response) error {
+		if _, ok := r.Header[http.CanonicalHeaderKey(name)]; !ok {
+			return fmt.Errorf("header %s is missing", name)
+		}
+		return nil
+	}}
+}
+
+// Response is read within the given time.
+func MaxElapsed(max time.Duration) Assertion {
+	return Assertion{"max-elapsed", func(r *Response) error {
+		if time.Duration(r.Elapsed) > max {
+			return fmt.Errorf("response took %v, limit %v", time.Duration(r.Elapsed), max)
+		}
+		return nil
+	}}
+}
+
+// Response body is not larger than the given number of bytes.
+func MaxBytes(max int) Assertion {
+	return Assertion{"max-bytes", func(r *Response) error {
+		if r.Bytes > max {
+			return fmt.Errorf("response has %d bytes, limit %d", r.Bytes, max)
+		}
+		return nil
+	}}
+}
+
+// Check the assertions and record the failures in the HttpMetric.
+func (hm *HttpMetric) assert(r *Response, assertions []Assertion) {
+	for _, a := range assertions {
+		if err := a.Check(r); err != nil {
+			if len(hm.Error) > 0 {
+				hm.Error += "; "
+			}
+			hm.Error += fmt.Sprintf("assertion %s failed: %v", a.Name, err)
+			hm.FailedAssertions = append(hm.FailedAssertions, a.Name)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package req
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/finklabs/GoGrinder/gogrinder"
+	time "github.com/finklabs/ttime"
+)
+
+func TestAssertions(t *testing.T) {
+	r := &Response{Code: 200, Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body: []byte(`{"id": 1, "name": "Enzo", "tags": ["red", {"speed": 218}]}`), Bytes: 60,
+		Elapsed: gogrinder.Elapsed(50 * time.Millisecond)}
+
+	tests := []struct {
+		assertion Assertion
+		err       string
+	}{
+		{Status(200, 201), ""},
+		{Status(201), "status code 200 not in [201]"},
+		{StatusRange(200, 299), ""},
+		{StatusRange(400, 599), "status code 200 not in range 400-599"},
+		{BodyContains(`"Enzo"`), ""},
+		{BodyContains("Ferrari"), `body does not contain "Ferrari"`},
+		{BodyMatches(`"id":\s*\d+`), ""},
+		{BodyMatches(`^\[`), "body does not match \"^\\\\[\""},
+		{JsonPath("id", 1), ""},
+		{JsonPath("tags.1.speed", 218), ""},
+		{JsonPath("tags.0", "red"), ""},
+		{JsonPath("name", "Ferrari"), "json path name is Enzo, expected Ferrari"},
+		{JsonPath("tags.2", "red"), "json path tags.2 not found"},
+		{Header("content-type"), ""},
+		{Header("Location"), "header Location is missing"},
+		{MaxElapsed(50 * time.Millisecond), ""},
+		{MaxElapsed(10 * time.Millisecond), "response took 50ms, limit 10ms"},
+		{MaxBytes(100), ""},
+		{MaxBytes(10), "response has 60 bytes, limit 10"},
+	}
+	for _, test := range tests {
+		err := test.assertion.Check(r)
+		if (err == nil && test.err != "") || (err != nil && err.Error() != test.err) {
+			t.Errorf("Assertion %s expected '%s' but was: %v", test.assertion.Name, test.err, err)
+		}
+	}
+}
+
+func TestJsonPathInvalidBody(t *testing.T) {
+	r := &Response{Body: []byte("<html></html>")}
+	err := JsonPath("id", 1).Check(r)
+	if err == nil || !strings.HasPrefix(err.Error(), "body is not valid json") {
+		t.Errorf("Error not as expected: %v", err)
+	}
+}
+
+func TestAssertRecordsFailures(t *testing.T) {
+	hm := &HttpMetric{Meta: gogrinder.Meta{Error: "connection reset"}}
+	hm.assert(&Response{Code: 500}, []Assertion{StatusRange(200, 299),
+		AssertFunc("custom", func(r *Response) error { return nil }),
+		Status(200).Named("ok")})
+
+	if exp := "connection reset; assertion status failed: status code 500 not in range 200-299; " +
+		"assertion ok failed: status code 500 not in [200]"; hm.Error != exp {
+		t.Errorf("Error not as expected: %s", hm.Error)
+	}
+	if !reflect.DeepEqual(hm.FailedAssertions, []string{"status", "ok"}) {
+		t.Errorf("Failed assertions not as expected: %v", hm.FailedAssertions)
+	}
+}
+
+func TestDoWithAssertions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "something is wrong"}`))
+	}))
+	defer ts.Close()
+
+	m := &gogrinder.Meta{Testcase: "sth", Teststep: "else", User: 0, Iteration: 0}
+	c := NewDefaultClient()
+
+	r, _ := http.NewRequest("GET", ts.URL, nil)
+	_, _, metric := DoRaw(c, r, m, StatusRange(200, 299), BodyContains("wrong"))
+	if !reflect.DeepEqual(metric.FailedAssertions, []string{"status"}) {
+		t.Errorf("DoRaw failed assertions not as expected: %v", metric.FailedAssertions)
+	}
+
+	r, _ = http.NewRequest("GET", ts.URL, nil)
+	_, _, metric = DoJson(c, r, m, Status(500), JsonPath("error", "all good"))
+	if !reflect.DeepEqual(metric.FailedAssertions, []string{"json-path"}) {
+		t.Errorf("DoJson failed assertions not as expected: %v", metric.FailedAssertions)
+	}
+
+	r, _ = http.NewRequest("GET", ts.URL, nil)
+	_, _, metric = Do(c, r, m, BodyContains("all good"), MaxBytes(1024))
+	if !reflect.DeepEqual(metric.FailedAssertions, []string{"body-contains"}) {
+		t.Errorf("Do failed assertions not as expected: %v", metric.FailedAssertions)
+	}
+
+	// without assertions the status code is not checked
+	r, _ = http.NewRequest("GET", ts.URL, nil)
+	_, _, metric = DoRaw(c, r, m)
+	if metric.Error != "" || metric.Code != 500 {
+		t.Errorf("Metric not as expected: %v", metric)
+	}
+}
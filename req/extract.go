@@ -0,0 +1,244 @@
+package req
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Extractors to correlate dynamic values (CSRF tokens, ids, session keys)
+// between requests. In contrast to type assertions on the response they
+// return an error if the value is not found.
+
+// Value of the json document at the given path (see JsonPath for the syntax).
+// The document is the result of DoJson or any unmarshalled json value.
+func ExtractJson(doc interface{}, path string) (interface{}, error) {
+	v, ok := lookupJsonPath(doc, path)
+	if !ok {
+		return nil, fmt.Errorf("json path %s not found", path)
+	}
+	return v, nil
+}
+
+// String value of the json document at the given path. Numbers and booleans
+// are converted.
+func ExtractJsonString(doc interface{}, path string) (string, error) {
+	v, err := ExtractJson(doc, path)
+	if err != nil {
+		return "", err
+	}
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(s), nil
+	}
+	return "", fmt.Errorf("json path %s is not a string: %v", path, v)
+}
+
+// Numeric value of the json document at the given path. Strings are parsed.
+func ExtractJsonFloat(doc interface{}, path string) (float64, error) {
+	v, err := ExtractJson(doc, path)
+	if err != nil {
+		return 0, err
+	}
+	switch f := v.(type) {
+	case float64:
+		return f, nil
+	case string:
+		if p, perr := strconv.ParseFloat(f, 64); perr == nil {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("json path %s is not a number: %v", path, v)
+}
+
+// Integer value of the json document at the given path. Strings are parsed.
+func ExtractJsonInt(doc interface{}, path string) (int, error) {
+	f, err := ExtractJsonFloat(doc, path)
+	if err != nil {
+		return 0, err
+	}
+	if f != float64(int(f)) {
+		return 0, fmt.Errorf("json path %s is not an integer: %v", path, f)
+	}
+	return int(f), nil
+}
+
+// Text of the first element matching the css selector. If attr is given the
+// value of the attribute is returned instead.
+//
+//	token, err := req.ExtractCss(doc, "input[name=csrf]", "value")
+func ExtractCss(doc *goquery.Document, selector string, attr string) (string, error) {
+	if doc == nil {
+		return "", fmt.Errorf("no document")
+	}
+	sel := doc.Find(selector).First()
+	if sel.Length() == 0 {
+		return "", fmt.Errorf("css selector %s not found", selector)
+	}
+	return selectionValue(sel, attr, selector)
+}
+
+// Helper to read the text or attribute of a selection.
+func selectionValue(sel *goquery.Selection, attr string, expr string) (string, error) {
+	if attr == "" {
+		return strings.TrimSpace(sel.Text()), nil
+	}
+	v, ok := sel.Attr(attr)
+	if !ok {
+		return "", fmt.Errorf("attribute %s not found for %s", attr, expr)
+	}
+	return v, nil
+}
+
+var (
+	xpathStep      = regexp.MustCompile(`^([\w*-]+)((?:\[[^\]]*\])*)$`)
+	xpathPredicate = regexp.MustCompile(`\[([^\]]*)\]`)
+	xpathAttribute = regexp.MustCompile(`^@([\w:-]+)(?:\s*=\s*(?:'([^']*)'|"([^"]*)"))?$`)
+)
+
+// Text or attribute value of the first element matching a simple xpath
+// expression. Supported are child (/) and descendant (//) steps, element
+// names or *, position predicates ([1]) and attribute predicates
+// ([@name] and [@name='value']). The last step can be @attr or text().
+// The position is the position within all matching elements.
+//
+//	token, err := req.ExtractXPath(doc, "//form[@id='login']//input[@name='csrf']/@value")
+func ExtractXPath(doc *goquery.Document, path string) (string, error) {
+	if doc == nil {
+		return "", fmt.Errorf("no document")
+	}
+	if strings.Trim(path, "/") == "" {
+		return "", fmt.Errorf("invalid xpath %s", path)
+	}
+	sel := doc.Selection
+	rest := path
+	for rest != "" {
+		descendant := true // relative paths start anywhere in the document
+		if strings.HasPrefix(rest, "//") {
+			rest = rest[2:]
+		} else if strings.HasPrefix(rest, "/") {
+			rest = rest[1:]
+			descendant = false
+		}
+		step := rest
+		if i := nextXPathStep(rest); i >= 0 {
+			step, rest = rest[:i], rest[i:]
+		} else {
+			rest = ""
+		}
+
+		// last step
+		if strings.HasPrefix(step, "@") || step == "text()" {
+			if rest != "" {
+				return "", fmt.Errorf("invalid xpath %s", path)
+			}
+			if step == "text()" {
+				return selectionValue(sel.First(), "", path)
+			}
+			return selectionValue(sel.First(), step[1:], path)
+		}
+
+		m := xpathStep.FindStringSubmatch(step)
+		if m == nil {
+			return "", fmt.Errorf("unsupported xpath step %s in %s", step, path)
+		}
+		if descendant {
+			sel = sel.Find(m[1])
+		} else {
+			sel = sel.ChildrenFiltered(m[1])
+		}
+		for _, p := range xpathPredicate.FindAllStringSubmatch(m[2], -1) {
+			pred := strings.TrimSpace(p[1])
+			if n, err := strconv.Atoi(pred); err == nil {
+				sel = sel.Eq(n - 1)
+				continue
+			}
+			a := xpathAttribute.FindStringSubmatch(pred)
+			if a == nil {
+				return "", fmt.Errorf("unsupported xpath predicate [%s] in %s", pred, path)
+			}
+			name, value, hasValue := a[1], a[2]+a[3], strings.Contains(pred, "=")
+			sel = sel.FilterFunction(func(i int, s *goquery.Selection) bool {
+				v, ok := s.Attr(name)
+				return ok && (!hasValue || v == value)
+			})
+		}
+		if sel.Length() == 0 {
+			return "", fmt.Errorf("xpath %s not found", path)
+		}
+	}
+	return selectionValue(sel.First(), "", path)
+}
+
+// Helper to find the end of the current xpath step (slashes inside of
+// predicates do not count).
+func nextXPathStep(path string) int {
+	depth := 0
+	for i, c := range path {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '/':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// First match of the regular expression in the body. If the expression
+// contains a group the value of the first group is returned.
+//
+//	id, err := req.ExtractRegex(raw, `"_id":\s*"(\d+)"`)
+func ExtractRegex(body []byte, expr string) (string, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", err
+	}
+	m := re.FindSubmatch(body)
+	if m == nil {
+		return "", fmt.Errorf("regex %s not found", expr)
+	}
+	if len(m) > 1 {
+		return string(m[1]), nil
+	}
+	return string(m[0]), nil
+}
+
+// Value of the response header.
+func ExtractHeader(header http.Header, name string) (string, error) {
+	if _, ok := header[http.CanonicalHeaderKey(name)]; !ok {
+		return "", fmt.Errorf("header %s not found", name)
+	}
+	return header.Get(name), nil
+}
+
+// Value of the cookie the client holds for the url (e.g. the session id).
+// The client needs a cookiejar (see NewDefaultClient).
+func ExtractCookie(c *http.Client, rawurl string, name string) (string, error) {
+	if c.Jar == nil {
+		return "", fmt.Errorf("client has no cookiejar")
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	for _, cookie := range c.Jar.Cookies(u) {
+		if cookie.Name == name {
+			return cookie.Value, nil
+		}
+	}
+	return "", fmt.Errorf("cookie %s not found for %s", name, rawurl)
+}
@@ -0,0 +1,166 @@
+package req
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/finklabs/GoGrinder/gogrinder"
+)
+
+const loginPage = `<!DOCTYPE html><html><body>
+<h1>Login</h1>
+<form id="search" action="/search"><input name="csrf" value="wrong"/></form>
+<form id="login" action="/login">
+  <input type="text" name="user"/>
+  <input type="hidden" name="csrf" value="a1b2c3"/>
+</form>
+<ul><li>first</li><li class="x">second</li><li>third</li></ul>
+</body></html>`
+
+func loginDoc(t *testing.T) *goquery.Document {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(loginPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestExtractJson(t *testing.T) {
+	var doc interface{}
+	json.Unmarshal([]byte(`{"_id": "00042", "count": 30, "price": 12.5, "ok": true,
+		"data": [{"name": "Enzo"}]}`), &doc)
+
+	if s, err := ExtractJsonString(doc, "data.0.name"); err != nil || s != "Enzo" {
+		t.Errorf("String not as expected: %s, %v", s, err)
+	}
+	if s, err := ExtractJsonString(doc, "count"); err != nil || s != "30" {
+		t.Errorf("String of a number not as expected: %s, %v", s, err)
+	}
+	if i, err := ExtractJsonInt(doc, "_id"); err != nil || i != 42 {
+		t.Errorf("Int not as expected: %d, %v", i, err)
+	}
+	if _, err := ExtractJsonInt(doc, "price"); err == nil ||
+		err.Error() != "json path price is not an integer: 12.5" {
+		t.Errorf("Error not as expected: %v", err)
+	}
+	if f, err := ExtractJsonFloat(doc, "price"); err != nil || f != 12.5 {
+		t.Errorf("Float not as expected: %f, %v", f, err)
+	}
+	if _, err := ExtractJsonFloat(doc, "data"); err == nil {
+		t.Errorf("Error expected for an array!")
+	}
+	if _, err := ExtractJsonString(doc, "data.1.name"); err == nil ||
+		err.Error() != "json path data.1.name not found" {
+		t.Errorf("Error not as expected: %v", err)
+	}
+	// no panic for unexpected responses
+	if _, err := ExtractJsonString(nil, "_id"); err == nil {
+		t.Errorf("Error expected for an empty response!")
+	}
+}
+
+func TestExtractCss(t *testing.T) {
+	doc := loginDoc(t)
+	if s, err := ExtractCss(doc, "#login input[name=csrf]", "value"); err != nil || s != "a1b2c3" {
+		t.Errorf("Attribute not as expected: %s, %v", s, err)
+	}
+	if s, err := ExtractCss(doc, "h1", ""); err != nil || s != "Login" {
+		t.Errorf("Text not as expected: %s, %v", s, err)
+	}
+	if _, err := ExtractCss(doc, "#logout", ""); err == nil ||
+		err.Error() != "css selector #logout not found" {
+		t.Errorf("Error not as expected: %v", err)
+	}
+	if _, err := ExtractCss(doc, "h1", "class"); err == nil ||
+		err.Error() != "attribute class not found for h1" {
+		t.Errorf("Error not as expected: %v", err)
+	}
+	if _, err := ExtractCss(nil, "h1", ""); err == nil {
+		t.Errorf("Error expected for a missing document!")
+	}
+}
+
+func TestExtractXPath(t *testing.T) {
+	doc := loginDoc(t)
+	tests := []struct {
+		path string
+		exp  string
+		err  string
+	}{
+		{"//form[@id='login']//input[@name='csrf']/@value", "a1b2c3", ""},
+		{"/html/body/form[2]/input[@type=\"hidden\"]/@value", "a1b2c3", ""},
+		{"//input[@name='csrf'][1]/@value", "wrong", ""},
+		{"//form[@action]/@id", "search", ""},
+		{"//ul/li[2]", "second", ""},
+		{"//ul/*[3]/text()", "third", ""},
+		{"h1", "Login", ""},
+		{"//form[@id='logout']", "", "xpath //form[@id='logout'] not found"},
+		{"//h1/@class", "", "attribute class not found for //h1/@class"},
+		{"//li[last()]", "", "unsupported xpath predicate [last()] in //li[last()]"},
+		{"//ul/..", "", "unsupported xpath step .. in //ul/.."},
+		{"//@id/form", "", "invalid xpath //@id/form"},
+		{"/", "", "invalid xpath /"},
+	}
+	for _, test := range tests {
+		s, err := ExtractXPath(doc, test.path)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("Error for %s not as expected: %v", test.path, err)
+			}
+			continue
+		}
+		if err != nil || s != test.exp {
+			t.Errorf("Value for %s not as expected: %s, %v", test.path, s, err)
+		}
+	}
+}
+
+func TestExtractRegex(t *testing.T) {
+	body := []byte(`{"_id": "00042", "name": "Enzo"}`)
+	if s, err := ExtractRegex(body, `"_id":\s*"(\d+)"`); err != nil || s != "00042" {
+		t.Errorf("Group not as expected: %s, %v", s, err)
+	}
+	if s, err := ExtractRegex(body, `E\w+`); err != nil || s != "Enzo" {
+		t.Errorf("Match not as expected: %s, %v", s, err)
+	}
+	if _, err := ExtractRegex(body, `"token"`); err == nil || err.Error() != `regex "token" not found` {
+		t.Errorf("Error not as expected: %v", err)
+	}
+	if _, err := ExtractRegex(body, `(`); err == nil {
+		t.Errorf("Error expected for an invalid regex!")
+	}
+}
+
+func TestExtractHeaderAndCookie(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t"})
+		w.Header().Set("X-Request-Id", "4711")
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	c := NewDefaultClient()
+	r, _ := http.NewRequest("GET", ts.URL, nil)
+	_, header, _ := DoRaw(c, r, &gogrinder.Meta{Testcase: "sth", Teststep: "else"})
+
+	if s, err := ExtractHeader(header, "x-request-id"); err != nil || s != "4711" {
+		t.Errorf("Header not as expected: %s, %v", s, err)
+	}
+	if _, err := ExtractHeader(header, "Location"); err == nil || err.Error() != "header Location not found" {
+		t.Errorf("Error not as expected: %v", err)
+	}
+	if s, err := ExtractCookie(c, ts.URL, "session"); err != nil || s != "s3cr3t" {
+		t.Errorf("Cookie not as expected: %s, %v", s, err)
+	}
+	if _, err := ExtractCookie(c, ts.URL, "other"); err == nil {
+		t.Errorf("Error expected for a missing cookie!")
+	}
+	if _, err := ExtractCookie(&http.Client{}, ts.URL, "session"); err == nil ||
+		err.Error() != "client has no cookiejar" {
+		t.Errorf("Error not as expected: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package req
 
 import (
+	"encoding/json"
 	"strconv"
 
 	"github.com/finklabs/GoGrinder/gogrinder"
@@ -9,30 +10,96 @@ import (
 )
 
 type HttpMetric struct {
-	gogrinder.Meta                   // std. GoGrinder metric info
-	FirstByte      gogrinder.Elapsed `json:"first-byte"` // first byte after [ns]
-	Bytes          int               `json:"kb"`         // response size [kb]
-	Code           int               `json:"status"`     // http status code
+	gogrinder.Meta                     // std. GoGrinder metric info
+	FirstByte        gogrinder.Elapsed `json:"first-byte" prometheus:"gogrinder_first_byte_ms,summary"`                                     // first byte after [ns]
+	Bytes            int               `json:"kb" prometheus:"gogrinder_response_kb,summary,kb"`                                            // response size [bytes] (reported in kb)
+	Code             int               `json:"status" prometheus:"gogrinder_response_code_count,counter,code"`                              // http status code
+	DNSLookup        gogrinder.Elapsed `json:"dns-lookup" prometheus:"gogrinder_dns_lookup_ms,summary"`                                     // dns lookup [ns]
+	Connect          gogrinder.Elapsed `json:"connect" prometheus:"gogrinder_connect_ms,summary"`                                           // tcp connect [ns]
+	TLSHandshake     gogrinder.Elapsed `json:"tls-handshake" prometheus:"gogrinder_tls_handshake_ms,summary"`                               // tls handshake [ns]
+	RequestWrite     gogrinder.Elapsed `json:"request-write" prometheus:"gogrinder_request_write_ms,summary"`                               // writing the request [ns]
+	ServerWait       gogrinder.Elapsed `json:"server-wait" prometheus:"gogrinder_server_wait_ms,summary"`                                   // request written until first byte [ns]
+	ContentTransfer  gogrinder.Elapsed `json:"content-transfer" prometheus:"gogrinder_content_transfer_ms,summary"`                         // first byte until body is read [ns]
+	FailedAssertions []string          `json:"failed-assertions,omitempty" prometheus:"gogrinder_assertion_failed_count,counter,assertion"` // names of the failed assertions
+}
+
+// Replay the http metrics of the event-log with all their fields.
+func init() {
+	gogrinder.RegisterEventLogDecoder(decodeHttpMetric)
+}
+
+// Event-log entries with a status code are http metrics.
+func decodeHttpMetric(entry []byte) (gogrinder.Metric, bool) {
+	var probe struct {
+		Code *int `json:"status"`
+	}
+	if err := json.Unmarshal(entry, &probe); err != nil || probe.Code == nil {
+		return nil, false
+	}
+	m := &HttpMetric{}
+	if err := json.Unmarshal(entry, m); err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
+// Access for reporters (e.g. gogrinder.JtlReporter)
+func (m *HttpMetric) GetCode() int {
+	return m.Code
+}
+
+// Access for reporters (e.g. gogrinder.JtlReporter)
+func (m *HttpMetric) GetBytes() int {
+	return m.Bytes
+}
+
+// Access for reporters (e.g. gogrinder.JtlReporter)
+func (m *HttpMetric) GetFirstByte() gogrinder.Elapsed {
+	return m.FirstByte
 }
 
 // Specific prometheus reporter for HttpMetric.
 // All metrics are represents as vectors of teststeps
+// Only one of HttpMetricReporter, gogrinder.MetricReporter and
+// gogrinder.PrometheusReporter can be exported, they all provide
+// gogrinder_elapsed_ms and gogrinder_error_count.
 type HttpMetricReporter struct {
-	elapsed   *prometheus.SummaryVec
-	firstByte *prometheus.SummaryVec
-	bytes     *prometheus.SummaryVec
-	code      *prometheus.CounterVec
-	error     *prometheus.CounterVec
+	elapsed         prometheus.ObserverVec
+	firstByte       prometheus.ObserverVec
+	bytes           *prometheus.SummaryVec
+	code            *prometheus.CounterVec
+	error           *prometheus.CounterVec
+	dnsLookup       prometheus.ObserverVec
+	connect         prometheus.ObserverVec
+	tlsHandshake    prometheus.ObserverVec
+	requestWrite    prometheus.ObserverVec
+	serverWait      prometheus.ObserverVec
+	contentTransfer prometheus.ObserverVec
+	assertion       *prometheus.CounterVec
 }
 
 func NewHttpMetricReporter() *HttpMetricReporter {
+	return newHttpMetricReporter(nil)
+}
+
+// HttpMetricReporter that uses histograms with the given buckets [ms] for the
+// times instead of summaries (nil uses the gogrinder.DefBuckets). The response
+// size is still reported as summary.
+func NewHistogramHttpMetricReporter(buckets []float64) *HttpMetricReporter {
+	if buckets == nil {
+		buckets = gogrinder.DefBuckets
+	}
+	return newHttpMetricReporter(buckets)
+}
+
+func newHttpMetricReporter(buckets []float64) *HttpMetricReporter {
 	return &HttpMetricReporter{
-		gogrinder.NewSummaryVec(
+		gogrinder.NewObserverVec(
 			"gogrinder_elapsed_ms",
-			"Current time elapsed of gogrinder teststep in ms."),
-		gogrinder.NewSummaryVec(
+			"Current time elapsed of gogrinder teststep in ms.", buckets),
+		gogrinder.NewObserverVec(
 			"gogrinder_first_byte_ms",
-			"Current time of gogrinder teststep until first byte received in ms."),
+			"Current time of gogrinder teststep until first byte received in ms.", buckets),
 		gogrinder.NewSummaryVec(
 			"gogrinder_response_kb",
 			"Current response of gogrinder teststep in kb."),
@@ -44,20 +111,72 @@ func NewHttpMetricReporter() *HttpMetricReporter {
 			Name: "gogrinder_error_count",
 			Help: "Current error of gogrinder teststep.",
 		}, []string{"teststep"}),
+		gogrinder.NewObserverVec(
+			"gogrinder_dns_lookup_ms",
+			"Current time of gogrinder teststep for the dns lookup in ms.", buckets),
+		gogrinder.NewObserverVec(
+			"gogrinder_connect_ms",
+			"Current time of gogrinder teststep for the tcp connect in ms.", buckets),
+		gogrinder.NewObserverVec(
+			"gogrinder_tls_handshake_ms",
+			"Current time of gogrinder teststep for the tls handshake in ms.", buckets),
+		gogrinder.NewObserverVec(
+			"gogrinder_request_write_ms",
+			"Current time of gogrinder teststep for writing the request in ms.", buckets),
+		gogrinder.NewObserverVec(
+			"gogrinder_server_wait_ms",
+			"Current time of gogrinder teststep waiting for the server in ms.", buckets),
+		gogrinder.NewObserverVec(
+			"gogrinder_content_transfer_ms",
+			"Current time of gogrinder teststep for the content transfer in ms.", buckets),
+		prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gogrinder_assertion_failed_count",
+			Help: "Failed assertions of gogrinder teststep.",
+		}, []string{"teststep", "assertion"}),
 	}
 }
 
-// We did not find out a way to implement a generic prometheus reporter.
-// So this is a specific prometheus reporter that deals with HttpMetric values.
+// Describe implements prometheus.Collector so the reporter can be registered.
+func (r *HttpMetricReporter) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range r.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector so the reporter can be registered.
+func (r *HttpMetricReporter) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range r.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func (r *HttpMetricReporter) collectors() []prometheus.Collector {
+	return []prometheus.Collector{r.elapsed, r.firstByte, r.bytes, r.code, r.error,
+		r.dnsLookup, r.connect, r.tlsHandshake, r.requestWrite, r.serverWait,
+		r.contentTransfer, r.assertion}
+}
+
+// Specific prometheus reporter that deals with HttpMetric values. The generic
+// gogrinder.PrometheusReporter derives the metrics from the struct tags of
+// HttpMetric instead.
 func (r *HttpMetricReporter) Update(m gogrinder.Metric) {
 	// find out if we deal with a HttpMetric
 	if h, ok := m.(*HttpMetric); ok {
 		r.bytes.WithLabelValues(h.Teststep).Observe(float64(h.Bytes) / float64(1024))
 		r.firstByte.WithLabelValues(h.Teststep).Observe(float64(h.FirstByte) / float64(time.Millisecond))
 		r.elapsed.WithLabelValues(h.Teststep).Observe(float64(h.Elapsed) / float64(time.Millisecond))
+		r.dnsLookup.WithLabelValues(h.Teststep).Observe(float64(h.DNSLookup) / float64(time.Millisecond))
+		r.connect.WithLabelValues(h.Teststep).Observe(float64(h.Connect) / float64(time.Millisecond))
+		r.tlsHandshake.WithLabelValues(h.Teststep).Observe(float64(h.TLSHandshake) / float64(time.Millisecond))
+		r.requestWrite.WithLabelValues(h.Teststep).Observe(float64(h.RequestWrite) / float64(time.Millisecond))
+		r.serverWait.WithLabelValues(h.Teststep).Observe(float64(h.ServerWait) / float64(time.Millisecond))
+		r.contentTransfer.WithLabelValues(h.Teststep).Observe(float64(h.ContentTransfer) / float64(time.Millisecond))
 		r.code.WithLabelValues(h.Teststep, strconv.FormatInt(int64(h.Code), 10)).Inc()
 		if len(h.Error) > 0 {
 			r.error.WithLabelValues(h.Teststep).Inc()
 		}
+		for _, a := range h.FailedAssertions {
+			r.assertion.WithLabelValues(h.Teststep, a).Inc()
+		}
 	}
 }
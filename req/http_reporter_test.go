@@ -1,7 +1,10 @@
 package req
 
 import (
+	"bytes"
 	"net/http"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/finklabs/GoGrinder/gogrinder"
@@ -26,9 +29,9 @@ func TestCheckHttpMetricImplementsMetricInterface(t *testing.T) {
 
 // TODO: Pending prometheus/client_golang#58
 // read metric helpers needs rework once testability is improved!
-func readSummaryVec(m *prometheus.SummaryVec, l prometheus.Labels) []*dto.Quantile {
+func readSummaryVec(m prometheus.ObserverVec, l prometheus.Labels) []*dto.Quantile {
 	pb := &dto.Metric{}
-	s := m.With(l)
+	s := m.With(l).(prometheus.Metric)
 	s.Write(pb)
 	return pb.GetSummary().GetQuantile()
 }
@@ -44,30 +47,123 @@ func TestHttpMetricUpdate(t *testing.T) {
 	hmr := NewHttpMetricReporter()
 
 	// add datapoint
-	hm := &HttpMetric{gogrinder.Meta{"01_tc", "01_01_ts", 0, 0, gogrinder.Timestamp(time.Now()),
-		gogrinder.Elapsed(600 * time.Millisecond), "something is wrong!"},
-		gogrinder.Elapsed(500 * time.Millisecond), 10240, http.StatusOK}
+	hm := &HttpMetric{gogrinder.Meta{Testcase: "01_tc", Teststep: "01_01_ts",
+		Timestamp: gogrinder.Timestamp(time.Now()), Elapsed: gogrinder.Elapsed(600 * time.Millisecond),
+		Error: "something is wrong!"},
+		gogrinder.Elapsed(500 * time.Millisecond), 10240, http.StatusOK,
+		gogrinder.Elapsed(10 * time.Millisecond), gogrinder.Elapsed(20 * time.Millisecond),
+		gogrinder.Elapsed(30 * time.Millisecond), gogrinder.Elapsed(1 * time.Millisecond),
+		gogrinder.Elapsed(439 * time.Millisecond), gogrinder.Elapsed(100 * time.Millisecond),
+		[]string{"status"}}
 	hmr.Update(hm)
 
 	// check that datapoint was reported
 	if exp, got := 600.0, readSummaryVec(hmr.elapsed,
 		prometheus.Labels{"teststep": "01_01_ts"})[0].GetValue(); exp != got {
-		t.Errorf("Expected elapsed %d, got %d.", exp, got)
+		t.Errorf("Expected elapsed %f, got %f.", exp, got)
 	}
 	if exp, got := 500.0, readSummaryVec(hmr.firstByte,
 		prometheus.Labels{"teststep": "01_01_ts"})[0].GetValue(); exp != got {
-		t.Errorf("Expected firstByte %d, got %d.", exp, got)
+		t.Errorf("Expected firstByte %f, got %f.", exp, got)
+	}
+	phases := []struct {
+		name string
+		vec  prometheus.ObserverVec
+		exp  float64
+	}{
+		{"dnsLookup", hmr.dnsLookup, 10.0},
+		{"connect", hmr.connect, 20.0},
+		{"tlsHandshake", hmr.tlsHandshake, 30.0},
+		{"requestWrite", hmr.requestWrite, 1.0},
+		{"serverWait", hmr.serverWait, 439.0},
+		{"contentTransfer", hmr.contentTransfer, 100.0},
+	}
+	for _, p := range phases {
+		if got := readSummaryVec(p.vec, prometheus.Labels{"teststep": "01_01_ts"})[0].GetValue(); p.exp != got {
+			t.Errorf("Expected %s %f, got %f.", p.name, p.exp, got)
+		}
 	}
 	if exp, got := 10.0, readSummaryVec(hmr.bytes,
 		prometheus.Labels{"teststep": "01_01_ts"})[0].GetValue(); exp != got {
-		t.Errorf("Expected kb %d, got %d.", exp, got)
+		t.Errorf("Expected kb %f, got %f.", exp, got)
 	}
 	if exp, got := 1.0, readCounterVec(hmr.error,
 		prometheus.Labels{"teststep": "01_01_ts"}); exp != got {
 		t.Errorf("Expected error counter %f, got %f.", exp, got)
 	}
+	if exp, got := 1.0, readCounterVec(hmr.assertion,
+		prometheus.Labels{"teststep": "01_01_ts", "assertion": "status"}); exp != got {
+		t.Errorf("Expected assertion counter %f, got %f.", exp, got)
+	}
 	if exp, got := 1.0, readCounterVec(hmr.code,
 		prometheus.Labels{"teststep": "01_01_ts", "code": "200"}); exp != got {
 		t.Errorf("Expected code counter %f, got %f.", exp, got)
 	}
 }
+
+func TestHttpMetricPrometheusReporter(t *testing.T) {
+	r, err := gogrinder.NewPrometheusReporter(&HttpMetric{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	r.Update(&HttpMetric{Meta: gogrinder.Meta{Teststep: "01_01_ts",
+		Elapsed: gogrinder.Elapsed(600 * time.Millisecond), Error: "something is wrong!"},
+		ServerWait: gogrinder.Elapsed(439 * time.Millisecond), Bytes: 10240, Code: http.StatusOK,
+		FailedAssertions: []string{"status"}})
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(r)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	values := make(map[string]float64)
+	for _, f := range families {
+		m := f.GetMetric()[0]
+		switch {
+		case m.GetSummary() != nil:
+			values[f.GetName()] = m.GetSummary().GetSampleSum()
+		case m.GetCounter() != nil:
+			values[f.GetName()] = m.GetCounter().GetValue()
+		}
+	}
+	exp := map[string]float64{
+		"gogrinder_elapsed_ms":             600,
+		"gogrinder_error_count":            1,
+		"gogrinder_first_byte_ms":          0,
+		"gogrinder_response_kb":            10,
+		"gogrinder_response_code_count":    1,
+		"gogrinder_dns_lookup_ms":          0,
+		"gogrinder_connect_ms":             0,
+		"gogrinder_tls_handshake_ms":       0,
+		"gogrinder_request_write_ms":       0,
+		"gogrinder_server_wait_ms":         439,
+		"gogrinder_content_transfer_ms":    0,
+		"gogrinder_assertion_failed_count": 1,
+	}
+	if !reflect.DeepEqual(values, exp) {
+		t.Errorf("Metrics expected %v but got: %v", exp, values)
+	}
+}
+
+func TestReadEventLogReplaysHttpMetric(t *testing.T) {
+	fake := gogrinder.NewTest()
+	if err := fake.ReadConfigValidate(`{"Scenario": "scenario1"}`, gogrinder.LoadmodelSchema); err != nil {
+		t.Fatal(err)
+	}
+	var jtl bytes.Buffer
+	fake.AddReportPlugin(gogrinder.NewJtlReporter(&jtl, nil, false, true))
+
+	log := `{"testcase":"01_tc","teststep":"home","user":0,"iteration":0,"ts":"2016-01-02T15:04:05Z","elapsed":8.000000,"first-byte":4.000000,"kb":1024,"status":200}
+`
+	if err := fake.ReadEventLog(strings.NewReader(log), gogrinder.EventLogFilter{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+	fake.CloseReportPlugins()
+
+	if !strings.Contains(jtl.String(), `<httpSample t="8" lt="4"`) ||
+		!strings.Contains(jtl.String(), `rc="200"`) || !strings.Contains(jtl.String(), `by="1024"`) ||
+		!strings.HasSuffix(jtl.String(), "</testResults>\n") {
+		t.Errorf("Replayed http metric not as expected: %s", jtl.String())
+	}
+}
@@ -28,7 +28,7 @@ func tc1(m *gogrinder.Meta, s gogrinder.Settings) {
 		r, err := http.NewRequest("GET", "http://localhost:3001/get_stuff", nil)
 		if err != nil {
 			m.Error += err.Error()
-			mm = &HttpMetric{*m, 0, 0, 400}
+			mm = &HttpMetric{Meta: *m, Code: 400}
 		}
 		_, _, mm = DoRaw(c, r, m)
 	}
@@ -45,7 +45,7 @@ func tc2(m *gogrinder.Meta, s gogrinder.Settings) {
 			util.NewRandReader(2000))
 		if err != nil {
 			m.Error += err.Error()
-			mm = &HttpMetric{*m, 0, 0, 400}
+			mm = &HttpMetric{Meta: *m, Code: 400}
 		}
 		_, _, mm = DoRaw(c, r, m)
 	}
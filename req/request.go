@@ -5,15 +5,19 @@ package req
 
 import (
 	"bufio"
-	//"bytes"
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	//"golang.org/x/net/html"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/cookiejar"
+	"net/http/httptrace"
 	//"net/url"
 	//"strings"
+	"sync"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/finklabs/GoGrinder/gogrinder"
@@ -55,12 +59,134 @@ func (fb *metricReader) Read(p []byte) (n int, err error) {
 	return
 }
 
+// Collect the timings of the http phases via httptrace. If the client
+// follows redirects the phases of all requests are summed up.
+type phaseTrace struct {
+	lock         sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	gotConn      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	dnsLookup    gogrinder.Elapsed
+	connect      gogrinder.Elapsed
+	tlsHandshake gogrinder.Elapsed
+	requestWrite gogrinder.Elapsed
+	serverWait   gogrinder.Elapsed
+}
+
+// Attach a phaseTrace to the request. Requests without a context of their
+// own use the context of the test run so they are cancelled when the test
+// is stopped.
+func newPhaseTrace(r *http.Request, m *gogrinder.Meta) (*http.Request, *phaseTrace) {
+	ctx := r.Context()
+	if ctx == context.Background() {
+		ctx = m.Context()
+	}
+	pt := &phaseTrace{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			pt.begin(&pt.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			pt.end(&pt.dnsStart, &pt.dnsLookup)
+		},
+		ConnectStart: func(network, addr string) {
+			// dual stack dialers might start more than one connect
+			pt.begin(&pt.connectStart)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				pt.end(&pt.connectStart, &pt.connect)
+			}
+		},
+		TLSHandshakeStart: func() {
+			pt.begin(&pt.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			pt.end(&pt.tlsStart, &pt.tlsHandshake)
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			pt.begin(&pt.gotConn)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			pt.end(&pt.gotConn, &pt.requestWrite)
+			pt.begin(&pt.wroteRequest)
+		},
+		GotFirstResponseByte: func() {
+			pt.lock.Lock()
+			defer pt.lock.Unlock()
+			pt.firstByte = time.Now()
+			if !pt.wroteRequest.IsZero() {
+				pt.serverWait += gogrinder.Elapsed(pt.firstByte.Sub(pt.wroteRequest))
+				pt.wroteRequest = time.Time{}
+			}
+		},
+	}
+	return r.WithContext(httptrace.WithClientTrace(ctx, trace)), pt
+}
+
+// Record the error of the request. Requests that are aborted because the test
+// is stopped are marked as cancelled instead.
+func (hm *HttpMetric) fail(r *http.Request, err error) {
+	if r.Context().Err() != nil {
+		hm.Cancelled = true
+		return
+	}
+	hm.Error += err.Error()
+}
+
+// Start of a phase.
+func (pt *phaseTrace) begin(start *time.Time) {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+	if start.IsZero() {
+		*start = time.Now()
+	}
+}
+
+// End of a phase.
+func (pt *phaseTrace) end(start *time.Time, phase *gogrinder.Elapsed) {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+	if !start.IsZero() {
+		*phase += gogrinder.Elapsed(time.Now().Sub(*start))
+		*start = time.Time{}
+	}
+}
+
+// Fill the phase timings into the HttpMetric once the response body is read.
+func (pt *phaseTrace) apply(hm *HttpMetric, mr *metricReader) {
+	pt.lock.Lock()
+	defer pt.lock.Unlock()
+	hm.DNSLookup = pt.dnsLookup
+	hm.Connect = pt.connect
+	hm.TLSHandshake = pt.tlsHandshake
+	hm.RequestWrite = pt.requestWrite
+	hm.ServerWait = pt.serverWait
+	if pt.firstByte.IsZero() {
+		// no trace information available (e.g. custom RoundTripper)
+		hm.FirstByte = mr.firstByteAfter
+		return
+	}
+	hm.FirstByte = gogrinder.Elapsed(pt.firstByte.Sub(pt.start))
+	hm.ContentTransfer = gogrinder.Elapsed(time.Now().Sub(pt.firstByte))
+}
+
+// Time from the start of the request until now.
+func (pt *phaseTrace) elapsed() gogrinder.Elapsed {
+	return gogrinder.Elapsed(time.Now().Sub(pt.start))
+}
+
 // JSON
-func DoJson(c *http.Client, r *http.Request, m *gogrinder.Meta) (map[string]interface{}, http.Header, *HttpMetric) {
-	hm := &HttpMetric{*m, gogrinder.Elapsed(0), 0, 421} // http status Misdirected Request
+func DoJson(c *http.Client, r *http.Request, m *gogrinder.Meta, assertions ...Assertion) (map[string]interface{}, http.Header, *HttpMetric) {
+	hm := &HttpMetric{Meta: *m, Code: 421} // http status Misdirected Request
+	r, pt := newPhaseTrace(r, m)
 	resp, err := c.Do(r)
 	if err != nil {
-		hm.Error += err.Error()
+		hm.fail(r, err)
 	}
 	if resp != nil {
 		defer resp.Body.Close()
@@ -69,7 +195,7 @@ func DoJson(c *http.Client, r *http.Request, m *gogrinder.Meta) (map[string]inte
 		// read the response body and parse as json
 		raw, err := ioutil.ReadAll(mr)
 		if err != nil {
-			m.Error += err.Error()
+			hm.fail(r, err)
 		}
 		doc := make(map[string]interface{})
 		if len(raw) > 0 {
@@ -85,13 +211,15 @@ func DoJson(c *http.Client, r *http.Request, m *gogrinder.Meta) (map[string]inte
 				err = json.Unmarshal(raw, &doc)
 			}
 			if err != nil {
-				m.Error += err.Error()
+				hm.fail(r, err)
 			}
 		}
 
-		hm.FirstByte = mr.firstByteAfter
+		pt.apply(hm, mr)
 		hm.Bytes = mr.bytes
 		hm.Code = resp.StatusCode
+		hm.assert(&Response{Code: resp.StatusCode, Header: resp.Header, Body: raw,
+			Bytes: mr.bytes, Elapsed: pt.elapsed()}, assertions)
 		return doc, resp.Header, hm
 	}
 
@@ -99,12 +227,13 @@ func DoJson(c *http.Client, r *http.Request, m *gogrinder.Meta) (map[string]inte
 }
 
 // RAW
-func DoRaw(c *http.Client, r *http.Request, m *gogrinder.Meta) ([]byte, http.Header, *HttpMetric) {
-	hm := &HttpMetric{*m, gogrinder.Elapsed(0), 0, 421} // http status Misdirected Request
+func DoRaw(c *http.Client, r *http.Request, m *gogrinder.Meta, assertions ...Assertion) ([]byte, http.Header, *HttpMetric) {
+	hm := &HttpMetric{Meta: *m, Code: 421} // http status Misdirected Request
+	r, pt := newPhaseTrace(r, m)
 
 	resp, err := c.Do(r)
 	if err != nil {
-		hm.Error += err.Error()
+		hm.fail(r, err)
 	}
 	if resp != nil {
 		defer resp.Body.Close()
@@ -113,12 +242,14 @@ func DoRaw(c *http.Client, r *http.Request, m *gogrinder.Meta) ([]byte, http.Hea
 		// read the response body
 		raw, err := ioutil.ReadAll(mr)
 		if err != nil {
-			hm.Error += err.Error()
+			hm.fail(r, err)
 		}
 
-		hm.FirstByte = mr.firstByteAfter
+		pt.apply(hm, mr)
 		hm.Bytes = mr.bytes
 		hm.Code = resp.StatusCode
+		hm.assert(&Response{Code: resp.StatusCode, Header: resp.Header, Body: raw,
+			Bytes: mr.bytes, Elapsed: pt.elapsed()}, assertions)
 		return raw, resp.Header, hm
 	}
 
@@ -126,25 +257,34 @@ func DoRaw(c *http.Client, r *http.Request, m *gogrinder.Meta) ([]byte, http.Hea
 }
 
 // DOC
-func Do(c *http.Client, r *http.Request, m *gogrinder.Meta) (*goquery.Document, http.Header, *HttpMetric) {
-	hm := &HttpMetric{*m, gogrinder.Elapsed(0), 0, 421} // http status Misdirected Request
+func Do(c *http.Client, r *http.Request, m *gogrinder.Meta, assertions ...Assertion) (*goquery.Document, http.Header, *HttpMetric) {
+	hm := &HttpMetric{Meta: *m, Code: 421} // http status Misdirected Request
+	r, pt := newPhaseTrace(r, m)
 	resp, err := c.Do(r)
 	if err != nil {
-		hm.Error += err.Error()
+		hm.fail(r, err)
 	}
 	if resp != nil {
 		defer resp.Body.Close()
 		mr := newMetricReader(resp.Body)
 
 		// read the response body and parse into document
-		doc, err := goquery.NewDocumentFromReader(mr)
+		// (keep a copy of the body only if we need it for the assertions)
+		var raw bytes.Buffer
+		var body io.Reader = mr
+		if len(assertions) > 0 {
+			body = io.TeeReader(mr, &raw)
+		}
+		doc, err := goquery.NewDocumentFromReader(body)
 		if err != nil {
-			m.Error += err.Error()
+			hm.fail(r, err)
 		}
 
-		hm.FirstByte = mr.firstByteAfter
+		pt.apply(hm, mr)
 		hm.Bytes = mr.bytes
 		hm.Code = resp.StatusCode
+		hm.assert(&Response{Code: resp.StatusCode, Header: resp.Header, Body: raw.Bytes(),
+			Bytes: mr.bytes, Elapsed: pt.elapsed()}, assertions)
 		return doc, resp.Header, hm
 	}
 
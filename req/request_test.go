@@ -1,6 +1,7 @@
 package req
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -103,6 +104,23 @@ func TestDoJson(t *testing.T) {
 	}
 }
 
+func TestDoJsonInvalidBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id": 1,`))
+	}))
+	defer ts.Close()
+
+	m := &gogrinder.Meta{Testcase: "sth", Teststep: "else", User: 0, Iteration: 0}
+	r, _ := http.NewRequest("GET", ts.URL, nil)
+	_, _, metric := DoJson(NewDefaultClient(), r, m)
+	if metric.Error != "unexpected end of JSON input" {
+		t.Errorf("Error of the metric not as expected: %s", metric.Error)
+	}
+	if m.Error != "" {
+		t.Errorf("Error of the given meta is expected to be untouched: %s", m.Error)
+	}
+}
+
 // RAW
 func TestDoRaw(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -182,3 +200,95 @@ func TestDefaultClientProvidesCookiejar(t *testing.T) {
 		t.Fatalf("Cookiejar is empty!")
 	}
 }
+
+func TestDoRawPhaseTimings(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ti.Sleep(30 * ti.Millisecond) // backend
+		w.Write([]byte("mark"))
+		w.(http.Flusher).Flush()
+		ti.Sleep(20 * ti.Millisecond) // content transfer
+		w.Write([]byte("fink"))
+	}))
+	defer ts.Close()
+
+	m := &gogrinder.Meta{Testcase: "sth", Teststep: "else", User: 0, Iteration: 0}
+	r, err := http.NewRequest("GET", ts.URL, nil)
+	if err != nil {
+		t.Fatalf("Something went wrong while parsing the URL.")
+	}
+	resp, _, metric := DoRaw(ts.Client(), r, m)
+	if len(metric.Error) > 0 {
+		t.Fatal(metric.Error)
+	}
+	if string(resp) != "markfink" {
+		t.Fatalf("DoRaw response not as expected: '%s'", resp)
+	}
+
+	if metric.DNSLookup != 0 {
+		t.Errorf("No dns lookup expected for an ip address but was: %v", metric.DNSLookup)
+	}
+	if metric.Connect <= 0 || metric.TLSHandshake <= 0 || metric.RequestWrite <= 0 {
+		t.Errorf("Connect, tls handshake and request write expected but was: %v, %v, %v",
+			metric.Connect, metric.TLSHandshake, metric.RequestWrite)
+	}
+	if metric.ServerWait < gogrinder.Elapsed(30*time.Millisecond) {
+		t.Errorf("Server wait expected >= 30 ms but was: %v", metric.ServerWait)
+	}
+	if metric.ContentTransfer < gogrinder.Elapsed(20*time.Millisecond) {
+		t.Errorf("Content transfer expected >= 20 ms but was: %v", metric.ContentTransfer)
+	}
+	if metric.FirstByte < metric.Connect+metric.TLSHandshake+metric.ServerWait {
+		t.Errorf("First byte expected after connect, tls handshake and server wait but was: %v",
+			metric.FirstByte)
+	}
+}
+
+func TestDoPhaseTimingsReusedConnection(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body><h1>My First Heading</h1></body></html>"))
+	}))
+	defer ts.Close()
+
+	m := &gogrinder.Meta{Testcase: "sth", Teststep: "else", User: 0, Iteration: 0}
+	c := NewDefaultClient()
+	var metric *HttpMetric
+	for i := 0; i < 2; i++ {
+		r, _ := http.NewRequest("GET", ts.URL, nil)
+		_, _, metric = Do(c, r, m)
+		if len(metric.Error) > 0 {
+			t.Fatal(metric.Error)
+		}
+	}
+	// the second request reuses the keep-alive connection
+	if metric.Connect != 0 || metric.TLSHandshake != 0 {
+		t.Errorf("No connect expected for a reused connection but was: %v, %v",
+			metric.Connect, metric.TLSHandshake)
+	}
+	if metric.ServerWait <= 0 || metric.FirstByte < metric.ServerWait {
+		t.Errorf("Server wait and first byte not as expected: %v, %v", metric.ServerWait, metric.FirstByte)
+	}
+}
+
+func TestDoRawCancelled(t *testing.T) {
+	release := make(chan bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	m := &gogrinder.Meta{Testcase: "sth", Teststep: "else", User: 0, Iteration: 0}
+	r, _ := http.NewRequest("GET", ts.URL, nil)
+	_, _, metric := DoRaw(NewDefaultClient(), r.WithContext(ctx), m)
+	if !metric.Cancelled {
+		t.Errorf("Request expected to be cancelled!")
+	}
+	if metric.Error != "" {
+		t.Errorf("No error expected for a cancelled request but was: %s", metric.Error)
+	}
+}
@@ -0,0 +1,85 @@
+package req
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/finklabs/GoGrinder/gogrinder"
+)
+
+// Vars gives access to the variables of a virtual user (e.g. extracted
+// values) so they can flow into subsequent requests. It is a view on the
+// variables of the session (see UserVars); values that are no strings are
+// formatted with fmt.Sprint.
+type Vars struct {
+	lock   sync.RWMutex
+	values map[string]interface{}
+}
+
+// Create an empty set of variables (not part of a session).
+func NewVars() *Vars {
+	return &Vars{values: make(map[string]interface{})}
+}
+
+// Set the value of a variable.
+func (v *Vars) Set(name string, value string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.values[name] = value
+}
+
+// Get the value of a variable.
+func (v *Vars) Get(name string) (string, bool) {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+	return v.get(name)
+}
+
+// Careful: the caller needs to hold the lock!
+func (v *Vars) get(name string) (string, bool) {
+	value, ok := v.values[name]
+	if !ok {
+		return "", false
+	}
+	if s, ok := value.(string); ok {
+		return s, true
+	}
+	return fmt.Sprint(value), true
+}
+
+// Remove a variable.
+func (v *Vars) Delete(name string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	delete(v.values, name)
+}
+
+var varsPattern = regexp.MustCompile(`\$\{([\w.-]+)\}`)
+
+// Replace the ${name} placeholders with the values of the variables (e.g. in
+// urls or request bodies). Unknown variables are not replaced.
+//
+//	r, err := http.NewRequest("GET", vars.Expand(base+"/rest/supercars/${id}"), nil)
+func (v *Vars) Expand(s string) string {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+	return varsPattern.ReplaceAllStringFunc(s, func(p string) string {
+		if value, ok := v.get(p[2 : len(p)-1]); ok {
+			return value
+		}
+		return p
+	})
+}
+
+// Variables of the virtual user. They are the variables of the session of the
+// user (gogrinder.Session.Vars) so they persist between the iterations.
+//
+//	vars := req.UserVars(m)
+//	id, err := req.ExtractJsonString(resp, "_id")
+//	if err == nil {
+//	    vars.Set("id", id)
+//	}
+func UserVars(m *gogrinder.Meta) *Vars {
+	return &Vars{values: m.Session().Vars}
+}
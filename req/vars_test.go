@@ -0,0 +1,70 @@
+package req
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/finklabs/GoGrinder/gogrinder"
+)
+
+func TestVars(t *testing.T) {
+	v := NewVars()
+	v.Set("id", "00042")
+	v.Set("host", "localhost")
+
+	if s, ok := v.Get("id"); !ok || s != "00042" {
+		t.Errorf("Variable not as expected: %s, %v", s, ok)
+	}
+	if exp, got := "http://localhost/rest/supercars/00042?token=${token}",
+		v.Expand("http://${host}/rest/supercars/${id}?token=${token}"); exp != got {
+		t.Errorf("Expanded string not as expected: %s", got)
+	}
+	v.Delete("id")
+	if _, ok := v.Get("id"); ok {
+		t.Errorf("Variable was expected to be deleted!")
+	}
+}
+
+func TestVarsConcurrentUse(t *testing.T) {
+	v := NewVars()
+	var wg sync.WaitGroup
+	for u := 0; u < 10; u++ {
+		wg.Add(1)
+		go func(u int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				v.Set("id", "x")
+				v.Expand("${id}")
+			}
+		}(u)
+	}
+	wg.Wait()
+}
+
+func TestUserVarsInSession(t *testing.T) {
+	m := &gogrinder.Meta{Testcase: "01_tc", User: 1}
+	UserVars(m).Set("id", "1")
+
+	// next iteration of the same user (same session)
+	next := *m
+	next.Iteration = 1
+	if id, _ := UserVars(&next).Get("id"); id != "1" {
+		t.Errorf("Variable of the user not as expected: %s", id)
+	}
+	if _, ok := UserVars(&gogrinder.Meta{Testcase: "01_tc", User: 1}).Get("id"); ok {
+		t.Errorf("Variables are expected to be kept per session!")
+	}
+}
+
+func TestUserVarsAreSessionVars(t *testing.T) {
+	m := &gogrinder.Meta{Testcase: "01_tc", User: 1}
+	UserVars(m).Set("id", "1")
+	if m.Session().Vars["id"] != "1" {
+		t.Errorf("Variable expected in the session: %v", m.Session().Vars)
+	}
+
+	m.Session().Vars["count"] = 3
+	if exp, got := "/rest/supercars/1?count=3", UserVars(m).Expand("/rest/supercars/${id}?count=${count}"); exp != got {
+		t.Errorf("Expanded string not as expected: %s", got)
+	}
+}
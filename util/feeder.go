@@ -0,0 +1,272 @@
+package util
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/finklabs/GoGrinder/gogrinder"
+)
+
+// Record of the test data. The keys are the columns of the csv header or the
+// keys of the json object.
+type Record map[string]string
+
+// FeederMode defines how the records are handed out to the virtual users.
+type FeederMode int
+
+const (
+	Sequential  FeederMode = iota // every user reads all records in order
+	Random                        // every call returns a random record (never ends)
+	Partitioned                   // every user reads its share of the records in order
+	Unique                        // every record is handed out only once
+)
+
+// EndOfData defines what happens if a user runs out of records.
+type EndOfData int
+
+const (
+	Recycle  EndOfData = iota // start again with the first record
+	StopUser                  // stop the user after the current iteration
+	StopTest                  // stop the test
+)
+
+// Next returns ErrEndOfData if the user runs out of records and the feeder
+// does not recycle them.
+var ErrEndOfData = errors.New("end of test data")
+
+// Feeder hands out test data records to the virtual users:
+//
+//	var users, _ = util.NewCsvFeeder("users.csv", util.Unique, util.StopTest)
+//
+//	func tc1(m *gogrinder.Meta, s gogrinder.Settings) {
+//	    user, err := users.Next(m)
+//	    if err != nil {
+//	        return
+//	    }
+//	    ...
+//
+// In Partitioned mode Users needs to be set to the number of users of the
+// testcase.
+type Feeder struct {
+	Mode    FeederMode
+	OnEnd   EndOfData
+	Users   int // number of partitions (Partitioned mode)
+	lock    sync.Mutex
+	records []Record
+	next    int         // next record (Unique mode)
+	users   map[int]int // next record per user (Sequential and Partitioned mode)
+	rand    *rand.Rand
+}
+
+// Create a feeder for the given records.
+func NewFeeder(records []Record, mode FeederMode, onEnd EndOfData) *Feeder {
+	return &Feeder{Mode: mode, OnEnd: onEnd, records: records,
+		users: make(map[int]int), rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Create a feeder for a csv file (.gz files are decompressed).
+func NewCsvFeeder(filename string, mode FeederMode, onEnd EndOfData) (*Feeder, error) {
+	records, err := ReadCsv(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewFeeder(records, mode, onEnd), nil
+}
+
+// Create a feeder for a json-lines file (.gz files are decompressed).
+func NewJsonlFeeder(filename string, mode FeederMode, onEnd EndOfData) (*Feeder, error) {
+	records, err := ReadJsonl(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewFeeder(records, mode, onEnd), nil
+}
+
+// Number of records of the feeder.
+func (f *Feeder) Len() int {
+	return len(f.records)
+}
+
+// Next record for the user of the iteration. If the user runs out of records
+// the OnEnd behaviour applies. The records are shared among the users so do
+// not modify them.
+func (f *Feeder) Next(m *gogrinder.Meta) (Record, error) {
+	user := 0
+	if m != nil {
+		user = m.User
+	}
+	f.lock.Lock()
+	r, ok := f.record(user)
+	f.lock.Unlock()
+	if ok {
+		return r, nil
+	}
+	if m != nil {
+		switch f.OnEnd {
+		case StopUser:
+			m.Session().Stop()
+		case StopTest:
+			m.Session().StopTest()
+		}
+	}
+	return nil, ErrEndOfData
+}
+
+// Helper to pick the next record.
+// Careful: the caller needs to hold the feeder lock!
+func (f *Feeder) record(user int) (Record, bool) {
+	if len(f.records) == 0 {
+		return nil, false
+	}
+	switch f.Mode {
+	case Random:
+		return f.records[f.rand.Intn(len(f.records))], true
+	case Unique:
+		if f.next >= len(f.records) {
+			if f.OnEnd != Recycle {
+				return nil, false
+			}
+			f.next = 0
+		}
+		f.next++
+		return f.records[f.next-1], true
+	case Partitioned:
+		partitions := f.Users
+		if partitions < 1 {
+			partitions = 1
+		}
+		// records of the user: user, user+partitions, user+2*partitions, ...
+		start := user % partitions
+		if start >= len(f.records) {
+			return nil, false // more users than records
+		}
+		i := f.users[user]
+		if start+i*partitions >= len(f.records) {
+			if f.OnEnd != Recycle {
+				return nil, false
+			}
+			i = 0
+		}
+		f.users[user] = i + 1
+		return f.records[start+i*partitions], true
+	}
+	// Sequential
+	i := f.users[user]
+	if i >= len(f.records) {
+		if f.OnEnd != Recycle {
+			return nil, false
+		}
+		i = 0
+	}
+	f.users[user] = i + 1
+	return f.records[i], true
+}
+
+// Helper to open a data file. Files ending with .gz are decompressed.
+func openData(filename string) (io.ReadCloser, error) {
+	fi, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(filename, ".gz") {
+		return fi, nil
+	}
+	gr, err := gzip.NewReader(fi)
+	if err != nil {
+		fi.Close()
+		return nil, err
+	}
+	return &gzFile{gr, fi}, nil
+}
+
+// Close the gzip reader and the file.
+type gzFile struct {
+	*gzip.Reader
+	fi *os.File
+}
+
+func (g *gzFile) Close() error {
+	g.Reader.Close()
+	return g.fi.Close()
+}
+
+// Read the records of a csv file. The first line contains the column names.
+func ReadCsv(filename string) ([]Record, error) {
+	fi, err := openData(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	cr := csv.NewReader(fi)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("can not read the header of %s: %v", filename, err)
+	}
+	records := []Record{}
+	for {
+		line, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can not read %s: %v", filename, err)
+		}
+		r := make(Record)
+		for i, name := range header {
+			if i < len(line) {
+				r[name] = line[i]
+			}
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// Read the records of a json-lines file (one json object per line). Values
+// that are not strings are kept in their json notation (e.g. 42, true).
+func ReadJsonl(filename string) ([]Record, error) {
+	fi, err := openData(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	records := []Record{}
+	scanner := bufio.NewScanner(fi)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for nbr := 1; scanner.Scan(); nbr++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		obj := make(map[string]json.RawMessage)
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			return nil, fmt.Errorf("can not read %s line %d: %v", filename, nbr, err)
+		}
+		r := make(Record)
+		for k, v := range obj {
+			var s string
+			if json.Unmarshal(v, &s) == nil {
+				r[k] = s
+			} else {
+				r[k] = string(v)
+			}
+		}
+		records = append(records, r)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can not read %s: %v", filename, err)
+	}
+	return records, nil
+}
@@ -0,0 +1,211 @@
+package util
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/finklabs/GoGrinder/gogrinder"
+)
+
+// Helper to write a test data file.
+func writeData(t *testing.T, name string, data string) string {
+	dir, err := ioutil.TempDir("", "feeder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, name)
+	fo, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fo.Close()
+	if filepath.Ext(name) == ".gz" {
+		gw := gzip.NewWriter(fo)
+		gw.Write([]byte(data))
+		gw.Close()
+	} else {
+		fo.Write([]byte(data))
+	}
+	return filename
+}
+
+func records(n int) []Record {
+	r := make([]Record, n)
+	for i := range r {
+		r[i] = Record{"id": string(rune('a' + i))}
+	}
+	return r
+}
+
+func TestReadCsv(t *testing.T) {
+	filename := writeData(t, "users.csv", "name,password\nmark,s3cr3t\n\"doe, john\",pwd\n")
+	defer os.RemoveAll(filepath.Dir(filename))
+
+	r, err := ReadCsv(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(r) != 2 || r[0]["name"] != "mark" || r[0]["password"] != "s3cr3t" ||
+		r[1]["name"] != "doe, john" {
+		t.Errorf("Records not as expected: %v", r)
+	}
+}
+
+func TestReadCsvGz(t *testing.T) {
+	filename := writeData(t, "users.csv.gz", "name,password\nmark,s3cr3t\n")
+	defer os.RemoveAll(filepath.Dir(filename))
+
+	r, err := ReadCsv(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(r) != 1 || r[0]["name"] != "mark" {
+		t.Errorf("Records not as expected: %v", r)
+	}
+}
+
+func TestReadCsvMissingFile(t *testing.T) {
+	if _, err := ReadCsv("does_not_exist.csv"); err == nil {
+		t.Errorf("Expected an error for a missing file!")
+	}
+}
+
+func TestReadJsonl(t *testing.T) {
+	filename := writeData(t, "cars.jsonl", `{"name": "Enzo", "power": 650, "new": true}
+
+{"name": "F40", "tags": ["a", "b"]}
+`)
+	defer os.RemoveAll(filepath.Dir(filename))
+
+	r, err := ReadJsonl(filename)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(r) != 2 || r[0]["name"] != "Enzo" || r[0]["power"] != "650" || r[0]["new"] != "true" ||
+		r[1]["tags"] != `["a", "b"]` {
+		t.Errorf("Records not as expected: %v", r)
+	}
+}
+
+func TestReadJsonlInvalid(t *testing.T) {
+	filename := writeData(t, "cars.jsonl", "{\"name\": \"Enzo\"}\nnot json\n")
+	defer os.RemoveAll(filepath.Dir(filename))
+
+	_, err := ReadJsonl(filename)
+	if err == nil {
+		t.Fatalf("Expected an error for invalid json!")
+	}
+	if exp := "can not read " + filename + " line 2: "; err.Error()[:len(exp)] != exp {
+		t.Errorf("Error message not as expected: %v", err)
+	}
+}
+
+func TestFeederSequential(t *testing.T) {
+	f := NewFeeder(records(2), Sequential, Recycle)
+	m0, m1 := &gogrinder.Meta{User: 0}, &gogrinder.Meta{User: 1}
+	ids := ""
+	for _, m := range []*gogrinder.Meta{m0, m0, m1, m0} {
+		r, err := f.Next(m)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ids += r["id"]
+	}
+	if ids != "abaa" {
+		t.Errorf("Records not as expected: %s", ids)
+	}
+}
+
+func TestFeederPartitioned(t *testing.T) {
+	f := NewFeeder(records(5), Partitioned, StopUser)
+	f.Users = 2
+	m0, m1 := &gogrinder.Meta{User: 0}, &gogrinder.Meta{User: 1}
+	ids := ""
+	for _, m := range []*gogrinder.Meta{m0, m1, m0, m1, m0} {
+		r, err := f.Next(m)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ids += r["id"]
+	}
+	if ids != "abcde" {
+		t.Errorf("Records not as expected: %s", ids)
+	}
+	if _, err := f.Next(m1); err != ErrEndOfData {
+		t.Errorf("Expected end of data but got: %v", err)
+	}
+	if !m1.Session().Stopped() || m0.Session().Stopped() {
+		t.Errorf("Expected user 1 to be stopped!")
+	}
+}
+
+func TestFeederUnique(t *testing.T) {
+	f := NewFeeder(records(3), Unique, StopUser)
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		r, err := f.Next(&gogrinder.Meta{User: i})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if seen[r["id"]] {
+			t.Errorf("Record %s handed out twice!", r["id"])
+		}
+		seen[r["id"]] = true
+	}
+	if _, err := f.Next(nil); err != ErrEndOfData {
+		t.Errorf("Expected end of data but got: %v", err)
+	}
+
+	f = NewFeeder(records(1), Unique, Recycle)
+	for i := 0; i < 3; i++ {
+		if _, err := f.Next(nil); err != nil {
+			t.Errorf("Expected the records to be recycled but got: %v", err)
+		}
+	}
+}
+
+func TestFeederRandom(t *testing.T) {
+	f := NewFeeder(records(3), Random, StopUser)
+	for i := 0; i < 100; i++ {
+		if r, err := f.Next(nil); err != nil || r == nil {
+			t.Fatalf("Random feeder is not expected to run out of records: %v", err)
+		}
+	}
+	if _, err := NewFeeder(nil, Random, Recycle).Next(nil); err != ErrEndOfData {
+		t.Errorf("Expected end of data for an empty feeder but got: %v", err)
+	}
+}
+
+func TestFeederStopTest(t *testing.T) {
+	filename := writeData(t, "users.csv", "name\nmark\njohn\nmary\n")
+	defer os.RemoveAll(filepath.Dir(filename))
+	f, err := NewCsvFeeder(filename, Unique, StopTest)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	test := gogrinder.NewTest()
+	var iterations int32
+	test.Testscenario("scenario1", func() {
+		test.Schedule("01_tc", func(m *gogrinder.Meta, s gogrinder.Settings) {
+			if _, err := f.Next(m); err == nil {
+				atomic.AddInt32(&iterations, 1)
+			}
+		})
+	})
+	loadmodel := `{"Scenario": "scenario1", "Loadmodel": [
+		{"Testcase": "01_tc", "Runfor": 100, "Users": 2, "Pacing": 0}]}`
+	if err := test.ReadConfigValidate(loadmodel, gogrinder.LoadmodelSchema); err != nil {
+		t.Fatal(err)
+	}
+	if err := test.Exec(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(&iterations); n != 3 {
+		t.Errorf("Expected an iteration per record but got %d", n)
+	}
+}